@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/coverage"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runCoverage implements go-sourcemap coverage, remapping V8 coverage of bundles to their original sources.
+func runCoverage(arguments []string) {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap coverage: go-sourcemap coverage [flags] coverage.json ...")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+	options := coverage.Options{}
+
+	fetch.register(flags)
+	lcovPath := flags.String("lcov", "", "File to save LCOV coverage to")
+	istanbulPath := flags.String("istanbul", "", "File to save Istanbul JSON coverage to")
+	mapDir := flags.String("maps", "", "Directory of source maps named after their script, e.g. app.js.map. By default each script's sourceMappingURL is followed")
+	flags.BoolVar(&options.KeepIgnored, "keep-ignored", false, "Keep the coverage of sources in the source map's ignoreList")
+	flags.Func("layout", "How source urls are turned into paths in the report: clean, origin or raw (default clean)", func(value string) error {
+		layout, err := tools.ParseLayout(value)
+		options.Layout = layout
+
+		return err
+	})
+
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	resolver := &tools.ScriptMapResolver{Fetcher: fetcher, MapDir: *mapDir, Scripts: make(map[string]string)}
+	report := coverage.NewReport()
+	remapped, failed := 0, 0
+
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+
+		if err != nil {
+			fmt.Printf("Error opening %s: %v\n", path, err)
+			os.Exit(-1)
+		}
+
+		scripts, err := coverage.ParseV8Coverage(file)
+		file.Close()
+
+		if err != nil {
+			fmt.Printf("Error parsing %s: %v\n", path, err)
+			os.Exit(-1)
+		}
+
+		for _, script := range scripts {
+			// Skip runtime internals such as node:fs, and eval'd code without a url
+			if script.Url == "" || strings.HasPrefix(script.Url, "node:") {
+				continue
+			}
+
+			err = remapScript(report, resolver, script, options)
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", script.Url, err)
+				failed++
+				continue
+			}
+
+			remapped++
+		}
+	}
+
+	if *lcovPath == "" && *istanbulPath == "" {
+		err = report.WriteLCOV(os.Stdout)
+	}
+
+	if *lcovPath != "" && err == nil {
+		err = writeOutputFile(*lcovPath, report.WriteLCOV)
+	}
+
+	if *istanbulPath != "" && err == nil {
+		err = writeOutputFile(*istanbulPath, report.WriteIstanbul)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Remapped %d scripts to %d original sources, %d failed\n", remapped, len(report.Files), failed)
+}
+
+// remapScript finds the contents and source map of script, and adds its coverage to report.
+func remapScript(report *coverage.Report, resolver *tools.ScriptMapResolver, script coverage.ScriptCoverage, options coverage.Options) error {
+	generated := script.Source
+
+	if generated == "" {
+		var err error
+		generated, err = resolver.Read(script.Url)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	resolver.Scripts[script.Url] = generated
+	mapRecord, err := resolver.Resolve(script.Url)
+	delete(resolver.Scripts, script.Url)
+
+	if err != nil {
+		return err
+	}
+
+	return report.Add(script, generated, mapRecord, options)
+}
@@ -0,0 +1,65 @@
+// Package coverage remaps V8 code coverage of bundled scripts to their original sources through source maps,
+// and writes it as Istanbul JSON or LCOV.
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Range is a V8 coverage range. Offsets are in UTF-16 code units from the start of the script, as JavaScript
+// measures strings, which is the same as bytes for ASCII scripts.
+type Range struct {
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+	Count       int `json:"count"`
+}
+
+// Function is the V8 coverage of a function. The first range covers the whole function,
+// the following ones, with block coverage, the blocks inside it.
+type Function struct {
+	FunctionName    string  `json:"functionName"`
+	Ranges          []Range `json:"ranges"`
+	IsBlockCoverage bool    `json:"isBlockCoverage"`
+}
+
+// ScriptCoverage is the V8 coverage of a script, as collected by Playwright, Puppeteer or NODE_V8_COVERAGE.
+type ScriptCoverage struct {
+	ScriptId string `json:"scriptId"`
+	Url      string `json:"url"`
+	// Source is the contents of the script, recorded by Playwright and Puppeteer
+	Source    string     `json:"source,omitempty"`
+	Functions []Function `json:"functions"`
+}
+
+// ParseV8Coverage parses V8 coverage read from r, either a list of ScriptCoverage as Playwright and Puppeteer
+// return them, or the {"result": [...]} files written by NODE_V8_COVERAGE and the DevTools protocol.
+func ParseV8Coverage(r io.Reader) ([]ScriptCoverage, error) {
+	contents, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading coverage: %w", err)
+	}
+
+	contents = bytes.TrimSpace(contents)
+	scripts := make([]ScriptCoverage, 0)
+
+	if len(contents) > 0 && contents[0] == '[' {
+		err = json.Unmarshal(contents, &scripts)
+	} else {
+		wrapped := struct {
+			Result []ScriptCoverage `json:"result"`
+		}{}
+
+		err = json.Unmarshal(contents, &wrapped)
+		scripts = wrapped.Result
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing coverage: %w", err)
+	}
+
+	return scripts, nil
+}
@@ -0,0 +1,68 @@
+package coverage
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// testGenerated is the minified file test1.js.map was generated for.
+const testGenerated = "function abcd(){}export default abcd;"
+
+// testScript is the coverage of testGenerated after importing it, without ever calling abcd.
+var testScript = ScriptCoverage{
+	ScriptId: "1",
+	Url:      "https://example.com/test1.js",
+	Functions: []Function{
+		{FunctionName: "", Ranges: []Range{{StartOffset: 0, EndOffset: len(testGenerated), Count: 1}}, IsBlockCoverage: true},
+		{FunctionName: "abcd", Ranges: []Range{{StartOffset: 0, EndOffset: 17, Count: 0}}, IsBlockCoverage: true},
+	},
+}
+
+func parseTestMap(t *testing.T) *spec.DecodedSourceMapRecord {
+	t.Helper()
+
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	mapRecord, err := spec.ParseSourceMap(string(contents), "")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	return mapRecord
+}
+
+func TestParseV8Coverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"playwright", `[{"url": "https://example.com/a.js", "scriptId": "1", "source": "x", "functions": [{"functionName": "", "isBlockCoverage": true, "ranges": [{"startOffset": 0, "endOffset": 1, "count": 1}]}]}]`},
+		{"node", `{"result": [{"url": "https://example.com/a.js", "scriptId": "1", "functions": [{"functionName": "", "isBlockCoverage": true, "ranges": [{"startOffset": 0, "endOffset": 1, "count": 1}]}]}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scripts, err := ParseV8Coverage(strings.NewReader(test.contents))
+
+			if err != nil {
+				t.Fatalf("Error parsing coverage: %v", err)
+			}
+
+			if len(scripts) != 1 || scripts[0].Url != "https://example.com/a.js" || scripts[0].Functions[0].Ranges[0].EndOffset != 1 {
+				t.Errorf("Unexpected coverage %+v", scripts)
+			}
+		})
+	}
+
+	if _, err := ParseV8Coverage(strings.NewReader("{")); err == nil {
+		t.Errorf("Expected an error for invalid coverage")
+	}
+}
@@ -0,0 +1,102 @@
+package coverage
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// IstanbulFunction is an entry of the fnMap of IstanbulFileCoverage.
+type IstanbulFunction struct {
+	Name string   `json:"name"`
+	Decl Location `json:"decl"`
+	Loc  Location `json:"loc"`
+	Line int      `json:"line"`
+}
+
+// IstanbulBranch is an entry of the branchMap of IstanbulFileCoverage.
+type IstanbulBranch struct {
+	Loc       Location   `json:"loc"`
+	Type      string     `json:"type"`
+	Locations []Location `json:"locations"`
+	Line      int        `json:"line"`
+}
+
+// IstanbulFileCoverage is the coverage of one file in the format of istanbul-lib-coverage, as read by nyc and c8.
+type IstanbulFileCoverage struct {
+	Path         string                      `json:"path"`
+	StatementMap map[string]Location         `json:"statementMap"`
+	FnMap        map[string]IstanbulFunction `json:"fnMap"`
+	BranchMap    map[string]IstanbulBranch   `json:"branchMap"`
+	S            map[string]int              `json:"s"`
+	F            map[string]int              `json:"f"`
+	B            map[string][]int            `json:"b"`
+}
+
+// compareLocations orders locations by start, then end.
+func compareLocations(a Location, b Location) int {
+	return cmp.Or(
+		cmp.Compare(a.Start.Line, b.Start.Line),
+		cmp.Compare(a.Start.Column, b.Start.Column),
+		cmp.Compare(a.End.Line, b.End.Line),
+		cmp.Compare(a.End.Column, b.End.Column),
+	)
+}
+
+// Istanbul converts file to the istanbul-lib-coverage format. Entries are numbered in source order.
+func (file *FileCoverage) Istanbul() *IstanbulFileCoverage {
+	istanbul := &IstanbulFileCoverage{
+		Path:         file.Path,
+		StatementMap: make(map[string]Location),
+		FnMap:        make(map[string]IstanbulFunction),
+		BranchMap:    make(map[string]IstanbulBranch),
+		S:            make(map[string]int),
+		F:            make(map[string]int),
+		B:            make(map[string][]int),
+	}
+
+	for index, loc := range slices.SortedFunc(maps.Keys(file.Statements), compareLocations) {
+		id := strconv.Itoa(index)
+		istanbul.StatementMap[id] = loc
+		istanbul.S[id] = file.Statements[loc]
+	}
+
+	functions := slices.SortedFunc(maps.Keys(file.Functions), func(a FunctionKey, b FunctionKey) int {
+		return cmp.Or(compareLocations(a.Loc, b.Loc), cmp.Compare(a.Name, b.Name))
+	})
+
+	for index, key := range functions {
+		id := strconv.Itoa(index)
+		istanbul.FnMap[id] = IstanbulFunction{Name: key.Name, Decl: key.Loc, Loc: key.Loc, Line: key.Loc.Start.Line}
+		istanbul.F[id] = file.Functions[key]
+	}
+
+	for index, loc := range slices.SortedFunc(maps.Keys(file.Branches), compareLocations) {
+		id := strconv.Itoa(index)
+		istanbul.BranchMap[id] = IstanbulBranch{Loc: loc, Type: "block", Locations: []Location{loc}, Line: loc.Start.Line}
+		istanbul.B[id] = []int{file.Branches[loc]}
+	}
+
+	return istanbul
+}
+
+// WriteIstanbul writes report to w as an istanbul-lib-coverage JSON coverage map, keyed by path, such as nyc's coverage-final.json.
+func (report *Report) WriteIstanbul(w io.Writer) error {
+	coverageMap := make(map[string]*IstanbulFileCoverage, len(report.Files))
+
+	for path, file := range report.Files {
+		coverageMap[path] = file.Istanbul()
+	}
+
+	err := json.NewEncoder(w).Encode(coverageMap)
+
+	if err != nil {
+		return fmt.Errorf("Error writing Istanbul coverage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteIstanbul(t *testing.T) {
+	report := NewReport()
+	report.Add(testScript, testGenerated, parseTestMap(t), Options{})
+
+	buffer := &bytes.Buffer{}
+
+	err := report.WriteIstanbul(buffer)
+
+	if err != nil {
+		t.Fatalf("Error writing coverage: %v", err)
+	}
+
+	coverageMap := map[string]*IstanbulFileCoverage{}
+
+	err = json.Unmarshal(buffer.Bytes(), &coverageMap)
+
+	if err != nil {
+		t.Fatalf("Error parsing coverage: %v", err)
+	}
+
+	file, ok := coverageMap["tests/fixtures/simple/original.js"]
+
+	if !ok {
+		t.Fatalf("Expected coverage of original.js, got %s", buffer.String())
+	}
+
+	if len(file.StatementMap) != 3 || file.S["0"] != 0 || file.S["2"] != 1 || file.StatementMap["2"].Start.Line != 3 {
+		t.Errorf("Unexpected statements %v %v", file.StatementMap, file.S)
+	}
+
+	if file.FnMap["0"].Name != "abcd" || file.F["0"] != 0 || len(file.B) != 0 {
+		t.Errorf("Unexpected functions %v %v", file.FnMap, file.F)
+	}
+}
@@ -0,0 +1,75 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// WriteLCOV writes report to w in the LCOV tracefile format read by genhtml and most coverage services,
+// one record per original source in path order.
+func (report *Report) WriteLCOV(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+
+	for _, path := range report.Paths() {
+		istanbul := report.Files[path].Istanbul()
+
+		fmt.Fprintln(buffered, "TN:")
+		fmt.Fprintf(buffered, "SF:%s\n", path)
+
+		hit := 0
+
+		for index := range len(istanbul.FnMap) {
+			function := istanbul.FnMap[fmt.Sprint(index)]
+			fmt.Fprintf(buffered, "FN:%d,%s\n", function.Line, function.Name)
+		}
+
+		for index := range len(istanbul.FnMap) {
+			id := fmt.Sprint(index)
+			fmt.Fprintf(buffered, "FNDA:%d,%s\n", istanbul.F[id], istanbul.FnMap[id].Name)
+
+			if istanbul.F[id] > 0 {
+				hit++
+			}
+		}
+
+		fmt.Fprintf(buffered, "FNF:%d\nFNH:%d\n", len(istanbul.FnMap), hit)
+
+		hit = 0
+
+		for index := range len(istanbul.BranchMap) {
+			id := fmt.Sprint(index)
+			fmt.Fprintf(buffered, "BRDA:%d,%d,0,%d\n", istanbul.BranchMap[id].Line, index, istanbul.B[id][0])
+
+			if istanbul.B[id][0] > 0 {
+				hit++
+			}
+		}
+
+		fmt.Fprintf(buffered, "BRF:%d\nBRH:%d\n", len(istanbul.BranchMap), hit)
+
+		lines := report.Files[path].Lines()
+		hit = 0
+
+		for _, line := range slices.Sorted(maps.Keys(lines)) {
+			fmt.Fprintf(buffered, "DA:%d,%d\n", line, lines[line])
+
+			if lines[line] > 0 {
+				hit++
+			}
+		}
+
+		fmt.Fprintf(buffered, "LF:%d\nLH:%d\n", len(lines), hit)
+		fmt.Fprintln(buffered, "end_of_record")
+	}
+
+	err := buffered.Flush()
+
+	if err != nil {
+		return fmt.Errorf("Error writing LCOV coverage: %w", err)
+	}
+
+	return nil
+}
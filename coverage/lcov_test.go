@@ -0,0 +1,38 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteLCOV(t *testing.T) {
+	report := NewReport()
+	report.Add(testScript, testGenerated, parseTestMap(t), Options{})
+
+	buffer := &bytes.Buffer{}
+
+	err := report.WriteLCOV(buffer)
+
+	if err != nil {
+		t.Fatalf("Error writing coverage: %v", err)
+	}
+
+	expected := `TN:
+SF:tests/fixtures/simple/original.js
+FN:2,abcd
+FNDA:0,abcd
+FNF:1
+FNH:0
+BRF:0
+BRH:0
+DA:2,0
+DA:3,1
+LF:2
+LH:1
+end_of_record
+`
+
+	if buffer.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buffer.String())
+	}
+}
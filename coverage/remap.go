@@ -0,0 +1,369 @@
+package coverage
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// maxFunctionNameDistance is how far after the start of a function, in UTF-16 units, its name is looked for
+// to recover the original name of minified functions.
+const maxFunctionNameDistance = 64
+
+// Position is a position in an original source, with a 1-based line and a 0-based column, as Istanbul uses.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Location is a range of an original source.
+type Location struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// FunctionKey identifies a function of an original source.
+type FunctionKey struct {
+	Name string
+	Loc  Location
+}
+
+// FileCoverage is the remapped coverage of one original source. Every map holds the execution count of its keys.
+type FileCoverage struct {
+	// Path is the path of the original source, normalized according to Options.Layout
+	Path string
+	// Statements are the mapped segments of the original source
+	Statements map[Location]int
+	// Functions are the functions V8 reported, at their original position
+	Functions map[FunctionKey]int
+	// Branches are the blocks V8 reported inside functions, at their original position
+	Branches map[Location]int
+}
+
+func newFileCoverage(path string) *FileCoverage {
+	return &FileCoverage{
+		Path:       path,
+		Statements: make(map[Location]int),
+		Functions:  make(map[FunctionKey]int),
+		Branches:   make(map[Location]int),
+	}
+}
+
+// Lines returns the execution count of every line with a statement, the highest count of the statements starting on it.
+func (file *FileCoverage) Lines() map[int]int {
+	lines := make(map[int]int)
+
+	for loc, count := range file.Statements {
+		if previous, ok := lines[loc.Start.Line]; !ok || count > previous {
+			lines[loc.Start.Line] = count
+		}
+	}
+
+	return lines
+}
+
+// Options controls how coverage is remapped.
+type Options struct {
+	// KeepIgnored keeps the coverage of sources in the source map's ignoreList, which is dropped by default
+	KeepIgnored bool
+	// Layout turns source urls into the paths of the report, tools.LayoutClean by default
+	Layout tools.Layout
+}
+
+// Report is remapped coverage, by original source path.
+type Report struct {
+	Files map[string]*FileCoverage
+}
+
+// NewReport returns an empty Report.
+func NewReport() *Report {
+	return &Report{Files: make(map[string]*FileCoverage)}
+}
+
+// Paths returns the path of every original source in report, sorted.
+func (report *Report) Paths() []string {
+	return slices.Sorted(maps.Keys(report.Files))
+}
+
+// Merge adds the counts of other to report, as when combining the coverage of several scripts or test runs.
+func (report *Report) Merge(other *Report) {
+	for path, otherFile := range other.Files {
+		file, ok := report.Files[path]
+
+		if !ok {
+			file = newFileCoverage(path)
+			report.Files[path] = file
+		}
+
+		for loc, count := range otherFile.Statements {
+			file.Statements[loc] += count
+		}
+
+		for key, count := range otherFile.Functions {
+			file.Functions[key] += count
+		}
+
+		for loc, count := range otherFile.Branches {
+			file.Branches[loc] += count
+		}
+	}
+}
+
+// Add remaps the coverage of script, whose contents are generated, through mapRecord, and merges it into report.
+// script.Source is used if generated is "".
+// When several generated positions map to the same original position, the highest count is used.
+func (report *Report) Add(script ScriptCoverage, generated string, mapRecord *spec.DecodedSourceMapRecord, options Options) error {
+	if generated == "" {
+		generated = script.Source
+	}
+
+	if generated == "" {
+		return fmt.Errorf("Error remapping coverage of %s: the contents of the script are unknown", script.Url)
+	}
+
+	remapper := newRemapper(generated, mapRecord, options)
+	remapper.paint(script.Functions)
+	remapper.statements()
+	remapper.functions(script.Functions)
+
+	report.Merge(remapper.report)
+
+	return nil
+}
+
+// remapper remaps the coverage of one script.
+type remapper struct {
+	options   Options
+	mapRecord *spec.DecodedSourceMapRecord
+	// lines are the lines of the generated script, and lineStarts their offsets in UTF-16 units
+	lines      []string
+	lineStarts []int
+	// counts is the execution count of every UTF-16 unit of the generated script, -1 if no range covers it
+	counts []int32
+	report *Report
+}
+
+func newRemapper(generated string, mapRecord *spec.DecodedSourceMapRecord, options Options) *remapper {
+	remapper := &remapper{
+		options:   options,
+		mapRecord: mapRecord,
+		lines:     tools.SplitLines(generated),
+		report:    NewReport(),
+	}
+
+	remapper.lineStarts = make([]int, len(remapper.lines))
+	offset := 0
+	position := 0
+
+	for i, line := range remapper.lines {
+		remapper.lineStarts[i] = offset
+		offset += tools.UTF16Length(line)
+		position += len(line)
+
+		// Count the line terminator, \r\n is two units
+		if position < len(generated) && generated[position] == '\r' {
+			offset++
+			position++
+		}
+
+		if position < len(generated) && generated[position] == '\n' {
+			offset++
+			position++
+		}
+	}
+
+	remapper.counts = make([]int32, offset)
+
+	for i := range remapper.counts {
+		remapper.counts[i] = -1
+	}
+
+	return remapper
+}
+
+// paint records the count of every range on counts. Inner ranges are painted after the ranges containing them,
+// so every unit ends up with the count of the innermost range covering it.
+func (remapper *remapper) paint(functions []Function) {
+	ranges := make([]Range, 0)
+
+	for _, function := range functions {
+		ranges = append(ranges, function.Ranges...)
+	}
+
+	sort.SliceStable(ranges, func(i int, j int) bool {
+		if ranges[i].StartOffset != ranges[j].StartOffset {
+			return ranges[i].StartOffset < ranges[j].StartOffset
+		}
+
+		return ranges[i].EndOffset > ranges[j].EndOffset
+	})
+
+	for _, r := range ranges {
+		for offset := max(r.StartOffset, 0); offset < min(r.EndOffset, len(remapper.counts)); offset++ {
+			remapper.counts[offset] = int32(r.Count)
+		}
+	}
+}
+
+// generatedPosition returns the 0-based generated line and column of the UTF-16 offset.
+func (remapper *remapper) generatedPosition(offset int) (int, int) {
+	line := sort.Search(len(remapper.lineStarts), func(i int) bool {
+		return remapper.lineStarts[i] > offset
+	}) - 1
+
+	return max(line, 0), offset - remapper.lineStarts[max(line, 0)]
+}
+
+// file returns the FileCoverage of source, or nil if source is nil or ignored.
+func (remapper *remapper) file(source *spec.DecodedSourceRecord) *FileCoverage {
+	if source == nil || (source.Ignored && !remapper.options.KeepIgnored) {
+		return nil
+	}
+
+	path := tools.NormalizeSourcePath(source.Url, remapper.options.Layout)
+	file, ok := remapper.report.Files[path]
+
+	if !ok {
+		file = newFileCoverage(path)
+		remapper.report.Files[path] = file
+	}
+
+	return file
+}
+
+// statements records every mapping of the script as a statement, with the count of its first generated unit.
+func (remapper *remapper) statements() {
+	mappings := remapper.mapRecord.Mappings
+
+	for i, mapping := range mappings {
+		if mapping.GeneratedLine >= len(remapper.lines) {
+			break
+		}
+
+		file := remapper.file(mapping.OriginalSource)
+		offset := remapper.lineStarts[mapping.GeneratedLine] + mapping.GeneratedColumn
+
+		if file == nil || offset >= len(remapper.counts) {
+			continue
+		}
+
+		length := tools.UTF16Length(remapper.lines[mapping.GeneratedLine]) - mapping.GeneratedColumn
+
+		if i+1 < len(mappings) && mappings[i+1].GeneratedLine == mapping.GeneratedLine {
+			length = mappings[i+1].GeneratedColumn - mapping.GeneratedColumn
+		}
+
+		start := Position{Line: mapping.OriginalLine + 1, Column: mapping.OriginalColumn}
+		loc := Location{Start: start, End: Position{Line: start.Line, Column: start.Column + max(length, 1)}}
+		count := int(max(remapper.counts[offset], 0))
+
+		if previous, ok := file.Statements[loc]; !ok || count > previous {
+			file.Statements[loc] = count
+		}
+	}
+}
+
+// original returns the mapping covering the UTF-16 offset, or nil if it isn't mapped to a source that is kept.
+func (remapper *remapper) original(offset int) (*spec.DecodedMappingRecord, *FileCoverage) {
+	line, column := remapper.generatedPosition(offset)
+	mapping := tools.OriginalPositionFor(remapper.mapRecord, line, column)
+
+	if mapping == nil {
+		return nil, nil
+	}
+
+	file := remapper.file(mapping.OriginalSource)
+
+	if file == nil {
+		return nil, nil
+	}
+
+	return mapping, file
+}
+
+// location returns the original location of the generated range from start to end, which is only start if end is
+// mapped to another source.
+func (remapper *remapper) location(start *spec.DecodedMappingRecord, end int) Location {
+	loc := Location{Start: Position{Line: start.OriginalLine + 1, Column: start.OriginalColumn}}
+	loc.End = loc.Start
+
+	if endMapping, _ := remapper.original(max(end-1, 0)); endMapping != nil && endMapping.OriginalSource == start.OriginalSource {
+		loc.End = Position{Line: endMapping.OriginalLine + 1, Column: endMapping.OriginalColumn}
+	}
+
+	return loc
+}
+
+// functions records every function of the script, and the blocks inside them as branches.
+func (remapper *remapper) functions(functions []Function) {
+	for index, function := range functions {
+		if len(function.Ranges) == 0 {
+			continue
+		}
+
+		whole := function.Ranges[0]
+
+		// The top level code of the script is reported first, as an anonymous function starting at 0
+		if index == 0 && whole.StartOffset == 0 && function.FunctionName == "" {
+			continue
+		}
+
+		mapping, file := remapper.original(whole.StartOffset)
+
+		if mapping == nil {
+			continue
+		}
+
+		key := FunctionKey{Name: remapper.functionName(function, index), Loc: remapper.location(mapping, whole.EndOffset)}
+
+		if previous, ok := file.Functions[key]; !ok || whole.Count > previous {
+			file.Functions[key] = whole.Count
+		}
+
+		for _, block := range function.Ranges[1:] {
+			blockMapping, blockFile := remapper.original(block.StartOffset)
+
+			if blockMapping == nil {
+				continue
+			}
+
+			loc := remapper.location(blockMapping, block.EndOffset)
+
+			if previous, ok := blockFile.Branches[loc]; !ok || block.Count > previous {
+				blockFile.Branches[loc] = block.Count
+			}
+		}
+	}
+}
+
+// functionName returns the original name of function, from the mapping of its minified name if it has one,
+// or the name reported by V8, or (anonymous_index).
+func (remapper *remapper) functionName(function Function, index int) string {
+	name := function.FunctionName
+
+	if name == "" {
+		return fmt.Sprintf("(anonymous_%d)", index)
+	}
+
+	line, column := remapper.generatedPosition(function.Ranges[0].StartOffset)
+	text := tools.SliceColumns(remapper.lines[line], column, column+maxFunctionNameDistance)
+	nameIndex := strings.Index(text, name)
+
+	if nameIndex < 0 {
+		return name
+	}
+
+	nameColumn := column + tools.UTF16Length(text[:nameIndex])
+	mapping := tools.OriginalPositionFor(remapper.mapRecord, line, nameColumn)
+
+	if mapping != nil && mapping.GeneratedColumn == nameColumn && mapping.Name != "" {
+		return mapping.Name
+	}
+
+	return name
+}
@@ -0,0 +1,88 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestReportAdd(t *testing.T) {
+	report := NewReport()
+
+	err := report.Add(testScript, testGenerated, parseTestMap(t), Options{})
+
+	if err != nil {
+		t.Fatalf("Error remapping coverage: %v", err)
+	}
+
+	file, ok := report.Files["tests/fixtures/simple/original.js"]
+
+	if !ok || len(report.Files) != 1 {
+		t.Fatalf("Expected coverage of original.js, got %v", report.Paths())
+	}
+
+	lines := file.Lines()
+
+	if len(lines) != 2 || lines[2] != 0 || lines[3] != 1 {
+		t.Errorf("Expected line 2 to be missed and line 3 to be hit, got %v", lines)
+	}
+
+	expectedFunction := FunctionKey{Name: "abcd", Loc: Location{Start: Position{Line: 2, Column: 0}, End: Position{Line: 2, Column: 9}}}
+
+	if count, ok := file.Functions[expectedFunction]; !ok || count != 0 || len(file.Functions) != 1 {
+		t.Errorf("Expected abcd to be remapped and never called, got %v", file.Functions)
+	}
+
+	// Coverage of the same script from a second run is summed
+	report.Add(testScript, testGenerated, parseTestMap(t), Options{})
+
+	if lines := file.Lines(); lines[3] != 2 {
+		t.Errorf("Expected line 3 to be hit twice, got %v", lines)
+	}
+}
+
+func TestReportAddIgnored(t *testing.T) {
+	mapRecord, err := spec.ParseSourceMap(`{"version": 3, "sources": ["node_modules/lib/index.js"], "names": [], "mappings": "AAAA", "ignoreList": [0]}`, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing source map: %v", err)
+	}
+
+	script := ScriptCoverage{Url: "lib.js", Source: "x()", Functions: []Function{{Ranges: []Range{{StartOffset: 0, EndOffset: 3, Count: 1}}}}}
+	report := NewReport()
+	report.Add(script, "", mapRecord, Options{})
+
+	if len(report.Files) != 0 {
+		t.Errorf("Expected ignored sources to be dropped, got %v", report.Paths())
+	}
+
+	report.Add(script, "", mapRecord, Options{KeepIgnored: true})
+
+	if len(report.Files) != 1 {
+		t.Errorf("Expected ignored sources to be kept, got %v", report.Paths())
+	}
+
+	if err := report.Add(ScriptCoverage{Url: "unknown.js"}, "", mapRecord, Options{}); err == nil {
+		t.Errorf("Expected an error without the contents of the script")
+	}
+}
+
+func TestPaintInnermostRange(t *testing.T) {
+	remapper := newRemapper("0123456789\r\nab", &spec.DecodedSourceMapRecord{}, Options{})
+	remapper.paint([]Function{
+		{Ranges: []Range{{StartOffset: 2, EndOffset: 6, Count: 0}}},
+		{Ranges: []Range{{StartOffset: 0, EndOffset: 14, Count: 3}, {StartOffset: 8, EndOffset: 9, Count: 1}}},
+	})
+
+	expected := []int32{3, 3, 0, 0, 0, 0, 3, 3, 1, 3, 3, 3, 3, 3}
+
+	for offset, count := range expected {
+		if remapper.counts[offset] != count {
+			t.Errorf("Offset %d: expected %d, got %d", offset, count, remapper.counts[offset])
+		}
+	}
+
+	if line, column := remapper.generatedPosition(13); line != 1 || column != 1 {
+		t.Errorf("Expected offset 13 to be 1:1, got %d:%d", line, column)
+	}
+}
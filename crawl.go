@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runCrawl implements go-sourcemap crawl, recovering every source map used by a web page.
+func runCrawl(arguments []string) {
+	flags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap crawl: go-sourcemap crawl [flags] url")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+	output := outputArgs{required: true}
+	crawler := tools.Crawler{}
+
+	fetch.register(flags)
+	output.register(flags)
+	flags.IntVar(&crawler.Concurrency, "j", 4, "Number of assets to download at once")
+	flags.BoolVar(&crawler.SameOrigin, "same-origin", true, "Only download assets and source maps from the page's origin, and -allow-host")
+	allowedHosts := flags.String("allow-host", "", "Comma separated hosts, e.g. a CDN, to download from in addition to the page's origin")
+	reportPath := flags.String("report", "", "File to save the JSON crawl report to. If not specified, the report is printed to stdout")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	if *allowedHosts != "" {
+		crawler.AllowedHosts = strings.Split(*allowedHosts, ",")
+	}
+
+	if output.outDir == "" && output.archive == "" {
+		fmt.Println("Either -d or -a is required")
+		os.Exit(-1)
+	}
+
+	var err error
+
+	crawler.Fetcher, err = fetch.build(flags.Arg(0))
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	crawler.Extract = tools.ExtractOptions{Layout: output.layout}
+
+	err = output.open()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	report, err := crawler.Crawl(context.Background(), flags.Arg(0), output.writer)
+
+	if err != nil {
+		output.close()
+		fmt.Printf("Error crawling %s: %v\n", flags.Arg(0), err)
+		os.Exit(-1)
+	}
+
+	recovered := 0
+
+	for _, crawled := range report.Maps {
+		if crawled.Report != nil {
+			output.record(crawled.Report)
+			recovered++
+		}
+	}
+
+	err = output.close()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	reportStr, err := json.MarshalIndent(report, "", "  ")
+
+	if err != nil {
+		fmt.Printf("Error stringifying report: %v\n", err)
+		os.Exit(-1)
+	}
+
+	if *reportPath != "" {
+		err = os.WriteFile(*reportPath, reportStr, 0600)
+
+		if err != nil {
+			fmt.Printf("Error writing %s: %v\n", *reportPath, err)
+			os.Exit(-1)
+		}
+	} else {
+		fmt.Println(string(reportStr))
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d assets: recovered %d source maps, skipped %d urls\n", len(report.Maps), recovered, len(report.Skipped))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runDiff implements go-sourcemap diff, comparing the sources, names and mappings of two source maps.
+func runDiff(arguments []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap diff: go-sourcemap diff [flags] old new")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+
+	fetch.register(flags)
+	jsonOutput := flags.Bool("json", false, "Print the diff as JSON")
+	limit := flags.Int("limit", 20, "Most sources, names and mappings of each kind to list in the readable diff, 0 for all")
+	exitCode := flags.Bool("exit-code", false, "Exit with status 1 if the source maps differ")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build(flags.Args()...)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	before, err := parseDiffInput(flags.Arg(0), fetcher)
+
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", flags.Arg(0), err)
+		os.Exit(-1)
+	}
+
+	after, err := parseDiffInput(flags.Arg(1), fetcher)
+
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", flags.Arg(1), err)
+		os.Exit(-1)
+	}
+
+	diff := tools.DiffSourceMaps(before, after)
+
+	if *jsonOutput {
+		err = json.NewEncoder(os.Stdout).Encode(diff)
+	} else {
+		err = diff.WriteText(os.Stdout, *limit)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if *exitCode && !diff.Equal() {
+		os.Exit(1)
+	}
+}
+
+// parseDiffInput parses the source map named by input like tools.ParseSourceMapFromInput, but keeps source urls as written
+// in the map rather than resolving them against input, so maps from different directories or hosts can be compared.
+func parseDiffInput(input string, fetcher *tools.Fetcher) (*spec.DecodedSourceMapRecord, error) {
+	contents, err := readInput(input, fetcher)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return spec.ParseSourceMap(contents, "")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// fetchArgs are the flags controlling how urls are downloaded, shared by every command.
+type fetchArgs struct {
+	fetcher         *tools.Fetcher
+	retries         int
+	cacheDir        string
+	credentialHosts string
+}
+
+// register adds the download flags to flags.
+func (args *fetchArgs) register(flags *flag.FlagSet) {
+	args.fetcher = &tools.Fetcher{Header: http.Header{}}
+
+	flags.Var(headerFlag(args.fetcher.Header), "H", "Header to send to the hosts of url inputs and -credential-host, of the form \"Name: value\". May be repeated")
+	flags.StringVar(&args.fetcher.BearerToken, "bearer", "", "Bearer token to send to the hosts of url inputs and -credential-host")
+	flags.StringVar(&args.credentialHosts, "credential-host", "", "Comma separated hosts to send -H and -bearer to, in addition to the hosts of url inputs")
+	flags.DurationVar(&args.fetcher.Timeout, "timeout", tools.DefaultFetcher.Timeout, "Timeout for each download")
+	flags.IntVar(&args.retries, "retries", 3, "Number of times to retry a download after a transient failure, with exponential backoff. 0 for a single attempt")
+	flags.StringVar(&args.cacheDir, "cache-dir", "", "Directory to cache downloads in, revalidated with ETag and Last-Modified")
+	flags.Int64Var(&args.fetcher.MaxBytes, "max-size", tools.DefaultMaxBytes, "Largest download in bytes, after decompression")
+}
+
+// build finishes configuring the fetcher once the flags have been parsed.
+// -H and -bearer are only sent to the hosts of the http(s) urls in inputs, and -credential-host.
+func (args *fetchArgs) build(inputs ...string) (*tools.Fetcher, error) {
+	if args.retries < 0 {
+		return nil, fmt.Errorf("Error: -retries must be at least 0, got %d", args.retries)
+	}
+
+	credentialHosts := make([]string, 0)
+
+	if args.credentialHosts != "" {
+		credentialHosts = strings.Split(args.credentialHosts, ",")
+	}
+
+	for _, input := range inputs {
+		if !isUrlInput(input) {
+			continue
+		}
+
+		inputUrl, err := url.Parse(input)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %w", input, err)
+		}
+
+		credentialHosts = append(credentialHosts, inputUrl.Host)
+	}
+
+	if (len(args.fetcher.Header) > 0 || args.fetcher.BearerToken != "") && len(credentialHosts) == 0 {
+		return nil, fmt.Errorf("Error: -H and -bearer are only sent to the hosts of url inputs, use -credential-host to name the hosts to send them to")
+	}
+
+	args.fetcher.CredentialHosts = credentialHosts
+
+	args.fetcher.Retry = &tools.RetryPolicy{MaxAttempts: args.retries + 1}
+
+	if args.cacheDir != "" {
+		cache, err := tools.NewDiskCache(args.cacheDir)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args.fetcher.Cache = cache
+	}
+
+	return args.fetcher, nil
+}
+
+// outputArgs are the flags controlling where extracted sources are written, shared by every command that extracts sources.
+type outputArgs struct {
+	// required is set by commands that have nothing to print, so either -d or -a must be given
+	required bool
+
+	outDir  string
+	archive string
+	layout  tools.Layout
+
+	// writer is where sources are extracted to, nil if neither -d nor -a was given
+	writer tools.SourceWriter
+	// manifest is every source written to writer
+	manifest []tools.WrittenSource
+
+	archiveWriter *tools.ArchiveWriter
+	archiveFile   *os.File
+}
+
+// register adds the output flags to flags.
+func (args *outputArgs) register(flags *flag.FlagSet) {
+	if args.required {
+		flags.StringVar(&args.outDir, "d", "", "Directory to save decoded source files. Either -d or -a is required")
+		flags.StringVar(&args.archive, "a", "", "Archive to save decoded source files to, a .zip, .tar, .tar.gz or .tgz file. Either -d or -a is required")
+	} else {
+		flags.StringVar(&args.outDir, "d", "", "Directory to save decoded source files. If not specified, decoded source map will be printed to stdout")
+		flags.StringVar(&args.archive, "a", "", "Archive to save decoded source files to, a .zip, .tar, .tar.gz or .tgz file")
+	}
+
+	flags.Func("layout", "How source urls are turned into paths under -d: clean, origin or raw (default clean)", func(value string) error {
+		layout, err := tools.ParseLayout(value)
+		args.layout = layout
+
+		return err
+	})
+}
+
+// open creates args.writer for -d or -a, if either was given.
+func (args *outputArgs) open() error {
+	if args.outDir != "" && args.archive != "" {
+		return fmt.Errorf("Cannot specify both -d and -a")
+	}
+
+	if args.outDir != "" {
+		writer, err := tools.NewDirectoryWriter(args.outDir)
+
+		if err != nil {
+			return err
+		}
+
+		args.writer = writer
+	} else if args.archive != "" {
+		format, err := tools.ArchiveFormatFromPath(args.archive)
+
+		if err != nil {
+			return err
+		}
+
+		args.archiveFile, err = os.OpenFile(args.archive, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+
+		if err != nil {
+			return fmt.Errorf("Error creating %s: %w", args.archive, err)
+		}
+
+		args.archiveWriter = tools.NewArchiveWriter(args.archiveFile, format)
+		args.writer = args.archiveWriter
+	}
+
+	return nil
+}
+
+// record adds the sources in report to the archive manifest, and prints anything rewritten, rejected or missing to stderr.
+func (args *outputArgs) record(report *tools.ExtractReport) {
+	args.manifest = append(args.manifest, report.Written...)
+
+	for _, rewritten := range report.Rewritten {
+		fmt.Fprintf(os.Stderr, "Rewrote %q to %q\n", rewritten.Url, rewritten.Path)
+	}
+
+	for _, rejected := range report.Rejected {
+		fmt.Fprintf(os.Stderr, "Skipped %q: not a usable path\n", rejected)
+	}
+
+	for _, missing := range report.Missing {
+		fmt.Fprintf(os.Stderr, "Skipped %q: no content: %s\n", missing.Url, missing.Error)
+	}
+
+	for _, duplicate := range report.Duplicates {
+		fmt.Fprintf(os.Stderr, "Skipped %q: %s was already written\n", duplicate.Url, duplicate.Path)
+	}
+
+	for _, conflict := range report.Conflicts {
+		fmt.Fprintf(os.Stderr, "Skipped %q: %s conflicts with another file or directory\n", conflict.Url, conflict.Path)
+	}
+}
+
+// close writes the archive manifest and closes the archive, if -a was given.
+func (args *outputArgs) close() error {
+	if args.archiveWriter == nil {
+		return nil
+	}
+
+	err := args.archiveWriter.WriteManifest(args.manifest)
+
+	if err == nil {
+		err = args.archiveWriter.Close()
+	}
+
+	if closeErr := args.archiveFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error saving %s: %w", args.archive, err)
+	}
+
+	return nil
+}
+
+// writeOutputFile creates path, and writes its contents with write.
+func writeOutputFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %w", path, err)
+	}
+
+	err = write(file)
+
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("Error writing %s: %w", path, closeErr)
+	}
+
+	return err
+}
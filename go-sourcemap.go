@@ -1,17 +1,101 @@
-// go-sourcemap parses a sourcemap, and either prints the sourcemap to stdout, or saves the maps sources to a directory.
+// go-sourcemap parses one or more sourcemaps, and either prints the sourcemaps to stdout, or saves the maps sources to a directory.
 //
 //	Usage:
-//	    go-sourcemap [flags]
+//	    go-sourcemap [flags] [input ...]
+//	Each input is a file path, a glob pattern such as dist/*.map, an http(s) url, or - to read from stdin.
 //	The flags are:
 //	    -u
-//	        Url to download the source map from. Cannot be specified at the same time as -f.
+//	        Url to download the source map from. Added to the list of inputs.
 //	    -f
-//	        File to read the source map from. Cannot be specified at the same time as -u.
+//	        File to read the source map from. Added to the list of inputs.
 //	    -d
 //	        Directory to save decoded source files. If not specifed the decoded source map will be printed to stdout.
+//	        Sources are always written inside the directory, urls that would escape it are rewritten and reported on stderr.
+//	    -layout
+//	        How source urls are turned into paths under -d: clean (default), origin or raw.
+//	        clean strips bundler schemes such as webpack:// and file://, origin groups paths by scheme, and raw uses urls as is.
+//	    -a
+//	        Archive to save decoded source files to instead of a directory, a .zip, .tar, .tar.gz or .tgz file.
+//	        Sources are stored under sources/, with a manifest.json recording the url, ignored flag, hash and size of each.
+//	    -H
+//	        Header to send when downloading, of the form "Name: value". May be repeated.
+//	    -bearer
+//	        Bearer token to send when downloading.
+//	    -credential-host
+//	        Comma separated hosts to send -H and -bearer to. They are always sent to the hosts of url inputs, and never to
+//	        any other host, such as a host named in a source map or reached by a redirect.
+//	    -timeout
+//	        Timeout for each download, e.g. 30s.
+//	    -retries
+//	        Number of times to retry a download after a transient failure, with exponential backoff. 0 for a single attempt.
+//	        Retries give up after 2 minutes of waiting, or when the server asks to wait longer than 30s with Retry-After.
+//	    -max-size
+//	        Largest download in bytes, after decompression. gzip, deflate and brotli responses are decompressed.
+//	    -cache-dir
+//	        Directory to cache downloads in. Cached downloads are revalidated with ETag and Last-Modified.
+//	        Downloads made with -H or -bearer are cached separately, and responses marked no-store or private are not cached.
+//	    -format
+//	        Format of the decoded source map printed to stdout when -d and -a are not given:
+//	        json (default) repeats each mapping's full original source, pretty is indented JSON with mappings referencing sources by index,
+//	        ndjson prints one source or mapping per line, compact is pretty on a single line without source contents,
+//	        and table prints a human readable table of generated line:column -> source:line:column name.
+//	    -generated
+//	        Generated file the source map belongs to, used by -format table -snippets to show generated code.
+//	    -snippets
+//	        Show the generated and original code of each mapping with -format table.
+//	    -source, -name, -lines
+//	        Only show mappings with -format table whose original source url contains -source, whose name is -name,
+//	        or whose generated line is in -lines, e.g. 10-20 or 5.
+//	    -j
+//	        Number of inputs to parse at once. Results are printed as they complete, so with -j > 1 the order may differ from the inputs.
+//	    -fetch-missing
+//	        Download sources that have no sourcesContent from their url, resolved against the source map's url.
+//	        Sources of local source maps may also be read from the map's directory, but never from outside it.
+//	        -H and -bearer are only sent with sources on the hosts of url inputs and -credential-host.
+//	        Unresolved sources are reported on stderr.
+//	    -source-dir
+//	        Read sources that have no sourcesContent from this local checkout instead, using the clean layout.
+//
+// When more than one input is given, each decoded source map is printed as a single line of the form
+// {"input": ..., "sourceMap": ...}, and a summary of successes and failures is printed to stderr.
+//
+// go-sourcemap also has the following commands, see go-sourcemap <command> -h for their flags:
+//
+//	go-sourcemap coverage [flags] coverage.json ...
+//	    Remap V8 coverage of bundled scripts, as collected by Playwright or NODE_V8_COVERAGE, to their original sources,
+//	    and write it as LCOV or Istanbul JSON. Sources in the ignoreList are dropped unless -keep-ignored is given.
+//	go-sourcemap crawl [flags] url
+//	    Recover the source maps of every script and stylesheet used by the web page at url.
+//	go-sourcemap diff [flags] old new
+//	    Compare two source maps: sources added, removed or with changed content, names, and where each original position
+//	    is now mapped from. Prints a readable report, or JSON with -json.
+//	go-sourcemap har [flags] file.har
+//	    Recover the source maps of the scripts and stylesheets recorded in a HAR file, without using the network unless -network is given.
+//	go-sourcemap profile [flags] file.cpuprofile
+//	    Remap a Chrome DevTools or node --cpu-prof .cpuprofile to the original functions of the scripts it sampled,
+//	    and write it as a .cpuprofile, or with -pprof, in pprof format for go tool pprof.
+//	go-sourcemap sbom [flags] input ...
+//	    Detect the npm packages, and their versions where the paths reveal them, whose sources are in the source maps,
+//	    and write them as a CycloneDX or, with -format spdx, an SPDX JSON SBOM.
+//	go-sourcemap secrets [flags] input ...
+//	    Scan the sourcesContent of source maps, or with -crawl url of every source map used by a web page, for API keys,
+//	    private keys, internal hostnames, credentials in TODO comments and other sensitive data. Secrets are redacted
+//	    unless -reveal is given, and -rules adds custom rules.
+//	go-sourcemap serve [flags] -dir maps
+//	    Answer position lookups, stack trace symbolication and validation requests over HTTP, see package server.
+//	go-sourcemap size [flags] bundle.js ...
+//	    Attribute every byte of bundles to the original source it came from, and print the bytes of every directory and
+//	    node_modules package as a tree, as JSON with -json, or as a treemap with -html report.html.
+//	go-sourcemap stats [flags] input ...
+//	    Print metrics that reveal degraded source maps, such as the share of generated code that is mapped,
+//	    sources without content and unused sources or names. Use -json for dashboards.
+//	go-sourcemap visualize [flags] input
+//	    Render the source map and its generated file side by side as a self-contained HTML page, e.g. with -o report.html.
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -23,57 +107,232 @@ import (
 type sourceMapArgs struct {
 	url    string
 	file   string
-	outDir string
+	inputs []string
+
+	fetchMissing bool
+	sourceDir    string
+	workers      int
+	format       tools.OutputFormat
+	table        tools.TableOptions
+	generated    string
+
+	fetch  fetchArgs
+	output outputArgs
+
+	// fetcher downloads url inputs and missing sources
+	fetcher *tools.Fetcher
+}
+
+// inputResult is the per-input output used when more than one input is given.
+type inputResult struct {
+	Input     string `json:"input"`
+	SourceMap any    `json:"sourceMap"`
+}
+
+// commands are the subcommands of go-sourcemap, each called with the arguments following its name.
+var commands = map[string]func(arguments []string){
+	"coverage":  runCoverage,
+	"crawl":     runCrawl,
+	"diff":      runDiff,
+	"har":       runHar,
+	"profile":   runProfile,
+	"sbom":      runSbom,
+	"secrets":   runSecrets,
+	"serve":     runServe,
+	"size":      runSize,
+	"stats":     runStats,
+	"visualize": runVisualize,
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	args := sourceMapArgs{}
 
 	flag.StringVar(&args.url, "u", "", "url to download from")
 	flag.StringVar(&args.file, "f", "", "path to location of sourcemap file")
-	flag.StringVar(&args.outDir, "d", "", "Directory to save decoded source files. If not specified, decoded source map will be printed to stdout")
+	args.output.register(flag.CommandLine)
+	args.fetch.register(flag.CommandLine)
+	flag.BoolVar(&args.fetchMissing, "fetch-missing", false, "Download sources without sourcesContent from their url, or read them from the directory of local source maps")
+	flag.StringVar(&args.sourceDir, "source-dir", "", "Local checkout to read sources without sourcesContent from")
+	flag.IntVar(&args.workers, "j", 1, "Number of inputs to parse at once")
+	flag.Func("format", "Format of the decoded source map printed to stdout: json, pretty, ndjson, compact or table (default json)", func(value string) error {
+		format, err := tools.ParseOutputFormat(value)
+		args.format = format
+
+		return err
+	})
+	flag.StringVar(&args.generated, "generated", "", "Generated file the source map belongs to, for -format table -snippets")
+	flag.BoolVar(&args.table.Snippets, "snippets", false, "Show the generated and original code of each mapping with -format table")
+	flag.StringVar(&args.table.Source, "source", "", "Only show mappings with -format table whose original source url contains this")
+	flag.StringVar(&args.table.Name, "name", "", "Only show mappings with -format table with this name")
+	flag.Func("lines", "Only show mappings with -format table on these generated lines, e.g. 10-20 or 5", func(value string) error {
+		var err error
+		args.table.FromLine, args.table.ToLine, err = parseLineRange(value)
+
+		return err
+	})
 
 	flag.Parse()
 
-	if args.url == "" && args.file == "" {
-		fmt.Println("Either -u or -f is required")
-		os.Exit(-1)
+	if args.url != "" {
+		args.inputs = append(args.inputs, args.url)
+	}
+
+	if args.file != "" {
+		args.inputs = append(args.inputs, args.file)
 	}
 
-	if args.url != "" && args.file != "" {
-		fmt.Println("Cannot specify both -u and -f")
+	args.inputs = append(args.inputs, flag.Args()...)
+
+	if len(args.inputs) == 0 {
+		fmt.Println("At least one input is required, either -u, -f, or a file, url, glob or - for stdin")
 		os.Exit(-1)
 	}
 
-	var decoded *spec.DecodedSourceMapRecord
-	var err error
+	fetcher, err := args.fetch.build(args.inputs...)
 
-	if args.url != "" {
-		decoded, err = tools.ParseSourceMapFromUrl(args.url)
-	} else if args.file != "" {
-		decoded, err = tools.ParseSourceMapFromFile(args.file)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
 	}
 
+	args.fetcher = fetcher
+
+	inputs, err := expandInputs(args.inputs)
+
 	if err != nil {
-		fmt.Printf("Error parsing source map from %s: %v\n", args.url, err)
+		fmt.Println(err)
 		os.Exit(-1)
 	}
 
-	if args.outDir != "" {
-		err := tools.SaveSourcesToDirectory(decoded, args.outDir)
+	if args.generated != "" {
+		generated, err := os.ReadFile(args.generated)
 
 		if err != nil {
-			fmt.Printf("Error saving sources to %s: %v\n", args.outDir, err)
+			fmt.Printf("Error reading contents of %s: %v\n", args.generated, err)
 			os.Exit(-1)
 		}
-	} else {
-		decodedStr, err := tools.MarshalDecodedSourceMapRecord(decoded)
+
+		args.table.Generated = string(generated)
+	}
+
+	err = args.output.open()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	failed := 0
+	batch := tools.Batch{Workers: args.workers, Fetcher: args.fetcher}
+
+	for result := range batch.Parse(context.Background(), inputs) {
+		err := result.Err
+
+		if err != nil {
+			err = fmt.Errorf("Error parsing source map from %s: %w", result.Input, err)
+		} else {
+			err = processInput(result.Input, result.Record, &args, len(inputs) > 1)
+		}
 
 		if err != nil {
-			fmt.Printf("Error stringifying decodedStr: %v\n", err)
-			os.Exit(-1)
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+		}
+	}
+
+	err = args.output.close()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if len(inputs) > 1 {
+		fmt.Fprintf(os.Stderr, "Processed %d inputs: %d succeeded, %d failed\n", len(inputs), len(inputs)-failed, failed)
+	}
+
+	if failed > 0 {
+		os.Exit(-1)
+	}
+}
+
+// processInput either saves the sources of decoded, parsed from input, to args.output or prints it to stdout.
+// If wrap is true, the printed source map is wrapped in an inputResult.
+func processInput(input string, decoded *spec.DecodedSourceMapRecord, args *sourceMapArgs, wrap bool) error {
+	if args.output.writer != nil {
+		options := tools.ExtractOptions{Layout: args.output.layout}
+
+		if args.sourceDir != "" {
+			options.ResolveContent = tools.LocalContentResolver(args.sourceDir)
+		} else if args.fetchMissing {
+			options.ResolveContent = args.fetcher.SourceResolver(input)
+		}
+
+		report, err := tools.ExtractSources(decoded, args.output.writer, options)
+		args.output.record(report)
+
+		if err != nil {
+			return fmt.Errorf("Error saving sources of %s: %w", input, err)
 		}
 
-		fmt.Println(decodedStr)
+		return nil
+	}
+	if args.format == tools.FormatTable {
+		if wrap {
+			fmt.Printf("==> %s <==\n", input)
+		}
+
+		return tools.RenderMappingTable(os.Stdout, decoded, args.table)
+	}
+
+	if !wrap {
+		return tools.WriteDecodedSourceMapRecord(os.Stdout, decoded, args.format)
 	}
+
+	if args.format == tools.FormatNDJSON {
+		// Each input's sources and mappings follow a line naming the input
+		err := newStdoutEncoder().Encode(map[string]string{"type": "input", "input": input})
+
+		if err != nil {
+			return fmt.Errorf("Error stringifying %s: %w", input, err)
+		}
+
+		return tools.WriteDecodedSourceMapRecord(os.Stdout, decoded, args.format)
+	}
+
+	result := inputResult{Input: input, SourceMap: decoded}
+
+	if args.format == tools.FormatPretty || args.format == tools.FormatCompact {
+		result.SourceMap = tools.IndexDecodedSourceMapRecord(decoded, args.format == tools.FormatPretty)
+	}
+
+	encoder := newStdoutEncoder()
+
+	if args.format == tools.FormatPretty {
+		encoder.SetIndent("", "  ")
+	}
+
+	err := encoder.Encode(result)
+
+	if err != nil {
+		return fmt.Errorf("Error stringifying %s: %w", input, err)
+	}
+
+	return nil
+}
+
+// newStdoutEncoder returns a JSON encoder writing to stdout that leaves <, > and & in source content as is,
+// like the unwrapped output formats.
+func newStdoutEncoder() *json.Encoder {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+
+	return encoder
 }
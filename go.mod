@@ -1,3 +1,5 @@
 module github.com/redawl/go-sourcemap
 
 go 1.23
+
+require github.com/andybalholm/brotli v1.2.0
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runHar implements go-sourcemap har, recovering the source maps of the scripts recorded in a HAR file.
+func runHar(arguments []string) {
+	flags := flag.NewFlagSet("har", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap har: go-sourcemap har [flags] file.har")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+	output := outputArgs{}
+
+	fetch.register(flags)
+	output.register(flags)
+	network := flags.Bool("network", false, "Download source maps that were not recorded in the HAR. By default the network is never used")
+	reportPath := flags.String("report", "", "File to save the JSON report to. If not specified, the report is printed to stdout")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+
+	if err != nil {
+		fmt.Printf("Error opening %s: %v\n", flags.Arg(0), err)
+		os.Exit(-1)
+	}
+
+	har, err := tools.ParseHar(file)
+	file.Close()
+
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", flags.Arg(0), err)
+		os.Exit(-1)
+	}
+
+	reader := tools.HarReader{}
+
+	if *network {
+		reader.Resolution = tools.HarThenNetwork
+		reader.Fetcher, err = fetch.build()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+
+	var harMaps []tools.HarMap
+
+	if output.outDir == "" && output.archive == "" {
+		harMaps = reader.ParseSourceMaps(context.Background(), har)
+	} else {
+		err = output.open()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		harMaps, err = reader.ExtractSources(context.Background(), har, output.writer, tools.ExtractOptions{Layout: output.layout})
+
+		for _, harMap := range harMaps {
+			if harMap.Report != nil {
+				output.record(harMap.Report)
+			}
+		}
+
+		if closeErr := output.close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+
+	reportStr, err := json.MarshalIndent(harMaps, "", "  ")
+
+	if err != nil {
+		fmt.Printf("Error stringifying report: %v\n", err)
+		os.Exit(-1)
+	}
+
+	if *reportPath != "" {
+		err = os.WriteFile(*reportPath, reportStr, 0600)
+
+		if err != nil {
+			fmt.Printf("Error writing %s: %v\n", *reportPath, err)
+			os.Exit(-1)
+		}
+	} else {
+		fmt.Println(string(reportStr))
+	}
+
+	recovered := 0
+
+	for _, harMap := range harMaps {
+		if harMap.Record != nil {
+			recovered++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d assets: recovered %d source maps\n", len(harMaps), recovered)
+}
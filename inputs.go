@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// isUrlInput reports whether input should be downloaded rather than read from disk.
+func isUrlInput(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// readInput returns the contents of input, which is tools.StdinInput, an http(s) url downloaded with fetcher, or a file path.
+func readInput(input string, fetcher *tools.Fetcher) (string, error) {
+	if input == tools.StdinInput {
+		contents, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			return "", fmt.Errorf("Error reading stdin: %w", err)
+		}
+
+		return string(contents), nil
+	}
+
+	resolver := tools.ScriptMapResolver{Fetcher: fetcher}
+
+	return resolver.Read(input)
+}
+
+// expandInputs expands glob patterns in inputs, leaving urls, stdin and plain paths untouched.
+// Returns an error if a pattern is malformed or matches no files.
+func expandInputs(inputs []string) ([]string, error) {
+	expanded := make([]string, 0, len(inputs))
+
+	for _, input := range inputs {
+		if input == tools.StdinInput || isUrlInput(input) || !strings.ContainsAny(input, "*?[") {
+			expanded = append(expanded, input)
+			continue
+		}
+
+		matches, err := filepath.Glob(input)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error expanding %s: %w", input, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("Error expanding %s: no files matched", input)
+		}
+
+		expanded = append(expanded, matches...)
+	}
+
+	return expanded, nil
+}
+
+// headerFlag collects repeated -H "Name: value" flags into an http.Header.
+type headerFlag http.Header
+
+func (header headerFlag) String() string {
+	return ""
+}
+
+func (header headerFlag) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("Error: header %q is not of the form \"Name: value\"", value)
+	}
+
+	http.Header(header).Add(strings.TrimSpace(name), strings.TrimSpace(headerValue))
+
+	return nil
+}
+
+// parseLineRange parses a 1-based, inclusive line range of the form "from-to", "from-", "-to" or "line".
+func parseLineRange(value string) (int, int, error) {
+	fromStr, toStr, isRange := strings.Cut(value, "-")
+
+	if !isRange {
+		toStr = fromStr
+	}
+
+	from, to := 0, 0
+	var err error
+
+	if fromStr != "" {
+		from, err = strconv.Atoi(fromStr)
+	}
+
+	if err == nil && toStr != "" {
+		to, err = strconv.Atoi(toStr)
+	}
+
+	if err != nil || from < 0 || to < 0 || (to > 0 && from > to) {
+		return 0, 0, fmt.Errorf("Error: invalid line range %q, expected e.g. 10-20 or 5", value)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/redawl/go-sourcemap/profile"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runProfile implements go-sourcemap profile, remapping a .cpuprofile to the original sources of the scripts it sampled.
+func runProfile(arguments []string) {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap profile: go-sourcemap profile [flags] file.cpuprofile")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+
+	fetch.register(flags)
+	outPath := flags.String("o", "", "File to save the remapped .cpuprofile to. If neither -o nor -pprof is specified, it is printed to stdout")
+	pprofPath := flags.String("pprof", "", "File to save the remapped profile to in pprof format, for go tool pprof")
+	mapDir := flags.String("maps", "", "Directory of source maps named after their script, e.g. app.js.map. By default each script's sourceMappingURL is followed. "+
+		"Function names stay minified with -maps, since the scripts are not downloaded")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+
+	if err != nil {
+		fmt.Printf("Error opening %s: %v\n", flags.Arg(0), err)
+		os.Exit(-1)
+	}
+
+	cpuProfile, err := profile.ParseCPUProfile(file)
+	file.Close()
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	resolver := &tools.ScriptMapResolver{Fetcher: fetcher, MapDir: *mapDir, Scripts: make(map[string]string)}
+	var readScript profile.ScriptReader
+
+	// Scripts are needed to find the minified function names, but are not downloaded with -maps
+	if *mapDir == "" {
+		readScript = func(url string) (string, error) {
+			script, err := resolver.Read(url)
+
+			if err == nil {
+				resolver.Scripts[url] = script
+			}
+
+			return script, err
+		}
+	}
+
+	report := cpuProfile.Remap(resolver.Resolve, readScript)
+
+	if *outPath == "" && *pprofPath == "" {
+		err = cpuProfile.Write(os.Stdout)
+	}
+
+	if *outPath != "" && err == nil {
+		err = writeOutputFile(*outPath, cpuProfile.Write)
+	}
+
+	if *pprofPath != "" && err == nil {
+		err = writeOutputFile(*pprofPath, cpuProfile.WritePprof)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	for scriptUrl, scriptErr := range report.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", scriptUrl, scriptErr)
+	}
+
+	fmt.Fprintf(os.Stderr, "Remapped %d nodes, %d without a mapping, %d scripts without a source map\n", report.Remapped, report.Unmapped, len(report.Errors))
+}
@@ -0,0 +1,238 @@
+package profile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// protoBuffer encodes the protocol buffer messages of profile.proto, which is all pprof needs,
+// without depending on a protobuf library.
+type protoBuffer struct {
+	data []byte
+}
+
+func (buffer *protoBuffer) varint(value uint64) {
+	for value >= 0x80 {
+		buffer.data = append(buffer.data, byte(value)|0x80)
+		value >>= 7
+	}
+
+	buffer.data = append(buffer.data, byte(value))
+}
+
+// tag writes the key of field with the wire type, 0 for varints and 2 for length delimited fields.
+func (buffer *protoBuffer) tag(field int, wireType int) {
+	buffer.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+// int64 writes a varint field, omitting zero values as proto3 does.
+func (buffer *protoBuffer) int64(field int, value int64) {
+	if value == 0 {
+		return
+	}
+
+	buffer.tag(field, 0)
+	buffer.varint(uint64(value))
+}
+
+func (buffer *protoBuffer) bytes(field int, value []byte) {
+	buffer.tag(field, 2)
+	buffer.varint(uint64(len(value)))
+	buffer.data = append(buffer.data, value...)
+}
+
+func (buffer *protoBuffer) string(field int, value string) {
+	buffer.bytes(field, []byte(value))
+}
+
+// packed writes a packed repeated varint field.
+func (buffer *protoBuffer) packed(field int, values []int64) {
+	packed := &protoBuffer{}
+
+	for _, value := range values {
+		packed.varint(uint64(value))
+	}
+
+	buffer.bytes(field, packed.data)
+}
+
+// message writes a length delimited message field, built by encode.
+func (buffer *protoBuffer) message(field int, encode func(message *protoBuffer)) {
+	message := &protoBuffer{}
+	encode(message)
+	buffer.bytes(field, message.data)
+}
+
+// stringTable interns the strings of a pprof profile, whose first string must be "".
+type stringTable struct {
+	strings []string
+	indexes map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indexes: map[string]int64{"": 0}}
+}
+
+func (table *stringTable) index(value string) int64 {
+	if index, ok := table.indexes[value]; ok {
+		return index
+	}
+
+	table.indexes[value] = int64(len(table.strings))
+	table.strings = append(table.strings, value)
+
+	return table.indexes[value]
+}
+
+// pprofFunction identifies a function of the pprof profile.
+type pprofFunction struct {
+	name string
+	file string
+	line int
+}
+
+// WritePprof writes profile to w as a gzipped pprof profile, with a samples/count and a cpu/nanoseconds value per sample.
+// Each sample's stack is the path from its node up to, but excluding, the (root) node. A sample lasts until the next one,
+// so the time of each sample is the time delta of the sample that follows it, and the last sample lasts the mean interval.
+func (profile *CPUProfile) WritePprof(w io.Writer) error {
+	parents := make(map[int]int)
+	locationIds := make(map[int]int64)
+
+	for i, node := range profile.Nodes {
+		// pprof ids must not be 0, which node ids may be
+		locationIds[node.Id] = int64(i + 1)
+
+		for _, child := range node.Children {
+			parents[child] = node.Id
+		}
+	}
+
+	durations := make(map[int]int64)
+	counts := make(map[int]int64)
+	interval := int64(0)
+
+	if len(profile.Samples) > 1 {
+		interval = (profile.EndTime - profile.StartTime) / int64(len(profile.Samples))
+	}
+
+	for i, id := range profile.Samples {
+		counts[id]++
+
+		if i+1 < len(profile.TimeDeltas) {
+			durations[id] += profile.TimeDeltas[i+1] * 1000
+		} else {
+			durations[id] += interval * 1000
+		}
+	}
+
+	table := newStringTable()
+	out := &protoBuffer{}
+
+	out.message(1, func(valueType *protoBuffer) {
+		valueType.int64(1, table.index("samples"))
+		valueType.int64(2, table.index("count"))
+	})
+	out.message(1, func(valueType *protoBuffer) {
+		valueType.int64(1, table.index("cpu"))
+		valueType.int64(2, table.index("nanoseconds"))
+	})
+
+	functionIds := make(map[pprofFunction]int64)
+	locations := make(map[int]bool)
+
+	for i := range profile.Nodes {
+		node := &profile.Nodes[i]
+
+		if counts[node.Id] == 0 {
+			continue
+		}
+
+		stack := make([]int64, 0)
+		// A malformed profile may have cyclic children, which would otherwise make the walk loop forever
+		seen := make(map[int]bool)
+
+		for id, ok := node.Id, true; ok; id, ok = parents[id] {
+			locationId, known := locationIds[id]
+
+			if !known || seen[id] || profile.Nodes[locationId-1].CallFrame.FunctionName == "(root)" {
+				break
+			}
+
+			seen[id] = true
+
+			stack = append(stack, locationId)
+			locations[id] = true
+		}
+
+		out.message(2, func(sample *protoBuffer) {
+			sample.packed(1, stack)
+			sample.packed(2, []int64{counts[node.Id], durations[node.Id]})
+		})
+	}
+
+	for i := range profile.Nodes {
+		node := &profile.Nodes[i]
+
+		if !locations[node.Id] {
+			continue
+		}
+
+		frame := node.CallFrame
+		name := frame.FunctionName
+
+		if name == "" {
+			name = "(anonymous)"
+		}
+
+		function := pprofFunction{name: name, file: frame.Url, line: frame.LineNumber + 1}
+		functionId, ok := functionIds[function]
+
+		if !ok {
+			functionId = int64(len(functionIds) + 1)
+			functionIds[function] = functionId
+
+			out.message(5, func(message *protoBuffer) {
+				message.int64(1, functionId)
+				message.int64(2, table.index(function.name))
+				message.int64(3, table.index(function.name))
+				message.int64(4, table.index(function.file))
+				message.int64(5, int64(function.line))
+			})
+		}
+
+		out.message(4, func(location *protoBuffer) {
+			location.int64(1, locationIds[node.Id])
+			location.message(4, func(line *protoBuffer) {
+				line.int64(1, functionId)
+				line.int64(2, int64(frame.LineNumber+1))
+				line.int64(3, int64(frame.ColumnNumber+1))
+			})
+		})
+	}
+
+	out.int64(9, profile.StartTime*1000)
+	out.int64(10, (profile.EndTime-profile.StartTime)*1000)
+	out.message(11, func(valueType *protoBuffer) {
+		valueType.int64(1, table.index("cpu"))
+		valueType.int64(2, table.index("nanoseconds"))
+	})
+	out.int64(12, interval*1000)
+
+	// The string table must be complete, so it is written last
+	for _, value := range table.strings {
+		out.string(6, value)
+	}
+
+	compressed := gzip.NewWriter(w)
+
+	if _, err := compressed.Write(out.data); err != nil {
+		return fmt.Errorf("Error writing pprof profile: %w", err)
+	}
+
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("Error writing pprof profile: %w", err)
+	}
+
+	return nil
+}
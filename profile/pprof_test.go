@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readVarint reads a varint from data at position.
+func readVarint(data []byte, position *int) uint64 {
+	value := uint64(0)
+
+	for shift := 0; ; shift += 7 {
+		b := data[*position]
+		*position++
+		value |= uint64(b&0x7f) << shift
+
+		if b < 0x80 {
+			return value
+		}
+	}
+}
+
+// readFields decodes the top level fields of a protocol buffer message, varints as uint64 and the rest as []byte.
+func readFields(data []byte) map[int][]any {
+	fields := make(map[int][]any)
+
+	for position := 0; position < len(data); {
+		key := readVarint(data, &position)
+		field := int(key >> 3)
+
+		if key&7 == 0 {
+			fields[field] = append(fields[field], readVarint(data, &position))
+			continue
+		}
+
+		length := int(readVarint(data, &position))
+		fields[field] = append(fields[field], data[position:position+length])
+		position += length
+	}
+
+	return fields
+}
+
+func TestWritePprof(t *testing.T) {
+	profile := parseTestProfile(t)
+	profile.Remap(testResolver(t), testScripts)
+
+	buffer := &bytes.Buffer{}
+
+	err := profile.WritePprof(buffer)
+
+	if err != nil {
+		t.Fatalf("Error writing pprof: %v", err)
+	}
+
+	reader, err := gzip.NewReader(buffer)
+
+	if err != nil {
+		t.Fatalf("Expected a gzipped profile: %v", err)
+	}
+
+	data, _ := io.ReadAll(reader)
+	fields := readFields(data)
+
+	strings := make([]string, 0)
+
+	for _, value := range fields[6] {
+		strings = append(strings, string(value.([]byte)))
+	}
+
+	if strings[0] != "" || !slices.Contains(strings, "abcd") || !slices.Contains(strings, "tests/fixtures/simple/original.js") {
+		t.Errorf("Unexpected string table %q", strings)
+	}
+
+	if slices.Contains(strings, "(root)") {
+		t.Errorf("Expected (root) to be left out of stacks")
+	}
+
+	if len(fields[2]) != 3 || len(fields[4]) != 3 || len(fields[5]) != 3 {
+		t.Fatalf("Expected 3 samples, locations and functions, got %d, %d and %d", len(fields[2]), len(fields[4]), len(fields[5]))
+	}
+
+	// The samples of node 3, abcd called from the top level code of test1.js
+	sample := readFields(fields[2][1].([]byte))
+	stack := sample[1][0].([]byte)
+	values := sample[2][0].([]byte)
+
+	if !bytes.Equal(stack, []byte{3, 2}) {
+		t.Errorf("Expected the stack of abcd to be locations 3 and 2, got %v", stack)
+	}
+
+	if !bytes.Equal(values[:1], []byte{2}) {
+		t.Errorf("Expected abcd to be sampled twice, got %v", values)
+	}
+}
+
+func TestWritePprofCyclicChildren(t *testing.T) {
+	profile, err := ParseCPUProfile(strings.NewReader(`{
+		"nodes": [
+			{"id": 1, "callFrame": {"functionName": "a", "url": "a.js"}, "hitCount": 1, "children": [2]},
+			{"id": 2, "callFrame": {"functionName": "b", "url": "a.js"}, "hitCount": 1, "children": [1]}
+		],
+		"samples": [1, 2],
+		"timeDeltas": [0, 100]
+	}`))
+
+	if err != nil {
+		t.Fatalf("Error parsing profile: %v", err)
+	}
+
+	if err := profile.WritePprof(&bytes.Buffer{}); err != nil {
+		t.Errorf("Error writing pprof: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Package profile remaps Chrome DevTools CPU profiles (.cpuprofile) of bundled scripts to their original sources,
+// and exports them to the pprof format read by go tool pprof.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// maxNameDistance is how many generated columns after the start of a function its minified name is looked for.
+const maxNameDistance = 64
+
+// ScriptReader returns the contents of the script at url.
+type ScriptReader func(url string) (string, error)
+
+// CallFrame is the function a ProfileNode was sampled in. Lines and columns are 0-based.
+type CallFrame struct {
+	FunctionName string `json:"functionName"`
+	ScriptId     string `json:"scriptId"`
+	Url          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// PositionTick is the number of samples taken on a 1-based line of a function.
+type PositionTick struct {
+	Line  int `json:"line"`
+	Ticks int `json:"ticks"`
+}
+
+// ProfileNode is a node of the call tree of a CPUProfile.
+type ProfileNode struct {
+	Id            int            `json:"id"`
+	CallFrame     CallFrame      `json:"callFrame"`
+	HitCount      int            `json:"hitCount"`
+	Children      []int          `json:"children,omitempty"`
+	DeoptReason   string         `json:"deoptReason,omitempty"`
+	PositionTicks []PositionTick `json:"positionTicks,omitempty"`
+}
+
+// CPUProfile is a profile in the format of the DevTools protocol Profiler domain, as saved by Chrome DevTools,
+// Puppeteer and node --cpu-prof.
+type CPUProfile struct {
+	Nodes []ProfileNode `json:"nodes"`
+	// StartTime and EndTime are in microseconds
+	StartTime int64 `json:"startTime"`
+	EndTime   int64 `json:"endTime"`
+	// Samples are the ids of the nodes sampled, one per sample
+	Samples []int `json:"samples"`
+	// TimeDeltas are the microseconds between each sample and the previous one, or StartTime for the first sample
+	TimeDeltas []int64 `json:"timeDeltas"`
+}
+
+// ParseCPUProfile parses a .cpuprofile read from r.
+func ParseCPUProfile(r io.Reader) (*CPUProfile, error) {
+	profile := &CPUProfile{}
+
+	err := json.NewDecoder(r).Decode(profile)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing CPU profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// Write writes profile to w as a .cpuprofile.
+func (profile *CPUProfile) Write(w io.Writer) error {
+	err := json.NewEncoder(w).Encode(profile)
+
+	if err != nil {
+		return fmt.Errorf("Error writing CPU profile: %w", err)
+	}
+
+	return nil
+}
+
+// RemapReport describes what Remap did.
+type RemapReport struct {
+	// Remapped is the number of nodes moved to their original source
+	Remapped int `json:"remapped"`
+	// Unmapped is the number of nodes of scripts with a source map, whose position has no mapping
+	Unmapped int `json:"unmapped"`
+	// Errors are why the source map of a script could not be resolved, by script url
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Remap rewrites the url, position and function name of every call frame of profile to the original source, using the
+// source maps returned by resolve, which is called once per script url. Frames without a url, such as (root), (program)
+// and native functions, are left as is. The positionTicks of remapped nodes are dropped, since they only record
+// generated lines. Function names are only remapped if read, which may be nil, returns the script, see functionName.
+func (profile *CPUProfile) Remap(resolve tools.MapResolver, read ScriptReader) *RemapReport {
+	report := &RemapReport{Errors: make(map[string]string)}
+	mapRecords := make(map[string]*spec.DecodedSourceMapRecord)
+	scripts := make(map[string][]string)
+
+	for i := range profile.Nodes {
+		frame := &profile.Nodes[i].CallFrame
+
+		if frame.Url == "" || frame.LineNumber < 0 {
+			continue
+		}
+
+		mapRecord, ok := mapRecords[frame.Url]
+
+		if !ok {
+			// Read first, so a resolver that reads the script too can reuse it
+			if read != nil {
+				if script, err := read(frame.Url); err == nil {
+					scripts[frame.Url] = tools.SplitLines(script)
+				}
+			}
+
+			var err error
+			mapRecord, err = resolve(frame.Url)
+			mapRecords[frame.Url] = mapRecord
+
+			if err != nil {
+				report.Errors[frame.Url] = err.Error()
+			}
+		}
+
+		if mapRecord == nil {
+			continue
+		}
+
+		mapping := tools.OriginalPositionFor(mapRecord, frame.LineNumber, frame.ColumnNumber)
+
+		if mapping == nil || mapping.OriginalSource == nil {
+			report.Unmapped++
+			continue
+		}
+
+		frame.FunctionName = functionName(frame, mapRecord, scripts[frame.Url])
+		frame.Url = mapping.OriginalSource.Url
+		frame.LineNumber = mapping.OriginalLine
+		frame.ColumnNumber = mapping.OriginalColumn
+		profile.Nodes[i].PositionTicks = nil
+		report.Remapped++
+	}
+
+	return report
+}
+
+// functionName returns the original name of the function frame is in, from the mapping at the minified name following
+// the start of the function in lines, the generated script. Returns frame.FunctionName if it is empty, such as for
+// anonymous functions, or there is no mapping with a name exactly at the minified name, since the nearest named mapping
+// is as likely to be a parameter or a function it calls.
+func functionName(frame *CallFrame, mapRecord *spec.DecodedSourceMapRecord, lines []string) string {
+	name := frame.FunctionName
+
+	if name == "" || frame.LineNumber >= len(lines) {
+		return name
+	}
+
+	text := tools.SliceColumns(lines[frame.LineNumber], frame.ColumnNumber, frame.ColumnNumber+maxNameDistance)
+	nameIndex := strings.Index(text, name)
+
+	if nameIndex < 0 {
+		return name
+	}
+
+	nameColumn := frame.ColumnNumber + tools.UTF16Length(text[:nameIndex])
+	mapping := tools.OriginalPositionFor(mapRecord, frame.LineNumber, nameColumn)
+
+	if mapping != nil && mapping.GeneratedColumn == nameColumn && mapping.Name != "" {
+		return mapping.Name
+	}
+
+	return name
+}
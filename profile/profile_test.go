@@ -0,0 +1,139 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// testProfile samples test1.js, whose minified abcd function is defined at 1:0 and is called at 1:29.
+const testProfile = `{
+	"nodes": [
+		{"id": 1, "callFrame": {"functionName": "(root)", "scriptId": "0", "url": "", "lineNumber": -1, "columnNumber": -1}, "hitCount": 0, "children": [2, 4]},
+		{"id": 2, "callFrame": {"functionName": "", "scriptId": "1", "url": "https://example.com/test1.js", "lineNumber": 0, "columnNumber": 29}, "hitCount": 1, "children": [3]},
+		{"id": 3, "callFrame": {"functionName": "a", "scriptId": "1", "url": "https://example.com/test1.js", "lineNumber": 0, "columnNumber": 0}, "hitCount": 2, "positionTicks": [{"line": 1, "ticks": 2}]},
+		{"id": 4, "callFrame": {"functionName": "b", "scriptId": "2", "url": "https://example.com/other.js", "lineNumber": 0, "columnNumber": 0}, "hitCount": 1}
+	],
+	"startTime": 1000,
+	"endTime": 1400,
+	"samples": [3, 2, 3, 4],
+	"timeDeltas": [0, 100, 100, 100]
+}`
+
+func parseTestProfile(t *testing.T) *CPUProfile {
+	t.Helper()
+
+	profile, err := ParseCPUProfile(strings.NewReader(testProfile))
+
+	if err != nil {
+		t.Fatalf("Error parsing profile: %v", err)
+	}
+
+	return profile
+}
+
+func testResolver(t *testing.T) func(url string) (*spec.DecodedSourceMapRecord, error) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	mapRecord, err := spec.ParseSourceMap(string(contents), "")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	return func(url string) (*spec.DecodedSourceMapRecord, error) {
+		if url == "https://example.com/test1.js" {
+			return mapRecord, nil
+		}
+
+		return nil, errors.New("no source map")
+	}
+}
+
+// testScripts reads the minified test1.js that test1.js.map belongs to.
+func testScripts(url string) (string, error) {
+	if url == "https://example.com/test1.js" {
+		return "function a(){}export default a;", nil
+	}
+
+	return "", errors.New("no script")
+}
+
+func TestRemap(t *testing.T) {
+	profile := parseTestProfile(t)
+	report := profile.Remap(testResolver(t), testScripts)
+
+	if report.Remapped != 2 || report.Unmapped != 0 || len(report.Errors) != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	expected := []CallFrame{
+		{FunctionName: "(root)", ScriptId: "0", LineNumber: -1, ColumnNumber: -1},
+		{FunctionName: "", ScriptId: "1", Url: "tests/fixtures/simple/original.js", LineNumber: 2, ColumnNumber: 15},
+		{FunctionName: "abcd", ScriptId: "1", Url: "tests/fixtures/simple/original.js", LineNumber: 1, ColumnNumber: 0},
+		{FunctionName: "b", ScriptId: "2", Url: "https://example.com/other.js", LineNumber: 0, ColumnNumber: 0},
+	}
+
+	for i, frame := range expected {
+		if profile.Nodes[i].CallFrame != frame {
+			t.Errorf("Node %d: expected %+v, got %+v", i, frame, profile.Nodes[i].CallFrame)
+		}
+	}
+
+	if profile.Nodes[2].PositionTicks != nil {
+		t.Errorf("Expected the position ticks of remapped nodes to be dropped")
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := profile.Write(buffer); err != nil {
+		t.Fatalf("Error writing profile: %v", err)
+	}
+
+	written, err := ParseCPUProfile(buffer)
+
+	if err != nil || len(written.Nodes) != 4 || len(written.Samples) != 4 {
+		t.Errorf("Expected the written profile to parse, got %v", err)
+	}
+}
+
+func TestRemapFunctionNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{"name mapped", "function a(){}export default a;", "abcd"},
+		// The named mapping at column 9 is a parameter, not the function
+		{"name not mapped", "function q(a){}export default q;", "a"},
+		{"name not found", "function x(){}export default x;", "a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile := parseTestProfile(t)
+			profile.Remap(testResolver(t), func(url string) (string, error) {
+				return test.script, nil
+			})
+
+			if name := profile.Nodes[2].CallFrame.FunctionName; name != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, name)
+			}
+		})
+	}
+
+	profile := parseTestProfile(t)
+	profile.Remap(testResolver(t), nil)
+
+	if name := profile.Nodes[2].CallFrame.FunctionName; name != "a" {
+		t.Errorf("Expected the minified name without the script, got %q", name)
+	}
+}
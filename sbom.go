@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/redawl/go-sourcemap/sbom"
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runSbom implements go-sourcemap sbom, writing the npm packages found in source maps as an SBOM.
+func runSbom(arguments []string) {
+	flags := flag.NewFlagSet("sbom", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap sbom: go-sourcemap sbom [flags] input ...")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+
+	fetch.register(flags)
+	format := flags.String("format", "cyclonedx", "Format of the SBOM: cyclonedx or spdx")
+	outputPath := flags.String("o", "", "File to save the SBOM to instead of printing it")
+	name := flags.String("name", "", "Name of the application the source maps belong to (default application)")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	var write func(w io.Writer, packages []*tools.NpmPackage, options sbom.Options) error
+
+	switch *format {
+	case "cyclonedx":
+		write = sbom.WriteCycloneDX
+	case "spdx":
+		write = sbom.WriteSPDX
+	default:
+		fmt.Printf("Error: unknown format %q, expected cyclonedx or spdx\n", *format)
+		os.Exit(-1)
+	}
+
+	inputs, err := expandInputs(flags.Args())
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build(flags.Args()...)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	sources := make([]*spec.DecodedSourceRecord, 0)
+
+	for _, input := range inputs {
+		contents, err := readInput(input, fetcher)
+
+		if err == nil {
+			var mapRecord *spec.DecodedSourceMapRecord
+			mapRecord, err = spec.ParseSourceMap(contents, "")
+
+			if err == nil {
+				sources = append(sources, mapRecord.Sources...)
+			}
+		}
+
+		if err != nil {
+			fmt.Printf("Error reading source map %s: %v\n", input, err)
+			os.Exit(-1)
+		}
+	}
+
+	packages := tools.DetectPackages(sources)
+	options := sbom.Options{Name: *name}
+
+	if *outputPath == "" {
+		err = write(os.Stdout, packages, options)
+	} else {
+		err = writeOutputFile(*outputPath, func(w io.Writer) error {
+			return write(w, packages, options)
+		})
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
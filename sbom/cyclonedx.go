@@ -0,0 +1,109 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// cycloneDXBom is a CycloneDX 1.5 document, see https://cyclonedx.org/docs/1.5/json/.
+type cycloneDXBom struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	Type     string             `json:"type"`
+	BomRef   string             `json:"bom-ref,omitempty"`
+	Group    string             `json:"group,omitempty"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	Purl     string             `json:"purl,omitempty"`
+	Evidence *cycloneDXEvidence `json:"evidence,omitempty"`
+}
+
+// cycloneDXEvidence lists the source urls a component was found in.
+type cycloneDXEvidence struct {
+	Occurrences []cycloneDXOccurrence `json:"occurrences"`
+}
+
+type cycloneDXOccurrence struct {
+	Location string `json:"location"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes packages to w as a CycloneDX 1.5 JSON SBOM, with a library component for each package that
+// depends on the application component named options.Name.
+func WriteCycloneDX(w io.Writer, packages []*tools.NpmPackage, options Options) error {
+	options, err := options.withDefaults()
+
+	if err != nil {
+		return err
+	}
+
+	application := cycloneDXComponent{Type: "application", BomRef: "application", Name: options.Name}
+	bom := cycloneDXBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + options.Serial,
+		Version:      1,
+		Components:   make([]cycloneDXComponent, 0, len(packages)),
+	}
+	bom.Metadata.Timestamp = options.Created.Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: ToolName}}
+	bom.Metadata.Component = application
+
+	dependency := cycloneDXDependency{Ref: application.BomRef, DependsOn: make([]string, 0, len(packages))}
+
+	for _, npmPackage := range packages {
+		group, name := splitScope(npmPackage.Name)
+		purl := PackageURL(npmPackage.Name, npmPackage.Version)
+		component := cycloneDXComponent{
+			Type:     "library",
+			BomRef:   purl,
+			Group:    group,
+			Name:     name,
+			Version:  npmPackage.Version,
+			Purl:     purl,
+			Evidence: &cycloneDXEvidence{Occurrences: make([]cycloneDXOccurrence, len(npmPackage.Sources))},
+		}
+
+		for i, source := range npmPackage.Sources {
+			component.Evidence.Occurrences[i] = cycloneDXOccurrence{Location: source}
+		}
+
+		bom.Components = append(bom.Components, component)
+		dependency.DependsOn = append(dependency.DependsOn, purl)
+	}
+
+	bom.Dependencies = []cycloneDXDependency{dependency}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(bom); err != nil {
+		return fmt.Errorf("Error writing CycloneDX SBOM: %w", err)
+	}
+
+	return nil
+}
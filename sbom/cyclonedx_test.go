@@ -0,0 +1,63 @@
+package sbom
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteCycloneDX(t *testing.T) {
+	var out strings.Builder
+
+	if err := WriteCycloneDX(&out, testPackages, testOptions); err != nil {
+		t.Fatalf("Error writing SBOM: %v", err)
+	}
+
+	bom := cycloneDXBom{}
+
+	if err := json.Unmarshal([]byte(out.String()), &bom); err != nil {
+		t.Fatalf("Error parsing SBOM: %v", err)
+	}
+
+	if bom.BomFormat != "CycloneDX" || bom.SpecVersion != "1.5" || bom.SerialNumber != "urn:uuid:"+testOptions.Serial {
+		t.Errorf("Unexpected header %s %s %s", bom.BomFormat, bom.SpecVersion, bom.SerialNumber)
+	}
+
+	if bom.Metadata.Timestamp != "2024-01-02T02:04:05Z" {
+		t.Errorf("Expected timestamp 2024-01-02T02:04:05Z, got %s", bom.Metadata.Timestamp)
+	}
+
+	if bom.Metadata.Component.Name != "app" {
+		t.Errorf("Expected application app, got %s", bom.Metadata.Component.Name)
+	}
+
+	expected := []cycloneDXComponent{
+		{Type: "library", BomRef: "pkg:npm/%40babel/runtime@7.23.2", Group: "@babel", Name: "runtime", Version: "7.23.2", Purl: "pkg:npm/%40babel/runtime@7.23.2"},
+		{Type: "library", BomRef: "pkg:npm/react@18.2.0", Name: "react", Version: "18.2.0", Purl: "pkg:npm/react@18.2.0"},
+		{Type: "library", BomRef: "pkg:npm/scheduler", Name: "scheduler", Purl: "pkg:npm/scheduler"},
+	}
+
+	if len(bom.Components) != len(expected) {
+		t.Fatalf("Expected %d components, got %d", len(expected), len(bom.Components))
+	}
+
+	for i, component := range bom.Components {
+		evidence := component.Evidence
+		component.Evidence = nil
+
+		if component != expected[i] {
+			t.Errorf("Component %d: expected %+v, got %+v", i, expected[i], component)
+		}
+
+		if evidence == nil || len(evidence.Occurrences) != len(testPackages[i].Sources) || evidence.Occurrences[0].Location != testPackages[i].Sources[0] {
+			t.Errorf("Component %d: expected occurrences of %v, got %+v", i, testPackages[i].Sources, evidence)
+		}
+	}
+
+	dependsOn := []string{expected[0].BomRef, expected[1].BomRef, expected[2].BomRef}
+
+	if len(bom.Dependencies) != 1 || bom.Dependencies[0].Ref != "application" || !slices.Equal(bom.Dependencies[0].DependsOn, dependsOn) {
+		t.Errorf("Expected application to depend on %v, got %+v", dependsOn, bom.Dependencies)
+	}
+}
@@ -0,0 +1,96 @@
+// Package sbom writes the npm packages found in source maps, as detected by tools.DetectPackages, as a software bill of
+// materials in CycloneDX or SPDX JSON.
+package sbom
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ToolName is the tool credited with creating the SBOMs.
+const ToolName = "go-sourcemap"
+
+// Options describes the SBOM document.
+type Options struct {
+	// Name is the name of the application the packages were found in, "application" if ""
+	Name string
+	// Created is when the SBOM was created, the current time if zero
+	Created time.Time
+	// Serial is the UUID identifying the SBOM, a random one if ""
+	Serial string
+}
+
+// withDefaults returns options with Name, Created and Serial set.
+func (options Options) withDefaults() (Options, error) {
+	if options.Name == "" {
+		options.Name = "application"
+	}
+
+	if options.Created.IsZero() {
+		options.Created = time.Now()
+	}
+
+	options.Created = options.Created.UTC().Truncate(time.Second)
+
+	if options.Serial == "" {
+		serial, err := newUUID()
+
+		if err != nil {
+			return options, err
+		}
+
+		options.Serial = serial
+	}
+
+	return options, nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	uuid := make([]byte, 16)
+
+	if _, err := rand.Read(uuid); err != nil {
+		return "", fmt.Errorf("Error generating serial number: %w", err)
+	}
+
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// splitScope splits a package name into its scope, e.g. @babel, and its name within the scope.
+func splitScope(name string) (string, string) {
+	if scope, rest, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return scope, rest
+	}
+
+	return "", name
+}
+
+// PackageURL returns the package url of an npm package, e.g. pkg:npm/%40babel/runtime@7.23.2.
+// The version is left out if it is "".
+func PackageURL(name string, version string) string {
+	scope, name := splitScope(name)
+	purl := "pkg:npm/"
+
+	if scope != "" {
+		purl += purlEscape(scope) + "/"
+	}
+
+	purl += purlEscape(name)
+
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+
+	return purl
+}
+
+// purlEscape percent-encodes a part of a package url, including the @ separating the version.
+func purlEscape(part string) string {
+	return strings.ReplaceAll(url.PathEscape(part), "@", "%40")
+}
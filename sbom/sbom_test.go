@@ -0,0 +1,59 @@
+package sbom
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// testPackages are the packages every SBOM test writes.
+var testPackages = []*tools.NpmPackage{
+	{Name: "@babel/runtime", Version: "7.23.2", Sources: []string{"webpack://app/./node_modules/@babel/runtime/helpers/extends.js"}},
+	{Name: "react", Version: "18.2.0", Sources: []string{"webpack://app/./node_modules/react/index.js", "webpack://app/./node_modules/react/cjs/react.production.min.js"}},
+	{Name: "scheduler", Sources: []string{"webpack://app/./node_modules/scheduler/index.js"}},
+}
+
+var testOptions = Options{
+	Name:    "app",
+	Created: time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("CET", 3600)),
+	Serial:  "3e671687-395b-41f5-a30f-a58921a69b79",
+}
+
+func TestPackageURL(t *testing.T) {
+	tests := []struct {
+		name, version, expected string
+	}{
+		{"react", "18.2.0", "pkg:npm/react@18.2.0"},
+		{"@babel/runtime", "7.23.2", "pkg:npm/%40babel/runtime@7.23.2"},
+		{"scheduler", "", "pkg:npm/scheduler"},
+		{"foo", "1.0.0+build", "pkg:npm/foo@1.0.0+build"},
+	}
+
+	for _, test := range tests {
+		if purl := PackageURL(test.name, test.version); purl != test.expected {
+			t.Errorf("%s %s: expected %s, got %s", test.name, test.version, test.expected, purl)
+		}
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	options, err := Options{}.withDefaults()
+
+	if err != nil {
+		t.Fatalf("Error setting defaults: %v", err)
+	}
+
+	if options.Name != "application" {
+		t.Errorf("Expected name application, got %s", options.Name)
+	}
+
+	if time.Since(options.Created) > time.Minute {
+		t.Errorf("Expected the current time, got %v", options.Created)
+	}
+
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(options.Serial) {
+		t.Errorf("Expected a version 4 UUID, got %s", options.Serial)
+	}
+}
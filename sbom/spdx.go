@@ -0,0 +1,120 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// noAssertion is the SPDX value of fields whose value is unknown.
+const noAssertion = "NOASSERTION"
+
+// spdxDocument is an SPDX 2.3 document, see https://spdx.github.io/spdx-spec/v2.3/.
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+// newSpdxPackage returns an SPDX package whose download location, licenses and copyright are unknown.
+func newSpdxPackage(id string, name string, version string) spdxPackage {
+	return spdxPackage{
+		Name:             name,
+		SPDXID:           id,
+		VersionInfo:      version,
+		DownloadLocation: noAssertion,
+		LicenseConcluded: noAssertion,
+		LicenseDeclared:  noAssertion,
+		CopyrightText:    noAssertion,
+	}
+}
+
+// WriteSPDX writes packages to w as an SPDX 2.3 JSON SBOM, describing the application package named options.Name,
+// which contains a package for each of packages. Licenses are NOASSERTION, since source maps don't record them.
+func WriteSPDX(w io.Writer, packages []*tools.NpmPackage, options Options) error {
+	options, err := options.withDefaults()
+
+	if err != nil {
+		return err
+	}
+
+	application := newSpdxPackage("SPDXRef-Application", options.Name, "")
+	document := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              options.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + url.PathEscape(options.Name) + "-" + options.Serial,
+		CreationInfo: spdxCreationInfo{
+			Created:  options.Created.Format(time.RFC3339),
+			Creators: []string{"Tool: " + ToolName},
+		},
+		Packages: []spdxPackage{application},
+		Relationships: []spdxRelationship{
+			{SpdxElementId: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSpdxElement: application.SPDXID},
+		},
+	}
+
+	for i, npmPackage := range packages {
+		spdxPackage := newSpdxPackage(fmt.Sprintf("SPDXRef-Package-%d", i+1), npmPackage.Name, npmPackage.Version)
+		spdxPackage.ExternalRefs = []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  PackageURL(npmPackage.Name, npmPackage.Version),
+		}}
+
+		document.Packages = append(document.Packages, spdxPackage)
+		document.Relationships = append(document.Relationships, spdxRelationship{
+			SpdxElementId:      application.SPDXID,
+			RelationshipType:   "CONTAINS",
+			RelatedSpdxElement: spdxPackage.SPDXID,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("Error writing SPDX SBOM: %w", err)
+	}
+
+	return nil
+}
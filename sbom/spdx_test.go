@@ -0,0 +1,79 @@
+package sbom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteSPDX(t *testing.T) {
+	var out strings.Builder
+
+	if err := WriteSPDX(&out, testPackages, testOptions); err != nil {
+		t.Fatalf("Error writing SBOM: %v", err)
+	}
+
+	document := spdxDocument{}
+
+	if err := json.Unmarshal([]byte(out.String()), &document); err != nil {
+		t.Fatalf("Error parsing SBOM: %v", err)
+	}
+
+	if document.SpdxVersion != "SPDX-2.3" || document.SPDXID != "SPDXRef-DOCUMENT" || document.Name != "app" {
+		t.Errorf("Unexpected header %s %s %s", document.SpdxVersion, document.SPDXID, document.Name)
+	}
+
+	if document.DocumentNamespace != "https://spdx.org/spdxdocs/app-"+testOptions.Serial {
+		t.Errorf("Unexpected namespace %s", document.DocumentNamespace)
+	}
+
+	if document.CreationInfo.Created != "2024-01-02T02:04:05Z" {
+		t.Errorf("Expected created 2024-01-02T02:04:05Z, got %s", document.CreationInfo.Created)
+	}
+
+	expected := []struct {
+		id, name, version, purl string
+	}{
+		{"SPDXRef-Application", "app", "", ""},
+		{"SPDXRef-Package-1", "@babel/runtime", "7.23.2", "pkg:npm/%40babel/runtime@7.23.2"},
+		{"SPDXRef-Package-2", "react", "18.2.0", "pkg:npm/react@18.2.0"},
+		{"SPDXRef-Package-3", "scheduler", "", "pkg:npm/scheduler"},
+	}
+
+	if len(document.Packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d", len(expected), len(document.Packages))
+	}
+
+	for i, spdxPackage := range document.Packages {
+		purl := ""
+
+		if len(spdxPackage.ExternalRefs) == 1 {
+			purl = spdxPackage.ExternalRefs[0].ReferenceLocator
+		}
+
+		if spdxPackage.SPDXID != expected[i].id || spdxPackage.Name != expected[i].name || spdxPackage.VersionInfo != expected[i].version || purl != expected[i].purl {
+			t.Errorf("Package %d: expected %+v, got %+v", i, expected[i], spdxPackage)
+		}
+
+		if spdxPackage.LicenseConcluded != noAssertion || spdxPackage.DownloadLocation != noAssertion {
+			t.Errorf("Package %d: expected NOASSERTION license and download location, got %+v", i, spdxPackage)
+		}
+	}
+
+	expectedRelationships := []spdxRelationship{
+		{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Application"},
+		{"SPDXRef-Application", "CONTAINS", "SPDXRef-Package-1"},
+		{"SPDXRef-Application", "CONTAINS", "SPDXRef-Package-2"},
+		{"SPDXRef-Application", "CONTAINS", "SPDXRef-Package-3"},
+	}
+
+	if len(document.Relationships) != len(expectedRelationships) {
+		t.Fatalf("Expected %d relationships, got %+v", len(expectedRelationships), document.Relationships)
+	}
+
+	for i, relationship := range document.Relationships {
+		if relationship != expectedRelationships[i] {
+			t.Errorf("Relationship %d: expected %+v, got %+v", i, expectedRelationships[i], relationship)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/secrets"
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// secretFinding is a finding printed by go-sourcemap secrets -json, with the input it was found in.
+type secretFinding struct {
+	Input string `json:"input"`
+	secrets.Finding
+}
+
+// runSecrets implements go-sourcemap secrets, scanning the sources of source maps for leaked credentials.
+func runSecrets(arguments []string) {
+	flags := flag.NewFlagSet("secrets", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap secrets: go-sourcemap secrets [flags] input ...")
+		fmt.Fprintln(flags.Output(), "       go-sourcemap secrets [flags] -crawl url")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+	scanner := secrets.Scanner{}
+
+	fetch.register(flags)
+	crawlUrl := flags.String("crawl", "", "Web page to recover the source maps of and scan, as go-sourcemap crawl does, instead of inputs")
+	allowedHosts := flags.String("allow-host", "", "Comma separated hosts, e.g. a CDN, to download from in addition to the -crawl page's origin")
+	rulesPath := flags.String("rules", "", "JSON file of additional rules, a list of {\"id\", \"description\", \"pattern\", \"minEntropy\"}")
+	disabled := flags.String("disable", "", "Comma separated ids of rules to leave out, e.g. high-entropy-string,internal-hostname")
+	flags.BoolVar(&scanner.SkipIgnored, "skip-ignored", false, "Don't scan sources in the ignoreList of source map inputs")
+	jsonOutput := flags.Bool("json", false, "Print one JSON object per finding per line")
+	reveal := flags.Bool("reveal", false, "Print secrets in full instead of redacting them")
+	exitCode := flags.Bool("exit-code", false, "Exit with status 1 if anything was found")
+
+	flags.Parse(arguments)
+
+	if (flags.NArg() == 0) == (*crawlUrl == "") {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	scanner.Rules = secrets.DefaultRules()
+
+	if *rulesPath != "" {
+		file, err := os.Open(*rulesPath)
+
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", *rulesPath, err)
+			os.Exit(-1)
+		}
+
+		rules, err := secrets.ParseRules(file)
+		file.Close()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		scanner.Rules = append(rules, scanner.Rules...)
+	}
+
+	if *disabled != "" {
+		ids := strings.Split(*disabled, ",")
+		scanner.Rules = slices.DeleteFunc(scanner.Rules, func(rule secrets.Rule) bool {
+			return slices.Contains(ids, rule.Id)
+		})
+	}
+
+	fetcher, err := fetch.build(append(flags.Args(), *crawlUrl)...)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	var results []secretFinding
+	failed := 0
+
+	if *crawlUrl != "" {
+		results, err = crawlSecrets(*crawlUrl, *allowedHosts, fetcher, &scanner)
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	} else {
+		inputs, err := expandInputs(flags.Args())
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		for _, input := range inputs {
+			findings, err := inputSecrets(input, fetcher, &scanner)
+
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed++
+				continue
+			}
+
+			for _, finding := range findings {
+				results = append(results, secretFinding{Input: input, Finding: finding})
+			}
+		}
+	}
+
+	// Chunks of the same app often embed the same sources, which only need reporting once
+	seen := make(map[secrets.Finding]bool)
+	encoder := json.NewEncoder(os.Stdout)
+
+	for _, result := range results {
+		if seen[result.Finding] {
+			continue
+		}
+
+		seen[result.Finding] = true
+
+		if !*reveal {
+			result.Secret = secrets.Redact(result.Secret)
+		}
+
+		if *jsonOutput {
+			err = encoder.Encode(result)
+		} else {
+			_, err = fmt.Printf("%s:%d:%d: %s: %s\n", result.Source, result.Line, result.Column, result.Rule, result.Secret)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(-1)
+	}
+
+	if *exitCode && len(results) > 0 {
+		os.Exit(1)
+	}
+}
+
+// inputSecrets scans the sources of the source map named by input. Source urls are kept as written in the map.
+func inputSecrets(input string, fetcher *tools.Fetcher, scanner *secrets.Scanner) ([]secrets.Finding, error) {
+	contents, err := readInput(input, fetcher)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mapRecord, err := spec.ParseSourceMap(contents, "")
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %w", input, err)
+	}
+
+	return scanner.ScanSourceMap(mapRecord), nil
+}
+
+// crawlSecrets recovers the source maps used by the page at pageUrl and scans their sources, which findings name by
+// their path in the clean layout. Assets whose source map could not be recovered are reported on stderr.
+func crawlSecrets(pageUrl string, allowedHosts string, fetcher *tools.Fetcher, scanner *secrets.Scanner) ([]secretFinding, error) {
+	crawler := tools.Crawler{Fetcher: fetcher, SameOrigin: true}
+
+	if allowedHosts != "" {
+		crawler.AllowedHosts = strings.Split(allowedHosts, ",")
+	}
+
+	writer := &secrets.ScanningWriter{Scanner: scanner}
+	report, err := crawler.Crawl(context.Background(), pageUrl, writer)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error crawling %s: %w", pageUrl, err)
+	}
+
+	for _, crawled := range report.Maps {
+		if crawled.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", crawled.AssetUrl, crawled.Error)
+		}
+	}
+
+	results := make([]secretFinding, len(writer.Findings))
+
+	for i, finding := range writer.Findings {
+		results[i] = secretFinding{Input: pageUrl, Finding: finding}
+	}
+
+	return results, nil
+}
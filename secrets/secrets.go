@@ -0,0 +1,123 @@
+// Package secrets scans the original sources embedded in source maps for leaked credentials and other sensitive data,
+// such as API keys, private keys, internal hostnames and credentials left in TODO comments.
+package secrets
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// Finding is sensitive data found in an original source.
+type Finding struct {
+	// Rule is the Id of the rule that found it
+	Rule string `json:"rule"`
+	// Source is the url of the original source, or its path
+	Source string `json:"source"`
+	// Line and Column are 1-based, Column counting characters, as printed by compilers and editors
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Secret is the sensitive data, see Redact
+	Secret string `json:"secret"`
+}
+
+// Scanner applies rules to the lines of original sources.
+type Scanner struct {
+	// Rules are applied to every line in order, DefaultRules if nil. A match overlapping the match of an earlier
+	// rule is left out, so more specific rules should come first
+	Rules []Rule
+	// SkipIgnored skips sources in the ignoreList, which are usually third party code
+	SkipIgnored bool
+}
+
+// ScanContent returns the findings in content, the content of the source called source.
+func (scanner *Scanner) ScanContent(source string, content string) []Finding {
+	rules := scanner.Rules
+
+	if rules == nil {
+		rules = DefaultRules()
+	}
+
+	findings := make([]Finding, 0)
+
+	for lineIndex, line := range tools.SplitLines(content) {
+		matches := make([]Match, 0)
+
+		for _, rule := range rules {
+			for _, match := range rule.Find(line) {
+				overlaps := slices.ContainsFunc(matches, func(previous Match) bool {
+					return match.Start < previous.End && previous.Start < match.End
+				})
+
+				if overlaps || match.Start >= match.End {
+					continue
+				}
+
+				matches = append(matches, match)
+				findings = append(findings, Finding{
+					Rule:   rule.Id,
+					Source: source,
+					Line:   lineIndex + 1,
+					Column: utf8.RuneCountInString(line[:match.Start]) + 1,
+					Secret: line[match.Start:match.End],
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a Finding, b Finding) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	return findings
+}
+
+// ScanSourceMap returns the findings in the content of every source of mapRecord, in the order of the sources.
+// Sources without content are skipped, as are sources with the same url and content as an earlier one.
+func (scanner *Scanner) ScanSourceMap(mapRecord *spec.DecodedSourceMapRecord) []Finding {
+	findings := make([]Finding, 0)
+	scanned := make(map[[2]string]bool)
+
+	for _, source := range mapRecord.Sources {
+		key := [2]string{source.Url, source.Content}
+
+		if source.Content == "" || scanned[key] || (scanner.SkipIgnored && source.Ignored) {
+			continue
+		}
+
+		scanned[key] = true
+		findings = append(findings, scanner.ScanContent(source.Url, source.Content)...)
+	}
+
+	return findings
+}
+
+// ScanningWriter is a tools.SourceWriter that scans the sources written to it, e.g. by tools.Crawler, instead of saving
+// them. Findings name sources by the path they would have been written to.
+type ScanningWriter struct {
+	Scanner  *Scanner
+	Findings []Finding
+}
+
+// WriteSource scans content, adding its findings to writer.Findings.
+func (writer *ScanningWriter) WriteSource(path string, content []byte) error {
+	writer.Findings = append(writer.Findings, writer.Scanner.ScanContent(path, string(content))...)
+
+	return nil
+}
+
+// Redact hides most of secret, keeping the first 4 characters of secrets long enough that they don't give it away,
+// so findings can be shared and told apart without leaking the secret again.
+func Redact(secret string) string {
+	runes := []rune(secret)
+
+	if len(runes) < 12 {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-4)
+}
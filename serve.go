@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/redawl/go-sourcemap/server"
+)
+
+// runServe implements go-sourcemap serve, answering source map lookups over HTTP until interrupted.
+func runServe(arguments []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap serve: go-sourcemap serve [flags] -dir maps")
+		flags.PrintDefaults()
+	}
+
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	dir := flags.String("dir", "", "Directory to load source maps from, by path or debugId")
+	cacheSize := flags.Int("cache-size", server.DefaultCacheSize, "Number of parsed source maps to keep in memory")
+
+	flags.Parse(arguments)
+
+	if *dir == "" || flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	mapServer, err := server.New(*dir, *cacheSize)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Serving source maps", "addr", *addr, "dir", *dir, "debugIds", mapServer.Store.Len())
+
+	err = mapServer.ListenAndServe(ctx, *addr)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,369 @@
+// Package server answers source map lookups over HTTP, so that services such as error dashboards can symbolicate
+// positions and stack traces without shelling out to go-sourcemap.
+//
+// Endpoints:
+//
+//	GET  /lookup?map=app.js.map&line=1&column=2345     original position of a generated position, or debugId= instead of map=
+//	POST /symbolicate                                  {"stackTrace": "...", "debugIds": {"https://example.com/app.js": "..."}}
+//	POST /validate                                     the source map to validate as the request body
+//	GET  /healthz
+//
+// Lines are 1-based and columns 0-based in /lookup, while stack traces use 1-based columns, as printed by browsers.
+// Every response is JSON, errors are of the form {"error": "..."}.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// ShutdownTimeout is how long ListenAndServe waits for requests in flight when shutting down.
+const ShutdownTimeout = 10 * time.Second
+
+// DefaultCacheSize is the number of parsed source maps kept in memory by New.
+const DefaultCacheSize = 64
+
+// DefaultReloadInterval is how often a Server rescans its directory for source maps with unknown debug ids at most,
+// when ReloadInterval is 0.
+const DefaultReloadInterval = 10 * time.Second
+
+// MaxRequestBytes is the largest request body accepted by /symbolicate and /validate.
+const MaxRequestBytes = 64 << 20
+
+// errNotFound is returned when a requested source map doesn't exist, and answered with 404.
+var errNotFound = errors.New("source map not found")
+
+// Server loads source maps from a directory, by path or debug id, and answers lookups for them.
+type Server struct {
+	// Dir is the directory source maps are loaded from, map paths in requests are relative to it
+	Dir string
+	// Store finds source maps by debug id, debug ids are not supported if nil
+	Store *tools.DebugIdStore
+	// Cache holds parsed source maps, every request parses its source maps again if nil
+	Cache *tools.MapCache
+	// ReloadInterval is the shortest time between rescans of Dir for an unknown debug id, DefaultReloadInterval if 0.
+	// It keeps requests for random debug ids from rescanning Dir every time
+	ReloadInterval time.Duration
+	// Logger logs every request, slog.Default() if nil
+	Logger *slog.Logger
+}
+
+// New returns a Server for the source maps in dir, indexing their debug ids and caching up to cacheSize parsed source maps.
+func New(dir string, cacheSize int) (*Server, error) {
+	store, err := tools.NewDebugIdStore(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{Dir: dir, Store: store, Cache: tools.NewMapCache(cacheSize)}, nil
+}
+
+// Handler returns the http.Handler serving the endpoints of server.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", server.handleHealth)
+	mux.HandleFunc("GET /lookup", server.handleLookup)
+	mux.HandleFunc("POST /symbolicate", server.handleSymbolicate)
+	mux.HandleFunc("POST /validate", server.handleValidate)
+
+	return server.logRequests(mux)
+}
+
+// ListenAndServe serves server on addr until ctx is done, then shuts down gracefully,
+// waiting up to ShutdownTimeout for requests in flight to complete.
+func (server *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("Error serving on %s: %w", addr, err)
+	case <-ctx.Done():
+	}
+
+	server.logger().Info("Shutting down", "addr", addr)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(shutdownCtx)
+
+	if err != nil {
+		return fmt.Errorf("Error shutting down: %w", err)
+	}
+
+	return nil
+}
+
+func (server *Server) logger() *slog.Logger {
+	if server.Logger == nil {
+		return slog.Default()
+	}
+
+	return server.Logger
+}
+
+// statusRecorder remembers the status code written by a handler, for logging.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (server *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		server.logger().Info("Handled request", "method", r.Method, "path", r.URL.Path, "status", recorder.status)
+	})
+}
+
+// writeJSON writes value to w as JSON with status.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+}
+
+// writeError writes err to w as {"error": ...} with status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// errorStatus returns the status code to answer a failure to load a source map with.
+func errorStatus(err error) int {
+	if errors.Is(err, errNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusUnprocessableEntity
+}
+
+func (server *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := map[string]int{"cached": 0, "debugIds": 0}
+
+	if server.Cache != nil {
+		status["cached"] = server.Cache.Len()
+	}
+
+	if server.Store != nil {
+		status["debugIds"] = server.Store.Len()
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// loadPath parses the source map at mapPath, relative to server.Dir.
+func (server *Server) loadPath(mapPath string) (*spec.DecodedSourceMapRecord, error) {
+	fullPath, err := tools.SafeJoin(server.Dir, tools.SanitizePath(mapPath))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error loading %s: %w", mapPath, errNotFound)
+	}
+
+	if _, err := os.Stat(fullPath); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("Error loading %s: %w", mapPath, errNotFound)
+	}
+
+	return server.parseFile(fullPath)
+}
+
+// parseFile parses the source map at fullPath, from server.Cache if possible.
+func (server *Server) parseFile(fullPath string) (*spec.DecodedSourceMapRecord, error) {
+	if server.Cache == nil {
+		return tools.ParseSourceMapFromFile(fullPath)
+	}
+
+	return server.Cache.ParseSourceMapFromFile(fullPath)
+}
+
+// loadDebugId parses the source map with the debug id id.
+func (server *Server) loadDebugId(id string) (*spec.DecodedSourceMapRecord, error) {
+	if server.Store == nil {
+		return nil, fmt.Errorf("Error loading debug id %s: %w", id, errNotFound)
+	}
+
+	fullPath, ok := server.Store.Path(id)
+
+	if !ok {
+		interval := server.ReloadInterval
+
+		if interval <= 0 {
+			interval = DefaultReloadInterval
+		}
+
+		// The source map may have been added since the store was scanned, or by a reload that was in progress
+		if _, err := server.Store.ReloadAfter(interval); err == nil {
+			fullPath, ok = server.Store.Path(id)
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("Error loading debug id %s: %w", id, errNotFound)
+	}
+
+	return server.parseFile(fullPath)
+}
+
+// LookupResponse is the answer of /lookup.
+type LookupResponse struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Source string `json:"source"`
+	Name   string `json:"name,omitempty"`
+}
+
+func (server *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	line, lineErr := strconv.Atoi(query.Get("line"))
+	column, columnErr := strconv.Atoi(query.Get("column"))
+
+	if lineErr != nil || columnErr != nil || line < 1 || column < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("Error: line and column are required, line is 1-based and column 0-based"))
+		return
+	}
+
+	var mapRecord *spec.DecodedSourceMapRecord
+	var err error
+
+	switch {
+	case query.Get("debugId") != "":
+		mapRecord, err = server.loadDebugId(query.Get("debugId"))
+	case query.Get("map") != "":
+		mapRecord, err = server.loadPath(query.Get("map"))
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("Error: either map or debugId is required"))
+		return
+	}
+
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	mapping := tools.OriginalPositionFor(mapRecord, line-1, column)
+
+	if mapping == nil || mapping.OriginalSource == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("Error: no mapping for %d:%d", line, column))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, LookupResponse{
+		Line:   mapping.OriginalLine + 1,
+		Column: mapping.OriginalColumn,
+		Source: mapping.OriginalSource.Url,
+		Name:   mapping.Name,
+	})
+}
+
+// SymbolicateRequest is the body of /symbolicate.
+type SymbolicateRequest struct {
+	// StackTrace is the stack trace to symbolicate, as printed by a browser or Node.js
+	StackTrace string `json:"stackTrace"`
+	// DebugIds maps script urls to the debug id of their source map.
+	// Scripts without a debug id use the source map at their url path with .map appended, relative to Dir,
+	// or failing that, the source map named after the script in Dir.
+	DebugIds map[string]string `json:"debugIds"`
+}
+
+// SymbolicateResponse is the answer of /symbolicate.
+type SymbolicateResponse struct {
+	Frames []tools.SymbolicatedFrame `json:"frames"`
+	// StackTrace is the symbolicated stack trace, frames that couldn't be symbolicated are left as is
+	StackTrace string `json:"stackTrace"`
+}
+
+func (server *Server) handleSymbolicate(w http.ResponseWriter, r *http.Request) {
+	request := &SymbolicateRequest{}
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBytes)).Decode(request)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Error parsing request: %w", err))
+		return
+	}
+
+	frames := tools.SymbolicateStackTrace(tools.ParseStackTrace(request.StackTrace), func(scriptUrl string) (*spec.DecodedSourceMapRecord, error) {
+		if id, ok := request.DebugIds[scriptUrl]; ok {
+			return server.loadDebugId(id)
+		}
+
+		return server.loadScriptMap(scriptUrl)
+	})
+
+	response := SymbolicateResponse{Frames: frames}
+	lines := make([]string, len(frames))
+
+	for i, frame := range frames {
+		if frame.Original != nil {
+			lines[i] = frame.Original.String()
+		} else {
+			lines[i] = frame.Generated.String()
+		}
+	}
+
+	response.StackTrace = strings.Join(lines, "\n")
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// loadScriptMap parses the source map of the script at scriptUrl, from its path or its name.
+func (server *Server) loadScriptMap(scriptUrl string) (*spec.DecodedSourceMapRecord, error) {
+	scriptPath := scriptUrl
+
+	if parsed, err := url.Parse(scriptUrl); err == nil && parsed.Scheme != "" {
+		scriptPath = parsed.Path
+	}
+
+	mapRecord, err := server.loadPath(scriptPath + ".map")
+
+	if errors.Is(err, errNotFound) {
+		return server.loadPath(path.Base(scriptPath) + ".map")
+	}
+
+	return mapRecord, err
+}
+
+func (server *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	contents, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestBytes))
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Error reading request: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tools.ValidateSourceMap(string(contents)))
+}
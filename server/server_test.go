@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+const testDebugId = "85314830-023f-4cf1-a267-535f4e37bb17"
+
+// newTestServer serves a directory with test1.js.map at assets/app.js.map, and a copy of it with a debugId at by-id.js.map.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "assets"), 0700)
+	os.WriteFile(filepath.Join(dir, "assets", "app.js.map"), contents, 0600)
+	withId := strings.Replace(string(contents), "{", `{"debugId": "`+strings.ToUpper(testDebugId)+`",`, 1)
+	os.WriteFile(filepath.Join(dir, "by-id.js.map"), []byte(withId), 0600)
+
+	server, err := New(dir, DefaultCacheSize)
+
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+
+	server.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return httpServer
+}
+
+// decodeResponse checks the status of response, and decodes its body into value.
+func decodeResponse(t *testing.T, response *http.Response, err error, status int, value any) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != status {
+		body, _ := io.ReadAll(response.Body)
+		t.Fatalf("Expected status %d, got %d: %s", status, response.StatusCode, body)
+	}
+
+	if response.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON response, got %s", response.Header.Get("Content-Type"))
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(value); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	httpServer := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		line   int
+		column int
+	}{
+		{"path", "map=assets/app.js.map&line=1&column=12", http.StatusOK, 2, 9},
+		{"debug id", "debugId=" + testDebugId + "&line=1&column=30", http.StatusOK, 3, 15},
+		{"unknown debug id", "debugId=nope&line=1&column=0", http.StatusNotFound, 0, 0},
+		{"missing map", "map=missing.js.map&line=1&column=0", http.StatusNotFound, 0, 0},
+		{"escaping map", "map=../../etc/passwd&line=1&column=0", http.StatusNotFound, 0, 0},
+		{"unmapped line", "map=assets/app.js.map&line=5&column=0", http.StatusNotFound, 0, 0},
+		{"no position", "map=assets/app.js.map", http.StatusBadRequest, 0, 0},
+		{"no map", "line=1&column=0", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := http.Get(httpServer.URL + "/lookup?" + test.query)
+
+			if test.status != http.StatusOK {
+				body := map[string]string{}
+				decodeResponse(t, response, err, test.status, &body)
+
+				if body["error"] == "" {
+					t.Errorf("Expected an error message")
+				}
+
+				return
+			}
+
+			lookup := &LookupResponse{}
+			decodeResponse(t, response, err, test.status, lookup)
+
+			if lookup.Line != test.line || lookup.Column != test.column || !strings.HasSuffix(lookup.Source, "original.js") {
+				t.Errorf("Unexpected lookup %+v", lookup)
+			}
+		})
+	}
+}
+
+func TestSymbolicate(t *testing.T) {
+	httpServer := newTestServer(t)
+
+	request := `{
+		"stackTrace": "Error: boom\n    at x (https://example.com/assets/app.js:1:10)\n    at https://cdn.example.com/static/by-id.js:1:1\n    at y (https://example.com/id.js:1:31)\n    at z (https://example.com/unknown.js:1:1)",
+		"debugIds": {"https://example.com/id.js": "` + testDebugId + `"}
+	}`
+
+	response, err := http.Post(httpServer.URL+"/symbolicate", "application/json", strings.NewReader(request))
+	symbolicated := &SymbolicateResponse{}
+	decodeResponse(t, response, err, http.StatusOK, symbolicated)
+
+	if len(symbolicated.Frames) != 4 {
+		t.Fatalf("Expected 4 frames, got %d", len(symbolicated.Frames))
+	}
+
+	expected := []tools.StackFrame{
+		{Function: "abcd", Line: 2, Column: 10},
+		{Line: 2, Column: 1},
+		{Function: "abcd", Line: 3, Column: 16},
+	}
+
+	for i, frame := range expected {
+		original := symbolicated.Frames[i].Original
+
+		if original == nil || original.Function != frame.Function || original.Line != frame.Line || original.Column != frame.Column {
+			t.Errorf("Frame %d: expected %+v, got %+v (%s)", i, frame, original, symbolicated.Frames[i].Error)
+		}
+	}
+
+	if symbolicated.Frames[3].Original != nil || symbolicated.Frames[3].Error == "" {
+		t.Errorf("Expected the last frame to fail, got %+v", symbolicated.Frames[3])
+	}
+
+	lines := strings.Split(symbolicated.StackTrace, "\n")
+
+	if len(lines) != 4 || !strings.Contains(lines[0], "at abcd (") || lines[3] != "    at z (https://example.com/unknown.js:1:1)" {
+		t.Errorf("Unexpected stack trace %q", symbolicated.StackTrace)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	httpServer := newTestServer(t)
+
+	response, err := http.Post(httpServer.URL+"/validate", "application/json", strings.NewReader(`{"version": 3, "sources": [], "mappings": "AAAA"}`))
+	report := &tools.ValidationReport{}
+	decodeResponse(t, response, err, http.StatusOK, report)
+
+	if report.Valid || len(report.Errors) == 0 {
+		t.Errorf("Expected the source map to be invalid, got %+v", report)
+	}
+}
+
+func TestListenAndServeShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("Error finding a free port: %v", err)
+	}
+
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := &Server{Dir: t.TempDir(), Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- server.ListenAndServe(ctx, addr)
+	}()
+
+	for range 50 {
+		if response, err := http.Get("http://" + addr + "/healthz"); err == nil {
+			response.Body.Close()
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(ShutdownTimeout):
+		t.Errorf("Server did not shut down")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/redawl/go-sourcemap/size"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runSize implements go-sourcemap size, attributing the bytes of bundles to the original sources and packages they came from.
+func runSize(arguments []string) {
+	flags := flag.NewFlagSet("size", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap size: go-sourcemap size [flags] bundle.js ...")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+	layout := tools.LayoutClean
+
+	fetch.register(flags)
+	jsonOutput := flags.Bool("json", false, "Print the size of each bundle as JSON instead of a tree")
+	htmlPath := flags.String("html", "", "File to save a treemap of the bundles to, as an HTML page")
+	depth := flags.Int("depth", 3, "Levels of the tree to print, 0 for all")
+	mapDir := flags.String("maps", "", "Directory of source maps named after their bundle, e.g. app.js.map. By default each bundle's sourceMappingURL is followed")
+	flags.Func("layout", "How source urls are turned into paths in the tree: clean, origin or raw (default clean)", func(value string) error {
+		var err error
+		layout, err = tools.ParseLayout(value)
+
+		return err
+	})
+
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	inputs, err := expandInputs(flags.Args())
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build(flags.Args()...)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	resolver := &tools.ScriptMapResolver{Fetcher: fetcher, MapDir: *mapDir, Scripts: make(map[string]string)}
+	bundles := make([]*size.Bundle, 0, len(inputs))
+
+	for _, input := range inputs {
+		bundle, err := attributeBundle(resolver, input, layout)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", input, err)
+			continue
+		}
+
+		bundles = append(bundles, bundle)
+	}
+
+	if *jsonOutput {
+		err = json.NewEncoder(os.Stdout).Encode(bundles)
+	} else if *htmlPath == "" {
+		for _, bundle := range bundles {
+			if err = bundle.WriteText(os.Stdout, *depth); err != nil {
+				break
+			}
+		}
+	}
+
+	if *htmlPath != "" && err == nil {
+		err = writeOutputFile(*htmlPath, func(w io.Writer) error {
+			return size.RenderTreemap(w, bundles)
+		})
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if len(bundles) < len(inputs) {
+		os.Exit(-1)
+	}
+}
+
+// attributeBundle reads the bundle at input and its source map, and attributes its bytes.
+func attributeBundle(resolver *tools.ScriptMapResolver, input string, layout tools.Layout) (*size.Bundle, error) {
+	generated, err := resolver.Read(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolver.Scripts[input] = generated
+	mapRecord, err := resolver.Resolve(input)
+	delete(resolver.Scripts, input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return size.Attribute(input, generated, mapRecord, layout), nil
+}
@@ -0,0 +1,204 @@
+// Package size attributes every byte of generated files to the original source it came from, like source-map-explorer,
+// and aggregates the bytes by directory and node_modules package.
+package size
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// Names of the nodes of a Bundle's tree that aren't original sources.
+const (
+	// UnmappedName is the node of the generated bytes that no mapping attributes to a source
+	UnmappedName = "[unmapped]"
+	// EOLName is the node of the line terminators of the generated file
+	EOLName = "[EOLs]"
+)
+
+// Kinds of Node.
+const (
+	KindBundle    = "bundle"
+	KindDirectory = "directory"
+	KindPackage   = "package"
+	KindSource    = "source"
+	// KindOther is the kind of the UnmappedName and EOLName nodes
+	KindOther = "other"
+)
+
+// Node is the bundle, a directory, a node_modules package or an original source in the tree of a Bundle.
+type Node struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Bytes is the number of generated bytes of the node, including all of its children
+	Bytes    int     `json:"bytes"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// child returns the child of node called name, adding it with kind if there is none.
+func (node *Node) child(name string, kind string) *Node {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+
+	child := &Node{Name: name, Kind: kind}
+	node.Children = append(node.Children, child)
+
+	return child
+}
+
+// sort orders the children of node and its descendants from the most bytes to the least.
+func (node *Node) sort() {
+	slices.SortStableFunc(node.Children, func(a *Node, b *Node) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), strings.Compare(a.Name, b.Name))
+	})
+
+	for _, child := range node.Children {
+		child.sort()
+	}
+}
+
+// Bundle is the size of a generated file, attributed to its original sources.
+type Bundle struct {
+	Name string `json:"name"`
+	// TotalBytes is the size of the generated file
+	TotalBytes int `json:"totalBytes"`
+	// MappedBytes is how many of TotalBytes are attributed to an original source
+	MappedBytes int `json:"mappedBytes"`
+	// UnmappedBytes is how many of TotalBytes are not covered by a mapping to a source, excluding line terminators
+	UnmappedBytes int `json:"unmappedBytes"`
+	// EOLBytes is how many of TotalBytes are line terminators
+	EOLBytes int `json:"eolBytes"`
+	// Sources are the bytes of each original source, by path
+	Sources map[string]int `json:"sources"`
+	// Packages are the bytes of each node_modules package, by package name
+	Packages map[string]int `json:"packages"`
+	// Tree is the bytes of every directory, package and source
+	Tree *Node `json:"tree"`
+}
+
+// Attribute attributes every byte of generated, the generated file called name, to the original source of the mapping
+// covering it according to mapRecord. A mapping covers its line up to the next mapping. Source urls are turned into paths
+// with layout, and the paths are split into directories and node_modules packages.
+func Attribute(name string, generated string, mapRecord *spec.DecodedSourceMapRecord, layout tools.Layout) *Bundle {
+	bundle := &Bundle{
+		Name:       name,
+		TotalBytes: len(generated),
+		Sources:    make(map[string]int),
+		Packages:   make(map[string]int),
+	}
+
+	mappings := slices.Clone(mapRecord.Mappings)
+	slices.SortStableFunc(mappings, func(a *spec.DecodedMappingRecord, b *spec.DecodedMappingRecord) int {
+		return cmp.Or(cmp.Compare(a.GeneratedLine, b.GeneratedLine), cmp.Compare(a.GeneratedColumn, b.GeneratedColumn))
+	})
+
+	paths := make(map[*spec.DecodedSourceRecord]string)
+	next := 0
+	lineBytes := 0
+
+	for lineIndex, line := range tools.SplitLines(generated) {
+		lineBytes += len(line)
+
+		for next < len(mappings) && mappings[next].GeneratedLine < lineIndex {
+			next++
+		}
+
+		// offset is where the current mapping starts, and source is its path, "" if it has no source
+		offset := 0
+		source := ""
+
+		for ; next < len(mappings) && mappings[next].GeneratedLine == lineIndex; next++ {
+			mapping := mappings[next]
+			start := tools.ByteOffset(line, mapping.GeneratedColumn)
+			bundle.add(source, start-offset)
+			offset = start
+			source = ""
+
+			if mapping.OriginalSource != nil {
+				path, ok := paths[mapping.OriginalSource]
+
+				if !ok {
+					path = tools.NormalizeSourcePath(mapping.OriginalSource.Url, layout)
+					paths[mapping.OriginalSource] = path
+				}
+
+				source = path
+			}
+		}
+
+		bundle.add(source, len(line)-offset)
+	}
+
+	bundle.EOLBytes = bundle.TotalBytes - lineBytes
+	bundle.Tree = bundle.tree()
+
+	return bundle
+}
+
+// add attributes bytes to the source at path, or to UnmappedBytes if path is "".
+func (bundle *Bundle) add(path string, bytes int) {
+	if bytes <= 0 {
+		return
+	}
+
+	if path == "" {
+		bundle.UnmappedBytes += bytes
+		return
+	}
+
+	bundle.MappedBytes += bytes
+	bundle.Sources[path] += bytes
+
+	if name, _ := tools.PackageFromPath(path); name != "" {
+		bundle.Packages[name] += bytes
+	}
+}
+
+// tree builds the tree of bundle's sources, with the unmapped bytes and line terminators as separate nodes.
+func (bundle *Bundle) tree() *Node {
+	root := &Node{Name: bundle.Name, Kind: KindBundle, Bytes: bundle.TotalBytes}
+
+	for path, bytes := range bundle.Sources {
+		node := root
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+
+		for i := 0; i < len(segments); i++ {
+			segment := segments[i]
+			kind := KindDirectory
+
+			switch {
+			case i == len(segments)-1:
+				kind = KindSource
+			case i > 0 && segments[i-1] == "node_modules" && segment != ".pnpm":
+				kind = KindPackage
+
+				// Scoped packages are a single node, e.g. @babel/runtime
+				if strings.HasPrefix(segment, "@") && i+2 < len(segments) {
+					i++
+					segment += "/" + segments[i]
+				}
+			}
+
+			node = node.child(segment, kind)
+			node.Bytes += bytes
+		}
+	}
+
+	if bundle.UnmappedBytes > 0 {
+		root.Children = append(root.Children, &Node{Name: UnmappedName, Kind: KindOther, Bytes: bundle.UnmappedBytes})
+	}
+
+	if bundle.EOLBytes > 0 {
+		root.Children = append(root.Children, &Node{Name: EOLName, Kind: KindOther, Bytes: bundle.EOLBytes})
+	}
+
+	root.sort()
+
+	return root
+}
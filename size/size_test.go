@@ -0,0 +1,97 @@
+package size
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+const testGenerated = "AAAAbbbbCCCC\nDDDD;\n//# sourceMappingURL=app.js.map"
+
+// testBundle attributes testGenerated, where each run of 4 letters comes from a different source.
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+
+	mapRecord, err := spec.ParseSourceMap(`{"version": 3, "sources": [
+		"webpack://app/./src/index.js",
+		"webpack://app/node_modules/react/index.js",
+		"webpack://app/node_modules/@babel/runtime/helpers/extends.js",
+		"webpack://app/node_modules/.pnpm/lodash@4.17.21/node_modules/lodash/map.js"
+	], "names": [], "mappings": "AAAA,ICAA,ICAA;ACAA,I"}`, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing source map: %v", err)
+	}
+
+	return Attribute("app.js", testGenerated, mapRecord, tools.LayoutClean)
+}
+
+func TestAttribute(t *testing.T) {
+	bundle := testBundle(t)
+
+	if bundle.TotalBytes != len(testGenerated) || bundle.MappedBytes != 16 || bundle.UnmappedBytes != 32 || bundle.EOLBytes != 2 {
+		t.Errorf("Unexpected totals: %d total, %d mapped, %d unmapped, %d EOLs", bundle.TotalBytes, bundle.MappedBytes, bundle.UnmappedBytes, bundle.EOLBytes)
+	}
+
+	expectedSources := map[string]int{
+		"app/src/index.js":                                             4,
+		"node_modules/react/index.js":                                  4,
+		"node_modules/@babel/runtime/helpers/extends.js":               4,
+		"node_modules/.pnpm/lodash@4.17.21/node_modules/lodash/map.js": 4,
+	}
+
+	if !maps.Equal(bundle.Sources, expectedSources) {
+		t.Errorf("Expected sources %v, got %v", expectedSources, bundle.Sources)
+	}
+
+	expectedPackages := map[string]int{"react": 4, "@babel/runtime": 4, "lodash": 4}
+
+	if !maps.Equal(bundle.Packages, expectedPackages) {
+		t.Errorf("Expected packages %v, got %v", expectedPackages, bundle.Packages)
+	}
+
+	tree := bundle.Tree
+	expectedChildren := []struct {
+		name  string
+		kind  string
+		bytes int
+	}{
+		{UnmappedName, KindOther, 32},
+		{"node_modules", KindDirectory, 12},
+		{"app", KindDirectory, 4},
+		{EOLName, KindOther, 2},
+	}
+
+	if tree.Bytes != bundle.TotalBytes || len(tree.Children) != len(expectedChildren) {
+		t.Fatalf("Unexpected tree %+v", tree)
+	}
+
+	for i, expected := range expectedChildren {
+		child := tree.Children[i]
+
+		if child.Name != expected.name || child.Kind != expected.kind || child.Bytes != expected.bytes {
+			t.Errorf("Child %d: expected %v, got %s %s %d", i, expected, child.Name, child.Kind, child.Bytes)
+		}
+	}
+
+	kinds := make(map[string]string)
+
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		kinds[node.Name] = node.Kind
+
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	for name, kind := range map[string]string{"react": KindPackage, "@babel/runtime": KindPackage, ".pnpm": KindDirectory,
+		"lodash@4.17.21": KindDirectory, "lodash": KindPackage, "map.js": KindSource} {
+		if kinds[name] != kind {
+			t.Errorf("Expected %s to be a %s, got %q", name, kind, kinds[name])
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package size
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FormatBytes formats bytes with a binary unit, e.g. 1.5 KiB.
+func FormatBytes(bytes int) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes) / unit
+
+	for _, suffix := range []string{"KiB", "MiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+
+		value /= unit
+	}
+
+	return fmt.Sprintf("%.1f GiB", value)
+}
+
+// WriteText writes the tree of bundle to w, with the size of every node and its share of the bundle.
+// Nodes deeper than depth are left out, unless depth <= 0.
+func (bundle *Bundle) WriteText(w io.Writer, depth int) error {
+	var out strings.Builder
+
+	share := func(bytes int) float64 {
+		if bundle.TotalBytes == 0 {
+			return 0
+		}
+
+		return float64(bytes) * 100 / float64(bundle.TotalBytes)
+	}
+
+	var write func(node *Node, prefix string, level int)
+	write = func(node *Node, prefix string, level int) {
+		if depth > 0 && level >= depth {
+			return
+		}
+
+		for i, child := range node.Children {
+			branch, indent := "├── ", "│   "
+
+			if i == len(node.Children)-1 {
+				branch, indent = "└── ", "    "
+			}
+
+			name := child.Name
+
+			if child.Kind == KindDirectory || child.Kind == KindPackage {
+				name += "/"
+			}
+
+			fmt.Fprintf(&out, "%s%s%s  %s  %.1f%%\n", prefix, branch, name, FormatBytes(child.Bytes), share(child.Bytes))
+			write(child, prefix+indent, level+1)
+		}
+	}
+
+	fmt.Fprintf(&out, "%s  %s\n", bundle.Name, FormatBytes(bundle.TotalBytes))
+	write(bundle.Tree, "", 0)
+
+	_, err := io.WriteString(w, out.String())
+
+	if err != nil {
+		return fmt.Errorf("Error writing size of %s: %w", bundle.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package size
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"}
+
+	for bytes, expected := range tests {
+		if formatted := FormatBytes(bytes); formatted != expected {
+			t.Errorf("%d: expected %s, got %s", bytes, expected, formatted)
+		}
+	}
+}
+
+func TestBundleWriteText(t *testing.T) {
+	var out strings.Builder
+
+	if err := testBundle(t).WriteText(&out, 2); err != nil {
+		t.Fatalf("Error writing text: %v", err)
+	}
+
+	expected := `app.js  50 B
+├── [unmapped]  32 B  64.0%
+├── node_modules/  12 B  24.0%
+│   ├── .pnpm/  4 B  8.0%
+│   ├── @babel/runtime/  4 B  8.0%
+│   └── react/  4 B  8.0%
+├── app/  4 B  8.0%
+│   └── src/  4 B  8.0%
+└── [EOLs]  2 B  4.0%
+`
+
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,256 @@
+package size
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Dimensions of each treemap in pixels, and of the header strip holding the name of a node with children.
+const (
+	treemapWidth  = 1200
+	treemapHeight = 700
+	headerHeight  = 18
+	// minLabelWidth and minLabelHeight are the smallest box that is labeled, smaller boxes only have a tooltip
+	minLabelWidth  = 48
+	minLabelHeight = 16
+)
+
+type rect struct {
+	x, y, w, h float64
+}
+
+// treemapBox is a node drawn in a treemap.
+type treemapBox struct {
+	Style template.CSS
+	Label string
+	Title string
+	Kind  string
+}
+
+type treemapPackage struct {
+	Name  string
+	Size  string
+	Share string
+}
+
+type treemapBundle struct {
+	Name     string
+	Size     string
+	Width    int
+	Height   int
+	Boxes    []treemapBox
+	Packages []treemapPackage
+}
+
+// squarify lays out values, sorted from largest to smallest, in r so that the area of each rectangle is proportional to
+// its value, keeping the rectangles as close to squares as possible. See Bruls, Huizing and van Wijk, Squarified Treemaps.
+func squarify(values []float64, r rect) []rect {
+	rects := make([]rect, len(values))
+	total := 0.0
+
+	for _, value := range values {
+		total += value
+	}
+
+	if total <= 0 || r.w <= 0 || r.h <= 0 {
+		return rects
+	}
+
+	scale := r.w * r.h / total
+	areas := make([]float64, len(values))
+
+	for i, value := range values {
+		areas[i] = value * scale
+	}
+
+	// worst returns the highest aspect ratio of row laid out along a side of length side
+	worst := func(row []float64, side float64) float64 {
+		sum, ratio := 0.0, 0.0
+
+		for _, area := range row {
+			sum += area
+		}
+
+		for _, area := range row {
+			ratio = max(ratio, side*side*area/(sum*sum), sum*sum/(side*side*area))
+		}
+
+		return ratio
+	}
+
+	for i := 0; i < len(areas); {
+		side := min(r.w, r.h)
+		j := i + 1
+
+		for j < len(areas) && worst(areas[i:j+1], side) <= worst(areas[i:j], side) {
+			j++
+		}
+
+		sum := 0.0
+
+		for _, area := range areas[i:j] {
+			sum += area
+		}
+
+		if r.w >= r.h {
+			// The row is a column on the left of r
+			width := sum / r.h
+			y := r.y
+
+			for k := i; k < j; k++ {
+				rects[k] = rect{x: r.x, y: y, w: width, h: areas[k] / width}
+				y += rects[k].h
+			}
+
+			r.x += width
+			r.w -= width
+		} else {
+			// The row is along the top of r
+			height := sum / r.w
+			x := r.x
+
+			for k := i; k < j; k++ {
+				rects[k] = rect{x: x, y: r.y, w: areas[k] / height, h: height}
+				x += rects[k].w
+			}
+
+			r.y += height
+			r.h -= height
+		}
+
+		i = j
+	}
+
+	return rects
+}
+
+// layoutBoxes adds a box for node in r, and for its children inside it, to boxes. hue is the color of the top level node
+// that node belongs to.
+func layoutBoxes(boxes []treemapBox, node *Node, path string, r rect, depth int, hue int, total int) []treemapBox {
+	if r.w < 1 || r.h < 1 {
+		return boxes
+	}
+
+	share := 0.0
+
+	if total > 0 {
+		share = float64(node.Bytes) * 100 / float64(total)
+	}
+
+	box := treemapBox{
+		Style: template.CSS(fmt.Sprintf("left: %.1fpx; top: %.1fpx; width: %.1fpx; height: %.1fpx; background: hsl(%d, 55%%, %d%%)",
+			r.x, r.y, r.w, r.h, hue, max(60, 88-6*depth))),
+		Title: fmt.Sprintf("%s\n%s (%.1f%%)", path, FormatBytes(node.Bytes), share),
+		Kind:  node.Kind,
+	}
+
+	if r.w >= minLabelWidth && r.h >= minLabelHeight {
+		box.Label = fmt.Sprintf("%s %s", node.Name, FormatBytes(node.Bytes))
+	}
+
+	boxes = append(boxes, box)
+
+	children := make([]*Node, 0, len(node.Children))
+	values := make([]float64, 0, len(node.Children))
+
+	for _, child := range node.Children {
+		if child.Bytes > 0 {
+			children = append(children, child)
+			values = append(values, float64(child.Bytes))
+		}
+	}
+
+	inner := rect{x: r.x + 2, y: r.y + headerHeight, w: r.w - 4, h: r.h - headerHeight - 2}
+
+	if len(children) == 0 || inner.w < 4 || inner.h < 4 {
+		return boxes
+	}
+
+	for i, childRect := range squarify(values, inner) {
+		boxes = layoutBoxes(boxes, children[i], path+"/"+children[i].Name, childRect, depth+1, hue, total)
+	}
+
+	return boxes
+}
+
+// RenderTreemap writes a self-contained HTML page to w, with a treemap of the tree of each bundle and a table of the
+// bytes of its node_modules packages.
+func RenderTreemap(w io.Writer, bundles []*Bundle) error {
+	page := make([]treemapBundle, len(bundles))
+
+	for i, bundle := range bundles {
+		page[i] = treemapBundle{
+			Name:   bundle.Name,
+			Size:   FormatBytes(bundle.TotalBytes),
+			Width:  treemapWidth,
+			Height: treemapHeight,
+			Boxes:  make([]treemapBox, 0),
+		}
+
+		values := make([]float64, len(bundle.Tree.Children))
+
+		for j, child := range bundle.Tree.Children {
+			values[j] = float64(child.Bytes)
+		}
+
+		for j, childRect := range squarify(values, rect{w: treemapWidth, h: treemapHeight}) {
+			child := bundle.Tree.Children[j]
+			page[i].Boxes = layoutBoxes(page[i].Boxes, child, child.Name, childRect, 0, j*47%360, bundle.TotalBytes)
+		}
+
+		names := slices.SortedFunc(maps.Keys(bundle.Packages), func(a string, b string) int {
+			return cmp.Or(cmp.Compare(bundle.Packages[b], bundle.Packages[a]), strings.Compare(a, b))
+		})
+
+		for _, name := range names {
+			page[i].Packages = append(page[i].Packages, treemapPackage{
+				Name:  name,
+				Size:  FormatBytes(bundle.Packages[name]),
+				Share: fmt.Sprintf("%.1f%%", float64(bundle.Packages[name])*100/float64(max(bundle.TotalBytes, 1))),
+			})
+		}
+	}
+
+	err := treemapTemplate.Execute(w, page)
+
+	if err != nil {
+		return fmt.Errorf("Error rendering treemap: %w", err)
+	}
+
+	return nil
+}
+
+var treemapTemplate = template.Must(template.New("treemap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Bundle size</title>
+<style>
+body { margin: 16px; font-family: sans-serif; font-size: 13px; }
+h2 { font-size: 16px; }
+.treemap { position: relative; border: 1px solid #999; }
+.box { position: absolute; box-sizing: border-box; border: 1px solid rgba(0, 0, 0, 0.25); overflow: hidden; white-space: nowrap; text-overflow: ellipsis; padding: 1px 3px; font-size: 11px; }
+.box:hover { outline: 2px solid #333; z-index: 1; }
+.other { background: repeating-linear-gradient(45deg, #eee, #eee 4px, #ddd 4px, #ddd 8px) !important; }
+table { border-collapse: collapse; margin-top: 12px; }
+td, th { padding: 2px 12px 2px 0; text-align: left; }
+td.bytes { text-align: right; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Name}} ({{.Size}})</h2>
+<div class="treemap" style="width: {{.Width}}px; height: {{.Height}}px">
+{{range .Boxes}}<div class="box{{if eq .Kind "other"}} other{{end}}" style="{{.Style}}" title="{{.Title}}">{{.Label}}</div>
+{{end}}</div>
+{{if .Packages}}<table>
+<tr><th>Package</th><th>Size</th><th>Share</th></tr>
+{{range .Packages}}<tr><td>{{.Name}}</td><td class="bytes">{{.Size}}</td><td class="bytes">{{.Share}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,52 @@
+package size
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSquarify(t *testing.T) {
+	values := []float64{6, 6, 4, 3, 2, 2, 1}
+	bounds := rect{w: 6, h: 4}
+	rects := squarify(values, bounds)
+
+	for i, r := range rects {
+		if math.Abs(r.w*r.h-values[i]) > 1e-9 {
+			t.Errorf("Rect %d: expected area %v, got %v", i, values[i], r.w*r.h)
+		}
+
+		if r.x < -1e-9 || r.y < -1e-9 || r.x+r.w > bounds.w+1e-9 || r.y+r.h > bounds.h+1e-9 {
+			t.Errorf("Rect %d is outside the bounds: %+v", i, r)
+		}
+
+		for j := i + 1; j < len(rects); j++ {
+			other := rects[j]
+
+			if r.x+1e-9 < other.x+other.w && other.x+1e-9 < r.x+r.w && r.y+1e-9 < other.y+other.h && other.y+1e-9 < r.y+r.h {
+				t.Errorf("Rects %d and %d overlap: %+v %+v", i, j, r, other)
+			}
+		}
+	}
+
+	// The first row of the example in the paper is the two 6s, stacked on the left
+	if rects[0] != (rect{x: 0, y: 0, w: 3, h: 2}) || rects[1] != (rect{x: 0, y: 2, w: 3, h: 2}) {
+		t.Errorf("Unexpected first row %+v %+v", rects[0], rects[1])
+	}
+}
+
+func TestRenderTreemap(t *testing.T) {
+	var out strings.Builder
+
+	if err := RenderTreemap(&out, []*Bundle{testBundle(t)}); err != nil {
+		t.Fatalf("Error rendering treemap: %v", err)
+	}
+
+	page := out.String()
+
+	for _, expected := range []string{"<h2>app.js (50 B)</h2>", `class="box other"`, "node_modules 12 B", "<td>@babel/runtime</td>", `title="node_modules/react`} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the page to contain %s", expected)
+		}
+	}
+}
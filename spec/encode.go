@@ -0,0 +1,171 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+)
+
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// EncodeBase64VLQ returns the base64 VLQ encoding of value, the inverse of DecodeBase64VLQ.
+func EncodeBase64VLQ(value int) string {
+	vlq := value << 1
+
+	if value < 0 {
+		vlq = (-value << 1) | 1
+	}
+
+	encoded := make([]byte, 0, 2)
+
+	for {
+		digit := vlq & 31
+		vlq >>= 5
+
+		if vlq > 0 {
+			digit |= 32
+		}
+
+		encoded = append(encoded, base64Alphabet[digit])
+
+		if vlq == 0 {
+			return string(encoded)
+		}
+	}
+}
+
+// EncodeMappings encodes mappings into a mappings string, the inverse of DecodeMappings.
+// Source indexes refer to sources, and names are returned in the order they are first used.
+// Mappings are ordered by generated position first, and mappings whose OriginalSource is not in sources are encoded
+// without an original position.
+func EncodeMappings(mappings []*DecodedMappingRecord, sources []*DecodedSourceRecord) (string, []string) {
+	sourceIndexes := make(map[*DecodedSourceRecord]int, len(sources))
+
+	for index, source := range sources {
+		if _, ok := sourceIndexes[source]; !ok {
+			sourceIndexes[source] = index
+		}
+	}
+
+	sorted := slices.Clone(mappings)
+	slices.SortStableFunc(sorted, func(a *DecodedMappingRecord, b *DecodedMappingRecord) int {
+		if a.GeneratedLine != b.GeneratedLine {
+			return a.GeneratedLine - b.GeneratedLine
+		}
+
+		return a.GeneratedColumn - b.GeneratedColumn
+	})
+
+	names := make([]string, 0)
+	nameIndexes := make(map[string]int)
+
+	var encoded strings.Builder
+
+	generatedLine := 0
+	generatedColumn := 0
+	sourceIndex := 0
+	originalLine := 0
+	originalColumn := 0
+	nameIndex := 0
+	// lineStarted is whether a segment was written on generatedLine, so the next one needs a separator
+	lineStarted := false
+
+	for _, mapping := range sorted {
+		if mapping.GeneratedLine < 0 || mapping.GeneratedColumn < 0 {
+			continue
+		}
+
+		if lineStarted && mapping.GeneratedLine == generatedLine {
+			encoded.WriteByte(',')
+		}
+
+		for generatedLine < mapping.GeneratedLine {
+			encoded.WriteByte(';')
+			generatedLine++
+			generatedColumn = 0
+		}
+
+		lineStarted = true
+
+		encoded.WriteString(EncodeBase64VLQ(mapping.GeneratedColumn - generatedColumn))
+		generatedColumn = mapping.GeneratedColumn
+
+		index, ok := sourceIndexes[mapping.OriginalSource]
+
+		if mapping.OriginalSource == nil || !ok {
+			continue
+		}
+
+		encoded.WriteString(EncodeBase64VLQ(index - sourceIndex))
+		encoded.WriteString(EncodeBase64VLQ(mapping.OriginalLine - originalLine))
+		encoded.WriteString(EncodeBase64VLQ(mapping.OriginalColumn - originalColumn))
+		sourceIndex = index
+		originalLine = mapping.OriginalLine
+		originalColumn = mapping.OriginalColumn
+
+		if mapping.Name == "" {
+			continue
+		}
+
+		index, ok = nameIndexes[mapping.Name]
+
+		if !ok {
+			index = len(names)
+			nameIndexes[mapping.Name] = index
+			names = append(names, mapping.Name)
+		}
+
+		encoded.WriteString(EncodeBase64VLQ(index - nameIndex))
+		nameIndex = index
+	}
+
+	return encoded.String(), names
+}
+
+// EncodeSourceMap encodes mapRecord into a SourceMap, the inverse of DecodeSourceMap.
+// sourcesContent is only included if a source has content, and sources that mappings refer to but are missing from
+// mapRecord.Sources are added.
+func EncodeSourceMap(mapRecord *DecodedSourceMapRecord) *SourceMap {
+	sources := slices.Clone(mapRecord.Sources)
+	known := make(map[*DecodedSourceRecord]bool, len(sources))
+
+	for _, source := range sources {
+		known[source] = true
+	}
+
+	for _, mapping := range mapRecord.Mappings {
+		if mapping.OriginalSource != nil && !known[mapping.OriginalSource] {
+			known[mapping.OriginalSource] = true
+			sources = append(sources, mapping.OriginalSource)
+		}
+	}
+
+	sourceMap := &SourceMap{
+		Version: 3,
+		File:    mapRecord.File,
+		Sources: make([]string, len(sources)),
+		DebugId: mapRecord.DebugId,
+	}
+
+	hasContent := false
+
+	for index, source := range sources {
+		sourceMap.Sources[index] = source.Url
+		hasContent = hasContent || source.Content != ""
+
+		if source.Ignored {
+			sourceMap.IgnoreList = append(sourceMap.IgnoreList, index)
+		}
+	}
+
+	if hasContent {
+		sourceMap.SourcesContent = make([]string, len(sources))
+
+		for index, source := range sources {
+			sourceMap.SourcesContent[index] = source.Content
+		}
+	}
+
+	sourceMap.Mappings, sourceMap.Names = EncodeMappings(mapRecord.Mappings, sources)
+
+	return sourceMap
+}
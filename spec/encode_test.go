@@ -0,0 +1,80 @@
+package spec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEncodeBase64VLQ(t *testing.T) {
+	for _, value := range []int{0, 1, -1, 15, 16, -16, 31, 32, 1000, -1000, 123456789, -123456789} {
+		encoded := EncodeBase64VLQ(value)
+		position := 0
+		decoded, err := DecodeBase64VLQ(encoded, &position)
+
+		if err != nil {
+			t.Errorf("%d: error decoding %s: %v", value, encoded, err)
+			continue
+		}
+
+		if decoded != value || position != len(encoded) {
+			t.Errorf("%d: %s decoded to %d", value, encoded, decoded)
+		}
+	}
+}
+
+func TestEncodeSourceMap(t *testing.T) {
+	for _, testFile := range testFiles {
+		t.Run(testFile, func(t *testing.T) {
+			contents, err := getTestFileContents(testFile)
+
+			if err != nil {
+				t.Fatalf("Error getting contents of %s: %v", testFile, err)
+			}
+
+			sourceMap, err := ParseJSON(contents)
+
+			if err != nil {
+				t.Fatalf("Error parsing %s: %v", testFile, err)
+			}
+
+			mapRecord, err := DecodeSourceMap(sourceMap, "")
+
+			if err != nil {
+				t.Fatalf("Error decoding %s: %v", testFile, err)
+			}
+
+			encoded := EncodeSourceMap(mapRecord)
+
+			if encoded.Mappings != sourceMap.Mappings {
+				t.Errorf("Expected mappings %s, got %s", sourceMap.Mappings, encoded.Mappings)
+			}
+
+			if !slices.Equal(encoded.Names, sourceMap.Names) {
+				t.Errorf("Expected names %v, got %v", sourceMap.Names, encoded.Names)
+			}
+
+			if !slices.Equal(encoded.Sources, sourceMap.Sources) || !slices.Equal(encoded.SourcesContent, sourceMap.SourcesContent) {
+				t.Errorf("Expected sources %v, got %v", sourceMap.Sources, encoded.Sources)
+			}
+		})
+	}
+}
+
+func TestEncodeMappingsUnmapped(t *testing.T) {
+	source := &DecodedSourceRecord{Url: "a.js"}
+	mappings := []*DecodedMappingRecord{
+		{GeneratedLine: 2, GeneratedColumn: 4, OriginalSource: source, OriginalLine: 1, OriginalColumn: 2, Name: "b"},
+		{GeneratedLine: 0, GeneratedColumn: 3},
+		{GeneratedLine: 0, GeneratedColumn: 0, OriginalSource: source},
+	}
+
+	encoded, names := EncodeMappings(mappings, []*DecodedSourceRecord{source})
+
+	if encoded != "AAAA,G;;IACEA" {
+		t.Errorf("Expected AAAA,G;;IACEA, got %s", encoded)
+	}
+
+	if !slices.Equal(names, []string{"b"}) {
+		t.Errorf("Expected names [b], got %v", names)
+	}
+}
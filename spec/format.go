@@ -5,21 +5,53 @@ type SourceMap struct {
 	// Version must always be 3
 	Version int `json:"version"`
 	// File is the *optional* name of the compiled output i.e. *.map.js
-	File string `json:"file"`
+	File string `json:"file,omitempty"`
 	// SourceRoot is optional
-	SourceRoot string `json:"sourceRoot"`
+	SourceRoot string `json:"sourceRoot,omitempty"`
 	// Sources is the original mapped sources names
 	Sources []string `json:"sources"`
 	// SourcesContent is the original mapped sources contents
-	SourcesContent []string `json:"sourcesContent"`
+	SourcesContent []string `json:"sourcesContent,omitempty"`
 	// Name is the optional symbol names which can be used by mappings field
 	Names []string `json:"names"`
 	// Mappings is the encoded mapping data
 	Mappings string `json:"mappings"`
 	// IgnoreList is an optional list of indices that should be considered third-party code
-	IgnoreList []int `json:"ignoreList"`
+	IgnoreList []int `json:"ignoreList,omitempty"`
 	// Deprecated: XGoogleIgnoreList is only checked if IgnoreList is not present
-	XGoogleIgnoreList []int `json:"x_google_ignoreList"`
+	XGoogleIgnoreList []int `json:"x_google_ignoreList,omitempty"`
+	// DebugId is the optional id shared by the source map and its generated file, see [Debug ID proposal]
+	//
+	// [Debug ID proposal]: https://github.com/tc39/ecma426/blob/main/proposals/debug-id.md
+	DebugId string `json:"debugId,omitempty"`
+	// Sections is only set for index maps, which have no mappings of their own, see IndexSourceMap
+	Sections []Section `json:"sections,omitempty"`
+}
+
+// IndexSourceMap represents the raw json of an index map, which combines the source maps of its sections
+//
+// [Source map format specification]
+//
+// [Source map format specification]: https://tc39.es/ecma426/#sec-index-source-map
+type IndexSourceMap struct {
+	// Version must always be 3
+	Version int `json:"version"`
+	// File is the *optional* name of the compiled output
+	File string `json:"file,omitempty"`
+	// Sections are ordered by their offset, and must not overlap
+	Sections []Section `json:"sections"`
+}
+
+// Section is a source map that applies to the generated output starting at Offset
+type Section struct {
+	Offset SectionOffset `json:"offset"`
+	Map    *SourceMap    `json:"map"`
+}
+
+// SectionOffset is the 0-based generated line and column where a Section starts
+type SectionOffset struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
 
 // DecodedSourceRecord represents an original source file
@@ -52,4 +84,6 @@ type DecodedSourceMapRecord struct {
 	Sources []*DecodedSourceRecord `json:"sources"`
 	// Mappings is the symbol mappings from source records to compuled output map record
 	Mappings []*DecodedMappingRecord `json:"mappings"`
+	// DebugId is the *optional* id shared by the source map and its generated file
+	DebugId string `json:"debugId,omitempty"`
 }
@@ -14,7 +14,6 @@ import (
 
 // ParseSourceMap parses str into a DecodedSourceMapRecord
 // Returns an error if parsing was not successfull
-// TODO: Support sourcemaps with the optional "sections" extension
 //
 // [Source map format specification]
 //
@@ -26,7 +25,9 @@ func ParseSourceMap(str string, baseURL string) (*DecodedSourceMapRecord, error)
 		return nil, fmt.Errorf("Error parsing str: %w", err)
 	}
 
-	// TODO: call DecodeIndexSourceMap(sourceMap, baseURL) here if "sections" exists
+	if sourceMap.Sections != nil {
+		return DecodeIndexSourceMap(sourceMap, baseURL)
+	}
 
 	return DecodeSourceMap(sourceMap, baseURL)
 }
@@ -84,9 +85,69 @@ func DecodeSourceMap(sourceMap *SourceMap, baseURL string) (*DecodedSourceMapRec
 		File:     sourceMap.File,
 		Sources:  sources,
 		Mappings: mappings,
+		DebugId:  sourceMap.DebugId,
 	}, nil
 }
 
+// DecodeIndexSourceMap decodes the index map sourceMap into a DecodedSourceMapRecord,
+// with the sources and mappings of every section, shifted to the offset of the section.
+// Returns an error if the sections are not ordered, or a section is itself an index map.
+//
+// [Source map format specification]
+//
+// [Source map format specification]: https://tc39.es/ecma426/#sec-DecodeIndexSourceMap
+func DecodeIndexSourceMap(sourceMap *SourceMap, baseURL string) (*DecodedSourceMapRecord, error) {
+	if sourceMap.Version != 3 {
+		slog.Warn("Version was not 3, parsing may fail", "version", sourceMap.Version)
+	}
+
+	decoded := &DecodedSourceMapRecord{
+		File:     sourceMap.File,
+		Sources:  make([]*DecodedSourceRecord, 0),
+		Mappings: make([]*DecodedMappingRecord, 0),
+		DebugId:  sourceMap.DebugId,
+	}
+
+	previous := SectionOffset{Line: -1, Column: -1}
+
+	for index, section := range sourceMap.Sections {
+		offset := section.Offset
+
+		if offset.Line < previous.Line || (offset.Line == previous.Line && offset.Column <= previous.Column) {
+			return nil, fmt.Errorf("Error: section %d offset %d:%d is not after the previous section", index, offset.Line, offset.Column)
+		}
+
+		previous = offset
+
+		if section.Map == nil {
+			return nil, fmt.Errorf("Error: section %d has no map", index)
+		}
+
+		if section.Map.Sections != nil {
+			return nil, fmt.Errorf("Error: section %d is an index map, which is not allowed", index)
+		}
+
+		sectionRecord, err := DecodeSourceMap(section.Map, baseURL)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding section %d: %w", index, err)
+		}
+
+		for _, mapping := range sectionRecord.Mappings {
+			if mapping.GeneratedLine == 0 {
+				mapping.GeneratedColumn += offset.Column
+			}
+
+			mapping.GeneratedLine += offset.Line
+		}
+
+		decoded.Sources = append(decoded.Sources, sectionRecord.Sources...)
+		decoded.Mappings = append(decoded.Mappings, sectionRecord.Mappings...)
+	}
+
+	return decoded, nil
+}
+
 // DecodeSourceMapSources decodes source map source information and returns a DecodedSourceRecord.
 //
 // [Source map format specification]
@@ -38,3 +38,71 @@ func TestParseSourceMap(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIndexSourceMap(t *testing.T) {
+	indexMap := `{
+		"version": 3,
+		"file": "bundle.js",
+		"sections": [
+			{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "names": ["foo"], "mappings": "AAAA,IAAIA;AACJ"}},
+			{"offset": {"line": 1, "column": 10}, "map": {"version": 3, "sources": ["b.js"], "names": [], "mappings": "AAAA;AACA"}}
+		]
+	}`
+
+	mapRecord, err := ParseSourceMap(indexMap, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing index map: %v", err)
+	}
+
+	if mapRecord.File != "bundle.js" || len(mapRecord.Sources) != 2 {
+		t.Fatalf("Expected bundle.js with 2 sources, got %s with %d sources", mapRecord.File, len(mapRecord.Sources))
+	}
+
+	expected := []struct {
+		generatedLine, generatedColumn int
+		source                         string
+		originalLine                   int
+	}{
+		{0, 0, "a.js", 0},
+		{0, 4, "a.js", 0},
+		{1, 0, "a.js", 1},
+		{1, 10, "b.js", 0},
+		{2, 0, "b.js", 1},
+	}
+
+	if len(mapRecord.Mappings) != len(expected) {
+		t.Fatalf("Expected %d mappings, got %d", len(expected), len(mapRecord.Mappings))
+	}
+
+	for i, mapping := range mapRecord.Mappings {
+		test := expected[i]
+
+		if mapping.GeneratedLine != test.generatedLine || mapping.GeneratedColumn != test.generatedColumn ||
+			mapping.OriginalSource.Url != test.source || mapping.OriginalLine != test.originalLine {
+			t.Errorf("Mapping %d: expected %v, got %d:%d %s:%d", i, test, mapping.GeneratedLine, mapping.GeneratedColumn,
+				mapping.OriginalSource.Url, mapping.OriginalLine)
+		}
+	}
+}
+
+func TestParseIndexSourceMapInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unordered": `{"version": 3, "sections": [
+			{"offset": {"line": 1, "column": 0}, "map": {"version": 3, "sources": [], "mappings": ""}},
+			{"offset": {"line": 0, "column": 5}, "map": {"version": 3, "sources": [], "mappings": ""}}
+		]}`,
+		"nested": `{"version": 3, "sections": [
+			{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sections": []}}
+		]}`,
+		"missing map": `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}}]}`,
+	}
+
+	for name, indexMap := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseSourceMap(indexMap, ""); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// statsResult is the per-input output of go-sourcemap stats -json when more than one input is given.
+type statsResult struct {
+	Input string                `json:"input"`
+	Stats *tools.SourceMapStats `json:"stats"`
+}
+
+// runStats implements go-sourcemap stats, printing quality metrics of source maps.
+func runStats(arguments []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap stats: go-sourcemap stats [flags] input ...")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+
+	fetch.register(flags)
+	jsonOutput := flags.Bool("json", false, "Print the stats as JSON, one object per line when there is more than one input")
+	generatedPath := flags.String("generated", "", "Generated file the source map belongs to, to measure coverage. "+
+		"Defaults to the input without its .map extension, if that file exists")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	inputs, err := expandInputs(flags.Args())
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if *generatedPath != "" && len(inputs) > 1 {
+		fmt.Println("-generated can only be given with a single input")
+		os.Exit(-1)
+	}
+
+	fetcher, err := fetch.build(flags.Args()...)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	failed := 0
+
+	for _, input := range inputs {
+		stats, err := inputStats(input, *generatedPath, fetcher)
+
+		if err == nil {
+			err = printStats(input, stats, *jsonOutput, len(inputs) > 1)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(-1)
+	}
+}
+
+// inputStats computes the stats of the source map named by input, with the generated file at generatedPath,
+// or next to input if generatedPath is "".
+func inputStats(input string, generatedPath string, fetcher *tools.Fetcher) (*tools.SourceMapStats, error) {
+	contents, err := readInput(input, fetcher)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sourceMap, err := spec.ParseJSON(contents)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %w", input, err)
+	}
+
+	options := tools.StatsOptions{Names: sourceMap.Names}
+
+	for _, section := range sourceMap.Sections {
+		if section.Map != nil {
+			options.Names = append(options.Names, section.Map.Names...)
+		}
+	}
+
+	var mapRecord *spec.DecodedSourceMapRecord
+
+	if sourceMap.Sections != nil {
+		mapRecord, err = spec.DecodeIndexSourceMap(sourceMap, "")
+	} else {
+		mapRecord, err = spec.DecodeSourceMap(sourceMap, "")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %w", input, err)
+	}
+
+	if generatedPath == "" && input != tools.StdinInput && !isUrlInput(input) && strings.HasSuffix(input, ".map") {
+		if _, err := os.Stat(strings.TrimSuffix(input, ".map")); err == nil {
+			generatedPath = strings.TrimSuffix(input, ".map")
+		}
+	}
+
+	if generatedPath != "" {
+		options.Generated, err = readInput(generatedPath, fetcher)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tools.Stats(mapRecord, options), nil
+}
+
+// printStats prints the stats of input to stdout, as text or JSON. If wrap is true, the output names the input.
+func printStats(input string, stats *tools.SourceMapStats, jsonOutput bool, wrap bool) error {
+	if !jsonOutput {
+		if wrap {
+			fmt.Printf("==> %s <==\n", input)
+		}
+
+		return stats.WriteText(os.Stdout)
+	}
+
+	var output any = stats
+
+	if wrap {
+		output = statsResult{Input: input, Stats: stats}
+	}
+
+	statsStr, err := json.Marshal(output)
+
+	if err != nil {
+		return fmt.Errorf("Error stringifying stats of %s: %w", input, err)
+	}
+
+	fmt.Println(string(statsStr))
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// SourceWriter is a destination for extracted sources.
+type SourceWriter interface {
+	// WriteSource writes content to path, a sanitized, slash separated relative path.
+	// Returns ErrDuplicateSource if path was already written, leaving the first content in place,
+	// and ErrPathConflict if path can't be written because of another file or directory
+	WriteSource(path string, content []byte) error
+}
+
+// ErrDuplicateSource is returned by SourceWriter.WriteSource when a path is written more than once.
+var ErrDuplicateSource = errors.New("path was already written")
+
+// ErrPathConflict is returned by SourceWriter.WriteSource when a path is both a file and a directory,
+// such as a.js and a.js/b.js.
+var ErrPathConflict = errors.New("path conflicts with another file or directory")
+
+// writtenPaths records the slash separated paths written by a SourceWriter, and their directories.
+type writtenPaths struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+func newWrittenPaths() *writtenPaths {
+	return &writtenPaths{files: make(map[string]bool), dirs: make(map[string]bool)}
+}
+
+// check returns ErrDuplicateSource if name was already written, or ErrPathConflict if name is the directory of a written
+// path, or is inside a written path.
+func (written *writtenPaths) check(name string) error {
+	if written.files[name] {
+		return ErrDuplicateSource
+	}
+
+	if written.dirs[name] {
+		return ErrPathConflict
+	}
+
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if written.files[dir] {
+			return ErrPathConflict
+		}
+	}
+
+	return nil
+}
+
+// add records name, and its directories, as written.
+func (written *writtenPaths) add(name string) {
+	written.files[name] = true
+
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		written.dirs[dir] = true
+	}
+}
+
+// DirectoryWriter is a SourceWriter that saves sources as files under a directory.
+// Only the first write to a path is saved, later writes of the same path return ErrDuplicateSource.
+type DirectoryWriter struct {
+	dir     string
+	written *writtenPaths
+}
+
+// NewDirectoryWriter returns a DirectoryWriter saving to dir.
+// If dir doesn't exist, it is recursively created with 0700 permissions.
+func NewDirectoryWriter(dir string) (*DirectoryWriter, error) {
+	err := os.MkdirAll(dir, 0700)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error creating %s: %w", dir, err)
+	}
+
+	return &DirectoryWriter{dir: dir, written: newWrittenPaths()}, nil
+}
+
+// WriteSource saves content to path under the writer's directory, creating parent directories with 0700 permissions.
+// Files are saved with 0600 permissions.
+// Returns an error if path would be outside of the directory. Files already in the directory that are in the way of
+// path, such as a file where a directory of path should be, are reported as ErrPathConflict.
+func (writer *DirectoryWriter) WriteSource(sourcePath string, content []byte) error {
+	fullPath, err := SafeJoin(writer.dir, sourcePath)
+
+	if err != nil {
+		return err
+	}
+
+	name := path.Clean(sourcePath)
+	err = writer.written.check(name)
+
+	if err != nil {
+		return fmt.Errorf("Error writing %s: %w", sourcePath, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(fullPath), 0700)
+
+	if errors.Is(err, syscall.ENOTDIR) || errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("Error creating %s: %w", filepath.Dir(fullPath), ErrPathConflict)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %w", filepath.Dir(fullPath), err)
+	}
+
+	err = os.WriteFile(fullPath, content, 0600)
+
+	if errors.Is(err, syscall.EISDIR) {
+		return fmt.Errorf("Error writing file contents to %s: %w", fullPath, ErrPathConflict)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Error writing file contents to %s: %w", fullPath, err)
+	}
+
+	writer.written.add(name)
+
+	return nil
+}
+
+// ArchiveFormat is the container format written by an ArchiveWriter.
+type ArchiveFormat int
+
+const (
+	// ArchiveZip writes a zip archive
+	ArchiveZip ArchiveFormat = iota
+	// ArchiveTar writes an uncompressed tar archive
+	ArchiveTar
+	// ArchiveTarGz writes a gzip compressed tar archive
+	ArchiveTarGz
+)
+
+// ArchiveFormatFromPath returns the ArchiveFormat matching the extension of name,
+// one of .zip, .tar, .tar.gz or .tgz.
+func ArchiveFormatFromPath(name string) (ArchiveFormat, error) {
+	lower := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz, nil
+	}
+
+	return ArchiveZip, fmt.Errorf("Error: cannot determine archive format of %s, expected .zip, .tar, .tar.gz or .tgz", name)
+}
+
+// ArchiveManifestPath is the path of the manifest written by ArchiveWriter.WriteManifest.
+const ArchiveManifestPath = "manifest.json"
+
+// archiveSourcesDir is the directory of the archive that sources are written under,
+// so that they can never collide with ArchiveManifestPath.
+const archiveSourcesDir = "sources/"
+
+// ArchiveWriter is a SourceWriter that writes sources into a zip or tar archive.
+// Sources are stored under sources/, and WriteManifest stores manifest.json at the root of the archive.
+// Only the first write to a path is stored, later writes of the same path return ErrDuplicateSource.
+type ArchiveWriter struct {
+	zipWriter  *zip.Writer
+	tarWriter  *tar.Writer
+	gzipWriter *gzip.Writer
+	written    *writtenPaths
+	modTime    time.Time
+}
+
+// NewArchiveWriter returns an ArchiveWriter writing an archive of format to w.
+// Close must be called to finish the archive, it does not close w.
+func NewArchiveWriter(w io.Writer, format ArchiveFormat) *ArchiveWriter {
+	writer := &ArchiveWriter{
+		written: newWrittenPaths(),
+		modTime: time.Now(),
+	}
+
+	switch format {
+	case ArchiveZip:
+		writer.zipWriter = zip.NewWriter(w)
+	case ArchiveTarGz:
+		writer.gzipWriter = gzip.NewWriter(w)
+		writer.tarWriter = tar.NewWriter(writer.gzipWriter)
+	default:
+		writer.tarWriter = tar.NewWriter(w)
+	}
+
+	return writer
+}
+
+// WriteSource stores content at sources/path in the archive.
+func (writer *ArchiveWriter) WriteSource(path string, content []byte) error {
+	if path == "" || SanitizePath(path) != path {
+		return fmt.Errorf("Error writing %q to archive: not a sanitized path", path)
+	}
+
+	return writer.writeEntry(archiveSourcesDir+path, content)
+}
+
+// WriteManifest stores manifest as manifest.json at the root of the archive.
+// The paths in manifest are as passed to WriteSource, and are stored relative to the archive root.
+func (writer *ArchiveWriter) WriteManifest(manifest []WrittenSource) error {
+	entries := make([]WrittenSource, len(manifest))
+
+	for i, entry := range manifest {
+		entry.Path = archiveSourcesDir + entry.Path
+		entries[i] = entry
+	}
+
+	contents, err := json.MarshalIndent(entries, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("Error stringifying manifest: %w", err)
+	}
+
+	return writer.writeEntry(ArchiveManifestPath, contents)
+}
+
+// writeEntry stores content at name in the archive. Returns ErrDuplicateSource if name was already written, and
+// ErrPathConflict if name is a directory of a written entry or inside one, which can't be extracted.
+func (writer *ArchiveWriter) writeEntry(name string, content []byte) error {
+	err := writer.written.check(name)
+
+	if err != nil {
+		return fmt.Errorf("Error adding %s to archive: %w", name, err)
+	}
+
+	writer.written.add(name)
+
+	if writer.zipWriter != nil {
+		entry, err := writer.zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: writer.modTime,
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error adding %s to archive: %w", name, err)
+		}
+
+		_, err = entry.Write(content)
+
+		if err != nil {
+			return fmt.Errorf("Error writing %s to archive: %w", name, err)
+		}
+
+		return nil
+	}
+
+	err = writer.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0600,
+		Size:     int64(len(content)),
+		ModTime:  writer.modTime,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error adding %s to archive: %w", name, err)
+	}
+
+	_, err = writer.tarWriter.Write(content)
+
+	if err != nil {
+		return fmt.Errorf("Error writing %s to archive: %w", name, err)
+	}
+
+	return nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (writer *ArchiveWriter) Close() error {
+	if writer.zipWriter != nil {
+		return writer.zipWriter.Close()
+	}
+
+	err := writer.tarWriter.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if writer.gzipWriter != nil {
+		return writer.gzipWriter.Close()
+	}
+
+	return nil
+}
+
+// ExtractSourcesToArchive writes mapRecord.Sources and a manifest.json to a new archive of format written to w.
+// See ExtractSources for how source urls are turned into paths.
+func ExtractSourcesToArchive(mapRecord *spec.DecodedSourceMapRecord, w io.Writer, format ArchiveFormat, options ExtractOptions) (*ExtractReport, error) {
+	writer := NewArchiveWriter(w, format)
+
+	report, err := ExtractSources(mapRecord, writer, options)
+
+	if err != nil {
+		writer.Close()
+		return report, err
+	}
+
+	err = writer.WriteManifest(report.Written)
+
+	if err != nil {
+		writer.Close()
+		return report, err
+	}
+
+	return report, writer.Close()
+}
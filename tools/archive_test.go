@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+var archiveTestRecord = &spec.DecodedSourceMapRecord{
+	Sources: []*spec.DecodedSourceRecord{
+		{Url: "webpack:///./src/index.js", Content: "index"},
+		{Url: "webpack:///./node_modules/lib/lib.js", Content: "lib", Ignored: true},
+	},
+}
+
+func checkArchiveEntries(t *testing.T, entries map[string][]byte) {
+	t.Helper()
+
+	for path, content := range map[string]string{"sources/src/index.js": "index", "sources/node_modules/lib/lib.js": "lib"} {
+		if string(entries[path]) != content {
+			t.Errorf("Expected %s to contain %q, got %q", path, content, entries[path])
+		}
+	}
+
+	var manifest []WrittenSource
+
+	err := json.Unmarshal(entries[ArchiveManifestPath], &manifest)
+
+	if err != nil {
+		t.Fatalf("Error parsing manifest: %v", err)
+	}
+
+	if len(manifest) != 2 {
+		t.Fatalf("Expected 2 manifest entries, got %v", manifest)
+	}
+
+	if manifest[1].Path != "sources/node_modules/lib/lib.js" || manifest[1].Url != "webpack:///./node_modules/lib/lib.js" || !manifest[1].Ignored || manifest[1].Size != 3 {
+		t.Errorf("Unexpected manifest entry %+v", manifest[1])
+	}
+
+	hash := sha256.Sum256([]byte("index"))
+
+	if manifest[0].Sha256 != hex.EncodeToString(hash[:]) {
+		t.Errorf("Unexpected hash %s", manifest[0].Sha256)
+	}
+}
+
+func TestExtractSourcesToArchiveZip(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	_, err := ExtractSourcesToArchive(archiveTestRecord, buffer, ArchiveZip, ExtractOptions{})
+
+	if err != nil {
+		t.Fatalf("Error writing archive: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+
+	if err != nil {
+		t.Fatalf("Error reading archive: %v", err)
+	}
+
+	entries := make(map[string][]byte)
+
+	for _, file := range reader.File {
+		entry, err := file.Open()
+
+		if err != nil {
+			t.Fatalf("Error opening %s: %v", file.Name, err)
+		}
+
+		entries[file.Name], _ = io.ReadAll(entry)
+		entry.Close()
+	}
+
+	checkArchiveEntries(t, entries)
+}
+
+func TestExtractSourcesToArchiveTarGz(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	_, err := ExtractSourcesToArchive(archiveTestRecord, buffer, ArchiveTarGz, ExtractOptions{})
+
+	if err != nil {
+		t.Fatalf("Error writing archive: %v", err)
+	}
+
+	gzipReader, err := gzip.NewReader(buffer)
+
+	if err != nil {
+		t.Fatalf("Error reading archive: %v", err)
+	}
+
+	reader := tar.NewReader(gzipReader)
+	entries := make(map[string][]byte)
+
+	for {
+		header, err := reader.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("Error reading archive: %v", err)
+		}
+
+		entries[header.Name], _ = io.ReadAll(reader)
+	}
+
+	checkArchiveEntries(t, entries)
+}
+
+func TestArchiveFormatFromPath(t *testing.T) {
+	tests := map[string]ArchiveFormat{
+		"out.zip":    ArchiveZip,
+		"out.tar":    ArchiveTar,
+		"out.tar.gz": ArchiveTarGz,
+		"out.TGZ":    ArchiveTarGz,
+	}
+
+	for name, expected := range tests {
+		format, err := ArchiveFormatFromPath(name)
+
+		if err != nil || format != expected {
+			t.Errorf("ArchiveFormatFromPath(%q) = %v, %v, expected %v", name, format, err, expected)
+		}
+	}
+
+	if _, err := ArchiveFormatFromPath("out.rar"); err == nil {
+		t.Errorf("Expected an error for out.rar")
+	}
+}
+
+func TestSourceWritersDuplicates(t *testing.T) {
+	mapRecord := &spec.DecodedSourceMapRecord{
+		Sources: []*spec.DecodedSourceRecord{
+			{Url: "webpack:///webpack/bootstrap", Content: "first"},
+			{Url: "webpack:///./webpack/bootstrap", Content: "second"},
+		},
+	}
+
+	directory, err := NewDirectoryWriter(t.TempDir())
+
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+
+	writers := map[string]SourceWriter{
+		"directory": directory,
+		"archive":   NewArchiveWriter(io.Discard, ArchiveZip),
+	}
+
+	for name, writer := range writers {
+		report, err := ExtractSources(mapRecord, writer, ExtractOptions{})
+
+		if err != nil {
+			t.Fatalf("%s: error extracting sources: %v", name, err)
+		}
+
+		hash := sha256.Sum256([]byte("first"))
+
+		if len(report.Written) != 1 || report.Written[0].Sha256 != hex.EncodeToString(hash[:]) {
+			t.Errorf("%s: expected only the first source to be written, got %+v", name, report.Written)
+		}
+
+		if len(report.Duplicates) != 1 || report.Duplicates[0].Url != "webpack:///./webpack/bootstrap" {
+			t.Errorf("%s: expected the second source to be a duplicate, got %+v", name, report.Duplicates)
+		}
+	}
+}
+
+func TestSourceWritersConflicts(t *testing.T) {
+	mapRecord := &spec.DecodedSourceMapRecord{
+		Sources: []*spec.DecodedSourceRecord{
+			{Url: "a.js", Content: "file"},
+			{Url: "a.js/b.js", Content: "inside a file"},
+			{Url: "c/d.js", Content: "nested"},
+			{Url: "c", Content: "a directory"},
+			{Url: "e.js", Content: "after the conflicts"},
+		},
+	}
+
+	dir := t.TempDir()
+	directory, err := NewDirectoryWriter(dir)
+
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+
+	writers := map[string]SourceWriter{
+		"directory": directory,
+		"archive":   NewArchiveWriter(io.Discard, ArchiveTar),
+	}
+
+	for name, writer := range writers {
+		report, err := ExtractSources(mapRecord, writer, ExtractOptions{})
+
+		if err != nil {
+			t.Fatalf("%s: error extracting sources: %v", name, err)
+		}
+
+		written := make([]string, 0)
+
+		for _, source := range report.Written {
+			written = append(written, source.Path)
+		}
+
+		if !slices.Equal(written, []string{"a.js", "c/d.js", "e.js"}) {
+			t.Errorf("%s: expected every source without a conflict to be written, got %v", name, written)
+		}
+
+		if len(report.Conflicts) != 2 || report.Conflicts[0].Url != "a.js/b.js" || report.Conflicts[1].Url != "c" {
+			t.Errorf("%s: expected two conflicts, got %+v", name, report.Conflicts)
+		}
+	}
+
+	// Files left in the directory by an earlier extraction conflict too
+	existing, err := NewDirectoryWriter(dir)
+
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+
+	for _, sourcePath := range []string{"e.js/f.js", "c"} {
+		if err := existing.WriteSource(sourcePath, []byte("x")); !errors.Is(err, ErrPathConflict) {
+			t.Errorf("Expected %s to conflict with the existing files, got %v", sourcePath, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// StdinInput is the input name that ParseSourceMapFromInput reads from os.Stdin.
+const StdinInput = "-"
+
+// ParseSourceMapFromInput parses the source map named by input, which is StdinInput, an http(s) url downloaded with fetcher,
+// or a file path. DefaultFetcher is used if fetcher is nil.
+func ParseSourceMapFromInput(ctx context.Context, input string, fetcher *Fetcher) (*spec.DecodedSourceMapRecord, error) {
+	if input == StdinInput {
+		return ParseSourceMapFromReader(os.Stdin, "")
+	}
+
+	if isHttpUrl(input) {
+		if fetcher == nil {
+			fetcher = DefaultFetcher
+		}
+
+		return fetcher.ParseSourceMap(ctx, input)
+	}
+
+	return ParseSourceMapFromFile(input)
+}
+
+// BatchResult is the outcome of parsing one input of a batch.
+type BatchResult struct {
+	// Index is the position of Input in the inputs passed to Batch.Parse
+	Index int
+	// Input is the file, url or StdinInput that was parsed
+	Input string
+	// Record is the parsed source map, nil if Err is set
+	Record *spec.DecodedSourceMapRecord
+	// Err is why Input could not be parsed
+	Err error
+}
+
+// Batch parses many source maps with a bounded pool of workers.
+type Batch struct {
+	// Workers is the number of inputs parsed at once, runtime.NumCPU() if 0
+	Workers int
+	// Fetcher downloads url inputs, DefaultFetcher if nil
+	Fetcher *Fetcher
+}
+
+// Parse parses every input with ParseSourceMapFromInput, and sends a BatchResult for each on the returned channel
+// as soon as it is ready, so results arrive in completion order rather than input order.
+// The channel is closed once every input has been parsed, or ctx is done, in which case the remaining inputs are
+// sent with ctx.Err().
+func (batch *Batch) Parse(ctx context.Context, inputs []string) <-chan BatchResult {
+	workers := batch.Workers
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	workers = min(workers, max(len(inputs), 1))
+
+	jobs := make(chan int)
+	results := make(chan BatchResult, workers)
+	waiting := sync.WaitGroup{}
+
+	for range workers {
+		waiting.Add(1)
+
+		go func() {
+			defer waiting.Done()
+
+			for index := range jobs {
+				result := BatchResult{Index: index, Input: inputs[index]}
+
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+				} else {
+					result.Record, result.Err = ParseSourceMapFromInput(ctx, inputs[index], batch.Fetcher)
+				}
+
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		for index := range inputs {
+			jobs <- index
+		}
+
+		close(jobs)
+		waiting.Wait()
+		close(results)
+	}()
+
+	return results
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatchParse(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("../testdata")))
+	defer server.Close()
+
+	inputs := []string{
+		"../testdata/test1.js.map",
+		server.URL + "/test2.js.map",
+		"../testdata/missing.js.map",
+		"../testdata/test2.js.map",
+	}
+
+	batch := &Batch{Workers: 2, Fetcher: &Fetcher{}}
+	seen := make(map[int]BatchResult)
+
+	for result := range batch.Parse(context.Background(), inputs) {
+		if _, ok := seen[result.Index]; ok {
+			t.Errorf("Received %s twice", result.Input)
+		}
+
+		seen[result.Index] = result
+	}
+
+	if len(seen) != len(inputs) {
+		t.Fatalf("Expected %d results, got %d", len(inputs), len(seen))
+	}
+
+	for index, input := range inputs {
+		result := seen[index]
+
+		if result.Input != input {
+			t.Errorf("Expected result %d to be for %s, got %s", index, input, result.Input)
+		}
+
+		if index == 2 {
+			if result.Err == nil {
+				t.Errorf("Expected an error parsing %s", input)
+			}
+		} else if result.Err != nil || result.Record == nil {
+			t.Errorf("Error parsing %s: %v", input, result.Err)
+		}
+	}
+}
+
+func TestBatchParseCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	count := 0
+
+	for result := range (&Batch{Workers: 1}).Parse(ctx, []string{"../testdata/test1.js.map", "../testdata/test2.js.map"}) {
+		if result.Err != context.Canceled {
+			t.Errorf("Expected context.Canceled for %s, got %v", result.Input, result.Err)
+		}
+
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("Expected a result for every input, got %d", count)
+	}
+}
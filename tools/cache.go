@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// MapCache is an in-memory least recently used cache of parsed source maps.
+// It is safe for concurrent use.
+type MapCache struct {
+	capacity int
+	mutex    sync.Mutex
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type mapCacheEntry struct {
+	key       string
+	mapRecord *spec.DecodedSourceMapRecord
+}
+
+// NewMapCache returns a MapCache holding at most capacity source maps.
+func NewMapCache(capacity int) *MapCache {
+	return &MapCache{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the source map stored under key, and marks it as recently used.
+func (cache *MapCache) Get(key string) (*spec.DecodedSourceMapRecord, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+
+	return element.Value.(*mapCacheEntry).mapRecord, true
+}
+
+// Add stores mapRecord under key, evicting the least recently used source map if the cache is full.
+func (cache *MapCache) Add(key string, mapRecord *spec.DecodedSourceMapRecord) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*mapCacheEntry).mapRecord = mapRecord
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&mapCacheEntry{key: key, mapRecord: mapRecord})
+
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*mapCacheEntry).key)
+	}
+}
+
+// Len returns the number of source maps in the cache.
+func (cache *MapCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.order.Len()
+}
+
+// ParseSourceMapFromFile is ParseSourceMapFromFile, returning the cached source map if filename has not
+// changed size or modification time since it was last parsed.
+func (cache *MapCache) ParseSourceMapFromFile(filename string) (*spec.DecodedSourceMapRecord, error) {
+	info, err := os.Stat(filename)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading contents of %s: %w", filename, err)
+	}
+
+	absolute, err := filepath.Abs(filename)
+
+	if err != nil {
+		absolute = filename
+	}
+
+	key := "file:" + absolute + ":" + strconv.FormatInt(info.Size(), 10) + ":" + strconv.FormatInt(info.ModTime().UnixNano(), 10)
+
+	if mapRecord, ok := cache.Get(key); ok {
+		return mapRecord, nil
+	}
+
+	mapRecord, err := ParseSourceMapFromFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Add(key, mapRecord)
+
+	return mapRecord, nil
+}
+
+// DiskCache stores downloaded responses on disk, keyed by url and credentials, so that a Fetcher can revalidate
+// them with ETag and Last-Modified instead of downloading them again.
+// It is safe for concurrent use by multiple Fetchers and processes.
+type DiskCache struct {
+	dir string
+}
+
+// DiskCacheEntry is a cached response.
+type DiskCacheEntry struct {
+	// Url is the url the response was downloaded from
+	Url string `json:"url"`
+	// Key identifies the entry, the Url if "". Fetcher adds a hash of its credentials to the url, so responses
+	// downloaded with one set of credentials are never returned to requests with others
+	Key string `json:"key,omitempty"`
+	// ETag is the ETag header of the response, if any
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the Last-Modified header of the response, if any
+	LastModified string `json:"lastModified,omitempty"`
+	// StoredAt is when the response was downloaded or last revalidated
+	StoredAt time.Time `json:"storedAt"`
+	// BodySha256 is the hex encoded SHA-256 hash of Body, set by DiskCache.Store, so that Load never pairs the
+	// metadata of one response with the body of another
+	BodySha256 string `json:"bodySha256"`
+	// Body is the decompressed response body, stored separately from the metadata
+	Body []byte `json:"-"`
+}
+
+// NewDiskCache returns a DiskCache storing responses in dir.
+// If dir doesn't exist, it is recursively created with 0700 permissions.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	err := os.MkdirAll(dir, 0700)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error creating %s: %w", dir, err)
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+// key returns the key of entry.
+func (entry *DiskCacheEntry) key() string {
+	if entry.Key == "" {
+		return entry.Url
+	}
+
+	return entry.Key
+}
+
+// paths returns the metadata and body file paths for key.
+func (cache *DiskCache) paths(key string) (string, string) {
+	hash := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(hash[:])
+
+	return filepath.Join(cache.dir, name+".json"), filepath.Join(cache.dir, name+".body")
+}
+
+// Load returns the cached response stored under key, see DiskCacheEntry.Key, or nil if there is none.
+func (cache *DiskCache) Load(key string) (*DiskCacheEntry, error) {
+	metaPath, bodyPath := cache.paths(key)
+
+	meta, err := os.ReadFile(metaPath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading cache entry for %s: %w", key, err)
+	}
+
+	entry := &DiskCacheEntry{}
+
+	err = json.Unmarshal(meta, entry)
+
+	if err != nil || entry.key() != key {
+		// Corrupt or colliding entry, treat as a miss
+		return nil, nil
+	}
+
+	entry.Body, err = os.ReadFile(bodyPath)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading cache entry for %s: %w", key, err)
+	}
+
+	if hash := sha256.Sum256(entry.Body); hex.EncodeToString(hash[:]) != entry.BodySha256 {
+		// The body was replaced by a concurrent Store, or a Store was interrupted, treat as a miss
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+// Store saves entry, replacing any previous entry with the same key, and sets entry.BodySha256.
+// Files are written to a temporary name and renamed, so concurrent readers never see a partial entry, and the metadata
+// records the hash of the body, so Load ignores an entry whose body and metadata come from different Stores.
+func (cache *DiskCache) Store(entry *DiskCacheEntry) error {
+	metaPath, bodyPath := cache.paths(entry.key())
+	hash := sha256.Sum256(entry.Body)
+	entry.BodySha256 = hex.EncodeToString(hash[:])
+
+	meta, err := json.Marshal(entry)
+
+	if err != nil {
+		return fmt.Errorf("Error stringifying cache entry for %s: %w", entry.Url, err)
+	}
+
+	err = writeFileAtomic(bodyPath, entry.Body)
+
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(metaPath, meta)
+}
+
+// writeFileAtomic writes contents to a temporary file next to path, then renames it to path.
+func writeFileAtomic(path string, contents []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %w", path, err)
+	}
+
+	_, err = file.Write(contents)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("Error writing %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestMapCacheEviction(t *testing.T) {
+	cache := NewMapCache(2)
+	first := &spec.DecodedSourceMapRecord{File: "first"}
+	second := &spec.DecodedSourceMapRecord{File: "second"}
+	third := &spec.DecodedSourceMapRecord{File: "third"}
+
+	cache.Add("first", first)
+	cache.Add("second", second)
+
+	if _, ok := cache.Get("first"); !ok {
+		t.Fatalf("Expected first to be cached")
+	}
+
+	cache.Add("third", third)
+
+	if _, ok := cache.Get("second"); ok {
+		t.Errorf("Expected second, the least recently used, to be evicted")
+	}
+
+	if mapRecord, ok := cache.Get("first"); !ok || mapRecord != first {
+		t.Errorf("Expected first to still be cached")
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached maps, got %d", cache.Len())
+	}
+}
+
+func TestMapCacheParseSourceMapFromFile(t *testing.T) {
+	cache := NewMapCache(4)
+
+	first, err := cache.ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	second, err := cache.ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil || first != second {
+		t.Errorf("Expected the cached source map to be returned, got %p and %p (%v)", first, second, err)
+	}
+}
+
+func TestFetcherDiskCacheRevalidation(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	downloads := &atomic.Int32{}
+	revalidations := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(contents)
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache, err := NewDiskCache(dir)
+
+	if err != nil {
+		t.Fatalf("Error creating cache: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		// A new Fetcher each time, so only the disk cache is shared
+		mapRecord, err := (&Fetcher{Cache: cache}).ParseSourceMap(context.Background(), server.URL)
+
+		if err != nil || len(mapRecord.Sources) != 1 {
+			t.Fatalf("Error parsing source map: %v", err)
+		}
+	}
+
+	if downloads.Load() != 1 || revalidations.Load() != 2 {
+		t.Errorf("Expected 1 download and 2 revalidations, got %d and %d", downloads.Load(), revalidations.Load())
+	}
+
+	fetcher := &Fetcher{Cache: cache, Maps: NewMapCache(4)}
+
+	for i := 0; i < 2; i++ {
+		_, err := fetcher.ParseSourceMap(context.Background(), server.URL)
+
+		if err != nil {
+			t.Fatalf("Error parsing source map: %v", err)
+		}
+	}
+
+	if revalidations.Load() != 3 {
+		t.Errorf("Expected the parsed map cache to skip the second revalidation, got %d revalidations", revalidations.Load())
+	}
+}
+
+func TestFetcherDiskCacheCredentials(t *testing.T) {
+	downloads := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.URL.Path == "/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "max-age=60, Private")
+		}
+
+		w.Write([]byte("body for " + r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(t.TempDir())
+
+	if err != nil {
+		t.Fatalf("Error creating cache: %v", err)
+	}
+
+	hosts := []string{"127.0.0.1"}
+	fetchers := []*Fetcher{
+		{Cache: cache},
+		{Cache: cache, BearerToken: "alice", CredentialHosts: hosts},
+		{Cache: cache, BearerToken: "bob", CredentialHosts: hosts},
+		{Cache: cache, Header: http.Header{"Authorization": {"Bearer bob"}}, CredentialHosts: hosts},
+	}
+	expected := []string{"body for ", "body for Bearer alice", "body for Bearer bob", "body for Bearer bob"}
+
+	for i, fetcher := range fetchers {
+		contents, err := fetcher.Fetch(context.Background(), server.URL+"/app.js.map")
+
+		if err != nil || string(contents) != expected[i] {
+			t.Errorf("Fetcher %d: expected %q, got %q (%v)", i, expected[i], contents, err)
+		}
+	}
+
+	if downloads.Load() != 4 {
+		t.Errorf("Expected every set of credentials to download once, got %d downloads", downloads.Load())
+	}
+
+	for _, path := range []string{"/no-store", "/private"} {
+		if _, err := fetchers[0].Fetch(context.Background(), server.URL+path); err != nil {
+			t.Fatalf("Error fetching %s: %v", path, err)
+		}
+
+		if entry, err := cache.Load(server.URL + path); entry != nil || err != nil {
+			t.Errorf("Expected %s not to be cached, got %+v (%v)", path, entry, err)
+		}
+	}
+}
+
+func TestDiskCacheMismatchedBody(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+
+	if err != nil {
+		t.Fatalf("Error creating cache: %v", err)
+	}
+
+	metaPath, _ := cache.paths("https://example.com/app.js.map")
+
+	if err := cache.Store(&DiskCacheEntry{Url: "https://example.com/app.js.map", ETag: `"v1"`, Body: []byte("first")}); err != nil {
+		t.Fatalf("Error storing entry: %v", err)
+	}
+
+	firstMeta, err := os.ReadFile(metaPath)
+
+	if err != nil {
+		t.Fatalf("Error reading metadata: %v", err)
+	}
+
+	if err := cache.Store(&DiskCacheEntry{Url: "https://example.com/app.js.map", ETag: `"v2"`, Body: []byte("second")}); err != nil {
+		t.Fatalf("Error storing entry: %v", err)
+	}
+
+	// As if the first Store renamed its metadata last, after the second Store renamed its body
+	if err := os.WriteFile(metaPath, firstMeta, 0600); err != nil {
+		t.Fatalf("Error writing metadata: %v", err)
+	}
+
+	if entry, err := cache.Load("https://example.com/app.js.map"); entry != nil || err != nil {
+		t.Errorf("Expected the mismatched entry to be a miss, got %+v (%v)", entry, err)
+	}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// ConcatInput is one file of a concatenated bundle.
+type ConcatInput struct {
+	// Generated is the contents of the file
+	Generated string
+	// Map is the source map of the file, or nil if it has none
+	Map *spec.DecodedSourceMapRecord
+	// Url, if set, is used as the original source of a file without a Map, so its lines map to themselves
+	Url string
+}
+
+// concatOffset is where an input starts in the concatenated output, in 0-based lines and UTF-16 columns.
+type concatOffset struct {
+	line   int
+	column int
+}
+
+// concatenate joins the generated contents of inputs with separator, and returns the output and where each input starts.
+func concatenate(inputs []ConcatInput, separator string) (string, []concatOffset) {
+	var output strings.Builder
+
+	offsets := make([]concatOffset, len(inputs))
+	offset := concatOffset{}
+
+	for i, input := range inputs {
+		if i > 0 {
+			output.WriteString(separator)
+			offset = advance(offset, separator)
+		}
+
+		offsets[i] = offset
+		output.WriteString(input.Generated)
+		offset = advance(offset, input.Generated)
+	}
+
+	return output.String(), offsets
+}
+
+// advance returns where text ends, when it starts at offset.
+func advance(offset concatOffset, text string) concatOffset {
+	lines := SplitLines(text)
+	last := UTF16Length(lines[len(lines)-1])
+
+	if len(lines) == 1 {
+		return concatOffset{line: offset.line, column: offset.column + last}
+	}
+
+	return concatOffset{line: offset.line + len(lines) - 1, column: last}
+}
+
+// inputMap returns the source map of input, which maps every line to itself for a file without a Map but with a Url,
+// or nil if it has neither.
+func inputMap(input ConcatInput) *spec.DecodedSourceMapRecord {
+	if input.Map != nil {
+		return input.Map
+	}
+
+	if input.Url == "" {
+		return nil
+	}
+
+	source := &spec.DecodedSourceRecord{Url: input.Url, Content: input.Generated}
+	mapRecord := &spec.DecodedSourceMapRecord{Sources: []*spec.DecodedSourceRecord{source}}
+
+	for line := range SplitLines(input.Generated) {
+		mapRecord.Mappings = append(mapRecord.Mappings, &spec.DecodedMappingRecord{
+			GeneratedLine:  line,
+			OriginalSource: source,
+			OriginalLine:   line,
+		})
+	}
+
+	return mapRecord
+}
+
+// sourceMerger merges sources with the same url and content from several source maps into one source, which is ignored
+// if any of them is.
+type sourceMerger struct {
+	sources []*spec.DecodedSourceRecord
+	keys    map[[2]string]*spec.DecodedSourceRecord
+	merged  map[*spec.DecodedSourceRecord]*spec.DecodedSourceRecord
+}
+
+func newSourceMerger() *sourceMerger {
+	return &sourceMerger{
+		sources: make([]*spec.DecodedSourceRecord, 0),
+		keys:    make(map[[2]string]*spec.DecodedSourceRecord),
+		merged:  make(map[*spec.DecodedSourceRecord]*spec.DecodedSourceRecord),
+	}
+}
+
+// source returns the merged source of original, which is nil if original is nil.
+func (merger *sourceMerger) source(original *spec.DecodedSourceRecord) *spec.DecodedSourceRecord {
+	if original == nil {
+		return nil
+	}
+
+	if mergedSource, ok := merger.merged[original]; ok {
+		return mergedSource
+	}
+
+	key := [2]string{original.Url, original.Content}
+	mergedSource, ok := merger.keys[key]
+
+	if !ok {
+		mergedSource = &spec.DecodedSourceRecord{Url: original.Url, Content: original.Content}
+		merger.keys[key] = mergedSource
+		merger.sources = append(merger.sources, mergedSource)
+	}
+
+	mergedSource.Ignored = mergedSource.Ignored || original.Ignored
+	merger.merged[original] = mergedSource
+
+	return mergedSource
+}
+
+// add adds the sources and mappings of mapRecord to merged, with mappings shifted to offset.
+func (merger *sourceMerger) add(merged *spec.DecodedSourceMapRecord, mapRecord *spec.DecodedSourceMapRecord, offset concatOffset) {
+	for _, original := range mapRecord.Sources {
+		merger.source(original)
+	}
+
+	for _, mapping := range mapRecord.Mappings {
+		shifted := shiftMapping(mapping, offset.line, offset.column)
+		shifted.OriginalSource = merger.source(mapping.OriginalSource)
+		merged.Mappings = append(merged.Mappings, shifted)
+	}
+
+	merged.Sources = merger.sources
+}
+
+// Concat joins the generated contents of inputs with separator, such as "\n", and returns the output with a flat source
+// map of it. The mappings of each input are shifted to where it starts in the output. Sources with the same url and
+// content are merged into one, which is ignored if any of them is. Names are merged when the map is encoded.
+func Concat(inputs []ConcatInput, separator string) (string, *spec.DecodedSourceMapRecord) {
+	output, offsets := concatenate(inputs, separator)
+	merged := &spec.DecodedSourceMapRecord{
+		Sources:  make([]*spec.DecodedSourceRecord, 0),
+		Mappings: make([]*spec.DecodedMappingRecord, 0),
+	}
+	merger := newSourceMerger()
+
+	for i, input := range inputs {
+		if mapRecord := inputMap(input); mapRecord != nil {
+			merger.add(merged, mapRecord, offsets[i])
+		}
+	}
+
+	return output, merged
+}
+
+// ConcatIndexMap joins the generated contents of inputs with separator like Concat, but returns an index map of the
+// output, with one section per input that has a source map, starting where the input starts.
+func ConcatIndexMap(inputs []ConcatInput, separator string) (string, *spec.IndexSourceMap) {
+	output, offsets := concatenate(inputs, separator)
+	indexMap := &spec.IndexSourceMap{Version: 3, Sections: make([]spec.Section, 0)}
+
+	for i, input := range inputs {
+		mapRecord := inputMap(input)
+
+		if mapRecord == nil {
+			continue
+		}
+
+		indexMap.Sections = append(indexMap.Sections, spec.Section{
+			Offset: spec.SectionOffset{Line: offsets[i].line, Column: offsets[i].column},
+			Map:    spec.EncodeSourceMap(mapRecord),
+		})
+	}
+
+	return output, indexMap
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func concatTestInputs(t *testing.T) []ConcatInput {
+	t.Helper()
+
+	first, err := spec.ParseSourceMap(`{"version":3,"sources":["a.js"],"names":["foo"],"mappings":"AAAA,IAAIA;AACJ"}`, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing first map: %v", err)
+	}
+
+	second, err := spec.ParseSourceMap(`{"version":3,"sources":["a.js","b.js"],"names":["foo"],"mappings":"ACAA,EDAAA","ignoreList":[1]}`, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing second map: %v", err)
+	}
+
+	return []ConcatInput{
+		{Generated: "var foo\nfoo()", Map: first},
+		{Generated: "/* no map */"},
+		{Generated: "x;y", Map: second},
+		{Generated: "plain\ncode", Url: "plain.js"},
+	}
+}
+
+func TestConcat(t *testing.T) {
+	output, mapRecord := Concat(concatTestInputs(t), ";")
+
+	if output != "var foo\nfoo();/* no map */;x;y;plain\ncode" {
+		t.Errorf("Unexpected output %q", output)
+	}
+
+	if len(mapRecord.Sources) != 3 {
+		t.Fatalf("Expected sources a.js, b.js and plain.js, got %d sources", len(mapRecord.Sources))
+	}
+
+	if !mapRecord.Sources[1].Ignored || mapRecord.Sources[0].Ignored {
+		t.Errorf("Expected only b.js to be ignored")
+	}
+
+	tests := []struct {
+		line, column int
+		source       string
+		originalLine int
+		name         string
+	}{
+		{0, 0, "a.js", 0, ""},
+		{0, 4, "a.js", 0, "foo"},
+		{1, 0, "a.js", 1, ""},
+		// the third input starts after "foo();/* no map */;"
+		{1, 19, "b.js", 0, ""},
+		{1, 21, "a.js", 0, "foo"},
+		{1, 23, "plain.js", 0, ""},
+		{2, 0, "plain.js", 1, ""},
+	}
+
+	for _, test := range tests {
+		mapping := OriginalPositionFor(mapRecord, test.line, test.column)
+
+		if mapping == nil || mapping.GeneratedColumn != test.column || mapping.OriginalSource == nil {
+			t.Errorf("%d:%d: expected a mapping, got %v", test.line, test.column, mapping)
+			continue
+		}
+
+		if mapping.OriginalSource.Url != test.source || mapping.OriginalLine != test.originalLine || mapping.Name != test.name {
+			t.Errorf("%d:%d: expected %s:%d %q, got %s:%d %q", test.line, test.column, test.source, test.originalLine, test.name,
+				mapping.OriginalSource.Url, mapping.OriginalLine, mapping.Name)
+		}
+	}
+
+	encoded := spec.EncodeSourceMap(mapRecord)
+
+	if len(encoded.Names) != 1 {
+		t.Errorf("Expected names to be merged into [foo], got %v", encoded.Names)
+	}
+
+	if len(encoded.IgnoreList) != 1 || encoded.IgnoreList[0] != 1 {
+		t.Errorf("Expected ignoreList [1], got %v", encoded.IgnoreList)
+	}
+}
+
+func TestConcatIndexMap(t *testing.T) {
+	_, indexMap := ConcatIndexMap(concatTestInputs(t), ";")
+
+	expected := []spec.SectionOffset{{Line: 0, Column: 0}, {Line: 1, Column: 19}, {Line: 1, Column: 23}}
+
+	if len(indexMap.Sections) != len(expected) {
+		t.Fatalf("Expected %d sections, got %d", len(expected), len(indexMap.Sections))
+	}
+
+	for i, section := range indexMap.Sections {
+		if section.Offset != expected[i] {
+			t.Errorf("Section %d: expected offset %v, got %v", i, expected[i], section.Offset)
+		}
+	}
+
+	if indexMap.Sections[0].Map.Mappings != "AAAA,IAAIA;AACJ" {
+		t.Errorf("Expected the first section to keep its mappings, got %s", indexMap.Sections[0].Map.Mappings)
+	}
+
+	if indexMap.Sections[2].Map.Mappings != "AAAA;AACA" {
+		t.Errorf("Expected the unmapped input to map to itself, got %s", indexMap.Sections[2].Map.Mappings)
+	}
+}
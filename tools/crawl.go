@@ -0,0 +1,496 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// Crawler recovers the source maps of every script and stylesheet used by a web page.
+type Crawler struct {
+	// Fetcher downloads the page, its assets and their source maps, DefaultFetcher if nil.
+	// Its credentials are only sent to the page's host, whatever its CredentialHosts
+	Fetcher *Fetcher
+	// Concurrency is the number of assets downloaded at once, 4 if 0
+	Concurrency int
+	// SameOrigin restricts assets, source maps and the redirects they follow to the origin of the page, and AllowedHosts
+	SameOrigin bool
+	// AllowedHosts are additional hosts, e.g. a CDN, allowed when SameOrigin is set
+	AllowedHosts []string
+	// Extract controls how sources are written by Crawl
+	Extract ExtractOptions
+}
+
+// CrawledMap is the outcome of recovering the source map of one asset.
+type CrawledMap struct {
+	// AssetUrl is the script or stylesheet the source map belongs to
+	AssetUrl string `json:"assetUrl"`
+	// MapUrl is the url of the source map, or "" if it was inline or none was found
+	MapUrl string `json:"mapUrl,omitempty"`
+	// Report describes the sources written, if the source map was found
+	Report *ExtractReport `json:"report,omitempty"`
+	// Error is why the source map could not be recovered, if it wasn't
+	Error string `json:"error,omitempty"`
+}
+
+// CrawlReport describes everything found while crawling a page.
+type CrawlReport struct {
+	// PageUrl is the crawled page
+	PageUrl string `json:"pageUrl"`
+	// Maps has an entry for every asset found, in the order they were processed
+	Maps []CrawledMap `json:"maps"`
+	// Skipped is every asset or source map url not fetched because of SameOrigin
+	Skipped []string `json:"skipped"`
+}
+
+// chunkManifests are build manifests listing an app's chunks, relative to the page and to the origin root.
+var chunkManifests = []string{
+	".vite/manifest.json",
+	"manifest.json",
+	"asset-manifest.json",
+}
+
+var (
+	htmlTag          = regexp.MustCompile(`(?is)<(script|link|base)\b[^>]*>`)
+	htmlAttribute    = regexp.MustCompile(`(?is)\b([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	sourceMappingUrl = regexp.MustCompile(`[#@]\s*sourceMappingURL\s*=\s*([^\s'"*]+)`)
+	relativeJsImport = regexp.MustCompile(`(?:\bimport\s*\(\s*|\bfrom\s*|\bimport\s*)["'](\.{0,2}/[^"'\s]+\.m?js)["']`)
+	assetExtension   = regexp.MustCompile(`(?i)\.(m?js|css)(\?.*)?$`)
+)
+
+// isAssetLink reports whether a <link> tag with the given attributes loads a script or stylesheet.
+func isAssetLink(attributes map[string]string) bool {
+	for _, rel := range strings.Fields(strings.ToLower(attributes["rel"])) {
+		switch rel {
+		case "stylesheet", "modulepreload":
+			return true
+		case "preload":
+			as := strings.ToLower(attributes["as"])
+
+			return as == "script" || as == "style"
+		}
+	}
+
+	return false
+}
+
+// htmlAttributes returns the lower cased attributes of an html tag.
+func htmlAttributes(tag string) map[string]string {
+	attributes := make(map[string]string)
+
+	for _, match := range htmlAttribute.FindAllStringSubmatch(tag, -1) {
+		attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+
+	return attributes
+}
+
+// FindPageAssets returns the absolute urls of the scripts, stylesheets and preloaded modules referenced by html,
+// resolved against pageUrl or the page's <base href>.
+func FindPageAssets(html string, pageUrl *url.URL) []*url.URL {
+	base := pageUrl
+	assets := make([]*url.URL, 0)
+
+	for _, tag := range htmlTag.FindAllStringSubmatch(html, -1) {
+		attributes := htmlAttributes(tag[0])
+		var reference string
+
+		switch strings.ToLower(tag[1]) {
+		case "base":
+			if href, err := pageUrl.Parse(attributes["href"]); err == nil && attributes["href"] != "" {
+				base = href
+			}
+		case "script":
+			reference = attributes["src"]
+		case "link":
+			if isAssetLink(attributes) {
+				reference = attributes["href"]
+			}
+		}
+
+		if reference == "" {
+			continue
+		}
+
+		if asset, err := base.Parse(reference); err == nil {
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets
+}
+
+// FindSourceMappingUrl returns the last sourceMappingURL comment in the contents of a script or stylesheet, or "".
+func FindSourceMappingUrl(contents string) string {
+	matches := sourceMappingUrl.FindAllStringSubmatch(contents, -1)
+
+	if len(matches) == 0 {
+		return ""
+	}
+
+	return matches[len(matches)-1][1]
+}
+
+// decodeDataUrl returns the contents of an inline data: source map url.
+func decodeDataUrl(dataUrl string) (string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(dataUrl, "data:"), ",")
+
+	if !ok {
+		return "", fmt.Errorf("Error: malformed data url")
+	}
+
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+
+		if err != nil {
+			return "", fmt.Errorf("Error decoding data url: %w", err)
+		}
+
+		return string(decoded), nil
+	}
+
+	decoded, err := url.PathUnescape(data)
+
+	if err != nil {
+		return "", fmt.Errorf("Error decoding data url: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// collectManifestAssets appends every string in the decoded json value that looks like a script or stylesheet.
+func collectManifestAssets(value any, assets []string) []string {
+	switch value := value.(type) {
+	case string:
+		if assetExtension.MatchString(value) {
+			assets = append(assets, value)
+		}
+	case []any:
+		for _, item := range value {
+			assets = collectManifestAssets(item, assets)
+		}
+	case map[string]any:
+		for _, item := range value {
+			assets = collectManifestAssets(item, assets)
+		}
+	}
+
+	return assets
+}
+
+// crawl holds the state of a single call to Crawler.Crawl.
+type crawl struct {
+	crawler *Crawler
+	fetcher *Fetcher
+	page    *url.URL
+	writer  SourceWriter
+
+	mutex   sync.Mutex
+	seen    map[string]bool
+	report  *CrawlReport
+	waiting sync.WaitGroup
+	workers chan struct{}
+}
+
+// Crawl downloads the page at pageUrl, finds its scripts, stylesheets, preloaded modules, relative module imports
+// and chunks listed in vite or webpack build manifests, and writes the sources of every source map found to writer.
+// Source maps are found from sourceMappingURL comments, inline data: urls, or by appending .map to the asset url.
+// Errors for individual assets are recorded in the report, an error is only returned if the page itself cannot be fetched.
+func (crawler *Crawler) Crawl(ctx context.Context, pageUrl string, writer SourceWriter) (*CrawlReport, error) {
+	page, err := url.Parse(pageUrl)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %w", pageUrl, err)
+	}
+
+	fetcher := crawler.Fetcher
+
+	if fetcher == nil {
+		fetcher = DefaultFetcher
+	}
+
+	concurrency := crawler.Concurrency
+
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	html, err := fetcher.Fetch(ctx, pageUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := &crawl{
+		crawler: crawler,
+		fetcher: fetcher,
+		page:    page,
+		writer:  writer,
+		seen:    make(map[string]bool),
+		report:  &CrawlReport{PageUrl: pageUrl, Maps: make([]CrawledMap, 0), Skipped: make([]string, 0)},
+		workers: make(chan struct{}, concurrency),
+	}
+
+	// Assets and source maps on other origins, allowed or not, never get the page's credentials
+	pageOnly := *fetcher
+	pageOnly.CredentialHosts = nil
+
+	if fetcher.sendsCredentials(page) {
+		pageOnly.CredentialHosts = []string{page.Host}
+	}
+
+	state.fetcher = &pageOnly
+
+	if crawler.SameOrigin {
+		state.fetcher = state.restrictRedirects(state.fetcher)
+	}
+
+	for _, asset := range FindPageAssets(string(html), page) {
+		state.queue(ctx, asset)
+	}
+
+	for _, manifest := range chunkManifests {
+		for _, base := range []string{"", "/"} {
+			manifestUrl, err := page.Parse(base + manifest)
+
+			if err == nil && state.allowed(manifestUrl) {
+				state.queueManifest(ctx, manifestUrl)
+			}
+		}
+	}
+
+	state.waiting.Wait()
+
+	return state.report, nil
+}
+
+// allowed reports whether target may be fetched under the crawler's origin policy.
+func (state *crawl) allowed(target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	if !state.crawler.SameOrigin {
+		return true
+	}
+
+	if target.Scheme == state.page.Scheme && target.Host == state.page.Host {
+		return true
+	}
+
+	return matchesHost(target, state.crawler.AllowedHosts)
+}
+
+// restrictRedirects returns a copy of fetcher that refuses redirects to urls not allowed by the origin policy,
+// so a same origin url can't be used to fetch from another host.
+func (state *crawl) restrictRedirects(fetcher *Fetcher) *Fetcher {
+	restricted := *fetcher
+	client := *fetcher.client()
+	checkRedirect := client.CheckRedirect
+
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if !state.allowed(request.URL) {
+			state.mutex.Lock()
+			state.report.Skipped = append(state.report.Skipped, request.URL.String())
+			state.mutex.Unlock()
+
+			return fmt.Errorf("Error: redirect to %s is not allowed by the origin policy", request.URL)
+		}
+
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+
+		// The default policy of http.Client
+		if len(via) >= 10 {
+			return fmt.Errorf("Error: stopped after 10 redirects")
+		}
+
+		return nil
+	}
+
+	restricted.Client = &client
+	// MaxRedirects is already applied by client
+	restricted.MaxRedirects = 0
+
+	return &restricted
+}
+
+// markSeen records key as seen, and reports whether it was seen before.
+func (state *crawl) markSeen(key string) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.seen[key] {
+		return true
+	}
+
+	state.seen[key] = true
+
+	return false
+}
+
+// run calls work in a new goroutine, limited to the crawler's concurrency.
+func (state *crawl) run(ctx context.Context, work func()) {
+	state.waiting.Add(1)
+
+	go func() {
+		defer state.waiting.Done()
+
+		select {
+		case state.workers <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		defer func() { <-state.workers }()
+
+		work()
+	}()
+}
+
+// queueManifest downloads a build manifest, if it exists, and queues every asset listed in it.
+func (state *crawl) queueManifest(ctx context.Context, manifestUrl *url.URL) {
+	if state.markSeen("manifest:" + manifestUrl.String()) {
+		return
+	}
+
+	state.run(ctx, func() {
+		contents, err := state.fetcher.Fetch(ctx, manifestUrl.String())
+
+		if err != nil {
+			// Most sites have no manifest, so failures are expected
+			return
+		}
+
+		var manifest any
+
+		if json.Unmarshal(contents, &manifest) != nil {
+			return
+		}
+
+		for _, reference := range collectManifestAssets(manifest, nil) {
+			// Manifest paths are relative to the directory the manifest is served from, or absolute
+			base := manifestUrl
+
+			if strings.HasSuffix(manifestUrl.Path, "/.vite/manifest.json") {
+				base, _ = manifestUrl.Parse("../")
+			}
+
+			if asset, err := base.Parse(reference); err == nil {
+				state.queue(ctx, asset)
+			}
+		}
+	})
+}
+
+// queue downloads asset, recovers its source map, and queues any modules it imports.
+func (state *crawl) queue(ctx context.Context, asset *url.URL) {
+	asset.Fragment = ""
+
+	if state.markSeen("asset:" + asset.String()) {
+		return
+	}
+
+	if !state.allowed(asset) {
+		state.mutex.Lock()
+		state.report.Skipped = append(state.report.Skipped, asset.String())
+		state.mutex.Unlock()
+
+		return
+	}
+
+	state.run(ctx, func() {
+		crawled := state.recover(ctx, asset)
+
+		state.mutex.Lock()
+		state.report.Maps = append(state.report.Maps, crawled)
+		state.mutex.Unlock()
+	})
+}
+
+// recover downloads asset, parses its source map, and writes the map's sources.
+func (state *crawl) recover(ctx context.Context, asset *url.URL) CrawledMap {
+	crawled := CrawledMap{AssetUrl: asset.String()}
+
+	contents, err := state.fetcher.Fetch(ctx, asset.String())
+
+	if err != nil {
+		crawled.Error = err.Error()
+		return crawled
+	}
+
+	for _, match := range relativeJsImport.FindAllStringSubmatch(string(contents), -1) {
+		if imported, err := asset.Parse(match[1]); err == nil {
+			state.queue(ctx, imported)
+		}
+	}
+
+	mapRecord, mapUrl, err := state.parseSourceMap(ctx, asset, string(contents))
+	crawled.MapUrl = mapUrl
+
+	if err != nil {
+		crawled.Error = err.Error()
+		return crawled
+	}
+
+	// Writers such as ArchiveWriter are not safe for concurrent use
+	state.mutex.Lock()
+	crawled.Report, err = ExtractSources(mapRecord, state.writer, state.crawler.Extract)
+	state.mutex.Unlock()
+
+	if err != nil {
+		crawled.Error = err.Error()
+	}
+
+	return crawled
+}
+
+// parseSourceMap finds and parses the source map of asset, whose contents have already been downloaded.
+// Returns the url of the source map, which is "" for inline source maps.
+func (state *crawl) parseSourceMap(ctx context.Context, asset *url.URL, contents string) (*spec.DecodedSourceMapRecord, string, error) {
+	reference := FindSourceMappingUrl(contents)
+
+	if strings.HasPrefix(reference, "data:") {
+		decoded, err := decodeDataUrl(reference)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		mapRecord, err := spec.ParseSourceMap(decoded, "")
+
+		return mapRecord, "", err
+	}
+
+	if reference == "" {
+		// No comment, but many servers still serve the map next to the asset
+		reference = asset.Path[strings.LastIndexByte(asset.Path, '/')+1:] + ".map"
+	}
+
+	mapUrl, err := asset.Parse(reference)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Error parsing sourceMappingURL %s: %w", reference, err)
+	}
+
+	if !state.allowed(mapUrl) {
+		state.mutex.Lock()
+		state.report.Skipped = append(state.report.Skipped, mapUrl.String())
+		state.mutex.Unlock()
+
+		return nil, mapUrl.String(), fmt.Errorf("Error: %s is not allowed by the origin policy", mapUrl)
+	}
+
+	mapRecord, err := state.fetcher.ParseSourceMap(ctx, mapUrl.String())
+
+	return mapRecord, mapUrl.String(), err
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFindPageAssets(t *testing.T) {
+	page, _ := url.Parse("https://example.com/app/index.html")
+	html := `<html><head>
+		<link rel="stylesheet" href="style.css">
+		<link rel="modulepreload" href="/assets/vendor.js">
+		<link rel="preload" as="font" href="font.woff2">
+		<link rel=icon href=favicon.ico>
+		<script type="module" src='main.js'></script>
+		<script>inline()</script>
+	</head></html>`
+
+	assets := FindPageAssets(html, page)
+	actual := make([]string, len(assets))
+
+	for i, asset := range assets {
+		actual[i] = asset.String()
+	}
+
+	expected := []string{
+		"https://example.com/app/style.css",
+		"https://example.com/assets/vendor.js",
+		"https://example.com/app/main.js",
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	test1, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	test2, err := os.ReadFile("../testdata/test2.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test2.js.map: %v", err)
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cdn()"))
+	}))
+	defer other.Close()
+
+	files := map[string]string{
+		"/index.html":          `<script src="/main.js"></script><script src="` + other.URL + `/cdn.js"></script><link rel="stylesheet" href="style.css">`,
+		"/main.js":             "import('./chunk.js')\n//# sourceMappingURL=maps/main.js.map",
+		"/maps/main.js.map":    string(test1),
+		"/chunk.js":            "chunk()\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(test2),
+		"/style.css":           "body{}",
+		"/.vite/manifest.json": `{"lazy.js": {"file": "assets/lazy.js"}}`,
+		"/assets/lazy.js":      "lazy()",
+		"/assets/lazy.js.map":  string(test1),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contents, ok := files[r.URL.Path]
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(contents))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writer, err := NewDirectoryWriter(dir)
+
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+
+	crawler := &Crawler{Fetcher: &Fetcher{}, SameOrigin: true}
+
+	report, err := crawler.Crawl(context.Background(), server.URL+"/index.html", writer)
+
+	if err != nil {
+		t.Fatalf("Error crawling: %v", err)
+	}
+
+	found := make(map[string]CrawledMap)
+
+	for _, crawled := range report.Maps {
+		found[crawled.AssetUrl] = crawled
+	}
+
+	for _, asset := range []string{"/main.js", "/chunk.js", "/assets/lazy.js"} {
+		crawled, ok := found[server.URL+asset]
+
+		// main.js and lazy.js share a source map, so whichever is crawled last finds its source already written
+		if !ok || crawled.Error != "" || crawled.Report == nil || len(crawled.Report.Written)+len(crawled.Report.Duplicates) != 1 {
+			t.Errorf("Expected the source map of %s to be recovered, got %+v", asset, crawled)
+		}
+	}
+
+	if crawled := found[server.URL+"/style.css"]; crawled.Error == "" {
+		t.Errorf("Expected style.css to have no source map")
+	}
+
+	if len(report.Skipped) != 1 || report.Skipped[0] != other.URL+"/cdn.js" {
+		t.Errorf("Expected the cross origin script to be skipped, got %v", report.Skipped)
+	}
+
+	if found[server.URL+"/main.js"].MapUrl != server.URL+"/maps/main.js.map" {
+		t.Errorf("Unexpected map url %s", found[server.URL+"/main.js"].MapUrl)
+	}
+
+	written := make([]string, 0)
+
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			written = append(written, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(written)
+
+	if len(written) != 2 {
+		t.Errorf("Expected the sources of test1.js.map and test2.js.map to be written, got %v", written)
+	}
+}
+
+func TestCrawlSameOriginRedirect(t *testing.T) {
+	test1, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/main.js.map" {
+			w.Write(test1)
+			return
+		}
+
+		w.Write([]byte("main()"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.html" {
+			w.Write([]byte(`<script src="/main.js"></script>`))
+			return
+		}
+
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	for _, sameOrigin := range []bool{true, false} {
+		writer, err := NewDirectoryWriter(t.TempDir())
+
+		if err != nil {
+			t.Fatalf("Error creating writer: %v", err)
+		}
+
+		crawler := &Crawler{Fetcher: &Fetcher{}, SameOrigin: sameOrigin}
+		report, err := crawler.Crawl(context.Background(), server.URL+"/index.html", writer)
+
+		if err != nil {
+			t.Fatalf("Error crawling: %v", err)
+		}
+
+		if len(report.Maps) != 1 {
+			t.Fatalf("Expected one asset, got %+v", report.Maps)
+		}
+
+		recovered := report.Maps[0].Report != nil
+
+		if recovered == sameOrigin {
+			t.Errorf("SameOrigin %v: expected recovered to be %v, got %+v", sameOrigin, !sameOrigin, report.Maps[0])
+		}
+
+		if sameOrigin && !slices.Contains(report.Skipped, other.URL+"/main.js") {
+			t.Errorf("Expected the redirect to be skipped, got %v", report.Skipped)
+		}
+	}
+}
+
+func TestCrawlCredentials(t *testing.T) {
+	test1, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	credentials := make(chan string, 10)
+	record := func(name string, files map[string]string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" || r.Header.Get("X-Test") != "" {
+				credentials <- name + " " + r.URL.Path
+			}
+
+			w.Write([]byte(files[r.URL.Path]))
+		}
+	}
+
+	cdn := httptest.NewServer(record("cdn", map[string]string{"/cdn.js": "cdn()", "/cdn.js.map": string(test1)}))
+	defer cdn.Close()
+
+	server := httptest.NewServer(record("page", map[string]string{
+		"/index.html": `<script src="/main.js"></script><script src="` + cdn.URL + `/cdn.js"></script>`,
+		"/main.js":    "main()",
+	}))
+	defer server.Close()
+
+	writer, err := NewDirectoryWriter(t.TempDir())
+
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+
+	fetcher := &Fetcher{
+		Header:          http.Header{"X-Test": {"yes"}},
+		BearerToken:     "secret",
+		CredentialHosts: []string{"127.0.0.1"},
+	}
+	crawler := &Crawler{Fetcher: fetcher, SameOrigin: false}
+
+	if _, err := crawler.Crawl(context.Background(), server.URL+"/index.html", writer); err != nil {
+		t.Fatalf("Error crawling: %v", err)
+	}
+
+	close(credentials)
+
+	for request := range credentials {
+		if !strings.HasPrefix(request, "page ") {
+			t.Errorf("Expected credentials to only be sent to the page's origin, got %s", request)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugIdComment matches the //# debugId= comment that ties a generated file to its source map.
+var debugIdComment = regexp.MustCompile(`(?m)^[ \t]*//[#@] debugId=([0-9a-fA-F-]+)[ \t]*$`)
+
+// FindDebugId returns the debug id of the generated file contents, from its last //# debugId= comment, or "".
+func FindDebugId(contents string) string {
+	matches := debugIdComment.FindAllStringSubmatch(contents, -1)
+
+	if len(matches) == 0 {
+		return ""
+	}
+
+	return NormalizeDebugId(matches[len(matches)-1][1])
+}
+
+// NormalizeDebugId lower cases id, so ids can be compared regardless of how they were written.
+func NormalizeDebugId(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// DebugIdStore indexes the source maps in a directory by their debugId.
+// It is safe for concurrent use.
+type DebugIdStore struct {
+	dir   string
+	mutex sync.RWMutex
+	paths map[string]string
+
+	// reloadMutex serializes reloads, and guards reloaded, the time of the last one
+	reloadMutex sync.Mutex
+	reloaded    time.Time
+}
+
+// NewDebugIdStore returns a DebugIdStore of the .map files in dir and its subdirectories.
+func NewDebugIdStore(dir string) (*DebugIdStore, error) {
+	store := &DebugIdStore{dir: dir}
+
+	err := store.Reload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Reload scans the directory of store again, picking up added, changed and removed source maps.
+// Source maps that can't be read are skipped.
+func (store *DebugIdStore) Reload() error {
+	store.reloadMutex.Lock()
+	defer store.reloadMutex.Unlock()
+
+	return store.reload()
+}
+
+// ReloadAfter reloads store like Reload, unless it was reloaded less than interval ago.
+// Callers waiting for a reload in progress don't start another one. Reports whether store was reloaded.
+func (store *DebugIdStore) ReloadAfter(interval time.Duration) (bool, error) {
+	store.reloadMutex.Lock()
+	defer store.reloadMutex.Unlock()
+
+	if time.Since(store.reloaded) < interval {
+		return false, nil
+	}
+
+	return true, store.reload()
+}
+
+// reload scans the directory of store, the caller must hold reloadMutex.
+func (store *DebugIdStore) reload() error {
+	store.reloaded = time.Now()
+	paths := make(map[string]string)
+
+	err := filepath.WalkDir(store.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".map") {
+			return nil
+		}
+
+		if id := readDebugId(path); id != "" {
+			paths[id] = path
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error scanning %s for source maps: %w", store.dir, err)
+	}
+
+	store.mutex.Lock()
+	store.paths = paths
+	store.mutex.Unlock()
+
+	return nil
+}
+
+// readDebugId returns the debugId of the source map at path, or "" if it has none or can't be read.
+func readDebugId(path string) string {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return ""
+	}
+
+	defer file.Close()
+
+	sourceMap := struct {
+		DebugId string `json:"debugId"`
+	}{}
+
+	if json.NewDecoder(file).Decode(&sourceMap) != nil {
+		return ""
+	}
+
+	return NormalizeDebugId(sourceMap.DebugId)
+}
+
+// Path returns the path of the source map with the debug id id.
+func (store *DebugIdStore) Path(id string) (string, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	path, ok := store.paths[NormalizeDebugId(id)]
+
+	return path, ok
+}
+
+// Len returns the number of source maps with a debug id in the store.
+func (store *DebugIdStore) Len() int {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return len(store.paths)
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindDebugId(t *testing.T) {
+	contents := "console.log(1);\n//# debugId=85314830-023F-4CF1-A267-535F4E37BB17\n//# sourceMappingURL=app.js.map\n"
+
+	if id := FindDebugId(contents); id != "85314830-023f-4cf1-a267-535f4e37bb17" {
+		t.Errorf("Unexpected debug id %q", id)
+	}
+
+	if id := FindDebugId("console.log(1);"); id != "" {
+		t.Errorf("Expected no debug id, got %q", id)
+	}
+}
+
+func TestDebugIdStore(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "nested"), 0700)
+	os.WriteFile(filepath.Join(dir, "nested", "app.js.map"), []byte(`{"version": 3, "debugId": "ABC-1", "sources": [], "mappings": ""}`), 0600)
+	os.WriteFile(filepath.Join(dir, "other.js.map"), []byte(`{"version": 3, "sources": [], "mappings": ""}`), 0600)
+
+	store, err := NewDebugIdStore(dir)
+
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+
+	if path, ok := store.Path("abc-1"); !ok || path != filepath.Join(dir, "nested", "app.js.map") {
+		t.Errorf("Unexpected path %q, %v", path, ok)
+	}
+
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 debug id, got %d", store.Len())
+	}
+
+	os.WriteFile(filepath.Join(dir, "late.js.map"), []byte(`{"version": 3, "debugId": "def-2", "sources": [], "mappings": ""}`), 0600)
+
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Error reloading store: %v", err)
+	}
+
+	if _, ok := store.Path("DEF-2"); !ok {
+		t.Errorf("Expected the reloaded store to find def-2")
+	}
+
+	os.WriteFile(filepath.Join(dir, "later.js.map"), []byte(`{"version": 3, "debugId": "ghi-3", "sources": [], "mappings": ""}`), 0600)
+
+	if reloaded, err := store.ReloadAfter(time.Hour); reloaded || err != nil {
+		t.Errorf("Expected no reload within the interval, got %v (%v)", reloaded, err)
+	}
+
+	if _, ok := store.Path("ghi-3"); ok {
+		t.Errorf("Expected ghi-3 not to be found before reloading")
+	}
+
+	if reloaded, err := store.ReloadAfter(0); !reloaded || err != nil {
+		t.Errorf("Expected a reload after the interval, got %v (%v)", reloaded, err)
+	}
+
+	if _, ok := store.Path("ghi-3"); !ok {
+		t.Errorf("Expected the reloaded store to find ghi-3")
+	}
+}
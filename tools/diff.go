@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// GeneratedPosition is a 0-based line and column of a generated file.
+type GeneratedPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// OriginalPosition is a 0-based line and column of an original source.
+type OriginalPosition struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// MappingChange is an original position whose generated positions differ between two source maps.
+type MappingChange struct {
+	Original OriginalPosition `json:"original"`
+	// Before are the generated positions mapped to Original by the old source map, empty if it is new
+	Before []GeneratedPosition `json:"before"`
+	// After are the generated positions mapped to Original by the new source map, empty if it was removed
+	After []GeneratedPosition `json:"after"`
+}
+
+// SourceMapDiff is the difference between two source maps, as returned by DiffSourceMaps.
+// Sources are compared by url, and mappings by original position.
+type SourceMapDiff struct {
+	// AddedSources are the sources of the new map missing from the old one
+	AddedSources []string `json:"addedSources"`
+	// RemovedSources are the sources of the old map missing from the new one
+	RemovedSources []string `json:"removedSources"`
+	// ChangedSources are the sources in both maps whose content differs
+	ChangedSources []string `json:"changedSources"`
+	// AddedNames are the names of the new map missing from the old one
+	AddedNames []string `json:"addedNames"`
+	// RemovedNames are the names of the old map missing from the new one
+	RemovedNames []string `json:"removedNames"`
+	// AddedMappings are original positions only mapped by the new map
+	AddedMappings []MappingChange `json:"addedMappings"`
+	// RemovedMappings are original positions only mapped by the old map
+	RemovedMappings []MappingChange `json:"removedMappings"`
+	// MovedMappings are original positions mapped by both maps, but to different generated positions
+	MovedMappings []MappingChange `json:"movedMappings"`
+	// UnchangedMappings is the number of original positions mapped to the same generated positions by both maps
+	UnchangedMappings int `json:"unchangedMappings"`
+}
+
+// Equal reports whether diff found no differences.
+func (diff *SourceMapDiff) Equal() bool {
+	return len(diff.AddedSources)+len(diff.RemovedSources)+len(diff.ChangedSources)+len(diff.AddedNames)+len(diff.RemovedNames)+
+		len(diff.AddedMappings)+len(diff.RemovedMappings)+len(diff.MovedMappings) == 0
+}
+
+// sourceContents returns the content of every source of mapRecord by url, the first one for duplicate urls.
+func sourceContents(mapRecord *spec.DecodedSourceMapRecord) map[string]string {
+	contents := make(map[string]string)
+
+	for _, source := range mapRecord.Sources {
+		if _, ok := contents[source.Url]; !ok {
+			contents[source.Url] = source.Content
+		}
+	}
+
+	return contents
+}
+
+// usedNames returns the set of names used by the mappings of mapRecord.
+func usedNames(mapRecord *spec.DecodedSourceMapRecord) map[string]bool {
+	names := make(map[string]bool)
+
+	for _, mapping := range mapRecord.Mappings {
+		if mapping.Name != "" {
+			names[mapping.Name] = true
+		}
+	}
+
+	return names
+}
+
+// generatedPositions returns the generated positions of every original position of mapRecord, sorted.
+// Mappings without an original source are left out.
+func generatedPositions(mapRecord *spec.DecodedSourceMapRecord) map[OriginalPosition][]GeneratedPosition {
+	positions := make(map[OriginalPosition][]GeneratedPosition)
+
+	for _, mapping := range mapRecord.Mappings {
+		if mapping.OriginalSource == nil {
+			continue
+		}
+
+		original := OriginalPosition{Source: mapping.OriginalSource.Url, Line: mapping.OriginalLine, Column: mapping.OriginalColumn}
+		positions[original] = append(positions[original], GeneratedPosition{Line: mapping.GeneratedLine, Column: mapping.GeneratedColumn})
+	}
+
+	for _, generated := range positions {
+		slices.SortFunc(generated, compareGeneratedPositions)
+	}
+
+	return positions
+}
+
+func compareGeneratedPositions(a GeneratedPosition, b GeneratedPosition) int {
+	return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+}
+
+func compareMappingChanges(a MappingChange, b MappingChange) int {
+	return cmp.Or(
+		strings.Compare(a.Original.Source, b.Original.Source),
+		cmp.Compare(a.Original.Line, b.Original.Line),
+		cmp.Compare(a.Original.Column, b.Original.Column),
+	)
+}
+
+// diffKeys returns the keys only in before and only in after, sorted.
+func diffKeys[V any](before map[string]V, after map[string]V) ([]string, []string) {
+	added := make([]string, 0)
+	removed := make([]string, 0)
+
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			added = append(added, key)
+		}
+	}
+
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	return added, removed
+}
+
+// DiffSourceMaps compares the old source map before with the new source map after.
+func DiffSourceMaps(before *spec.DecodedSourceMapRecord, after *spec.DecodedSourceMapRecord) *SourceMapDiff {
+	diff := &SourceMapDiff{
+		ChangedSources:  make([]string, 0),
+		AddedMappings:   make([]MappingChange, 0),
+		RemovedMappings: make([]MappingChange, 0),
+		MovedMappings:   make([]MappingChange, 0),
+	}
+
+	beforeContents := sourceContents(before)
+	afterContents := sourceContents(after)
+	diff.AddedSources, diff.RemovedSources = diffKeys(beforeContents, afterContents)
+
+	for url, content := range afterContents {
+		if beforeContent, ok := beforeContents[url]; ok && beforeContent != content {
+			diff.ChangedSources = append(diff.ChangedSources, url)
+		}
+	}
+
+	slices.Sort(diff.ChangedSources)
+
+	diff.AddedNames, diff.RemovedNames = diffKeys(usedNames(before), usedNames(after))
+
+	beforePositions := generatedPositions(before)
+	afterPositions := generatedPositions(after)
+
+	for original, afterGenerated := range afterPositions {
+		beforeGenerated, ok := beforePositions[original]
+		change := MappingChange{Original: original, Before: make([]GeneratedPosition, 0), After: afterGenerated}
+
+		switch {
+		case !ok:
+			diff.AddedMappings = append(diff.AddedMappings, change)
+		case slices.Equal(beforeGenerated, afterGenerated):
+			diff.UnchangedMappings++
+		default:
+			change.Before = beforeGenerated
+			diff.MovedMappings = append(diff.MovedMappings, change)
+		}
+	}
+
+	for original, beforeGenerated := range beforePositions {
+		if _, ok := afterPositions[original]; !ok {
+			diff.RemovedMappings = append(diff.RemovedMappings, MappingChange{Original: original, Before: beforeGenerated, After: make([]GeneratedPosition, 0)})
+		}
+	}
+
+	slices.SortFunc(diff.AddedMappings, compareMappingChanges)
+	slices.SortFunc(diff.RemovedMappings, compareMappingChanges)
+	slices.SortFunc(diff.MovedMappings, compareMappingChanges)
+
+	return diff
+}
+
+// formatGeneratedPositions formats positions as line:column, with 1-based lines and 0-based columns.
+func formatGeneratedPositions(positions []GeneratedPosition) string {
+	formatted := make([]string, len(positions))
+
+	for i, position := range positions {
+		formatted[i] = fmt.Sprintf("%d:%d", position.Line+1, position.Column)
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// WriteText writes diff to w in a readable form, with + for additions, - for removals and ~ for changes.
+// Lines are 1-based and columns 0-based, as in RenderMappingTable. At most limit entries of each kind are listed,
+// or all of them if limit <= 0.
+func (diff *SourceMapDiff) WriteText(w io.Writer, limit int) error {
+	var out strings.Builder
+
+	list := func(prefix string, entries []string) {
+		for i, entry := range entries {
+			if limit > 0 && i == limit {
+				fmt.Fprintf(&out, "  ... and %d more\n", len(entries)-limit)
+				break
+			}
+
+			fmt.Fprintf(&out, "%s %s\n", prefix, entry)
+		}
+	}
+	changes := func(prefix string, mappingChanges []MappingChange, format func(change MappingChange) string) {
+		entries := make([]string, len(mappingChanges))
+
+		for i, change := range mappingChanges {
+			original := change.Original
+			entries[i] = fmt.Sprintf("%s:%d:%d %s", original.Source, original.Line+1, original.Column, format(change))
+		}
+
+		list(prefix, entries)
+	}
+
+	fmt.Fprintf(&out, "Sources: %d added, %d removed, %d changed\n", len(diff.AddedSources), len(diff.RemovedSources), len(diff.ChangedSources))
+	list("+", diff.AddedSources)
+	list("-", diff.RemovedSources)
+	list("~", diff.ChangedSources)
+
+	fmt.Fprintf(&out, "Names: %d added, %d removed\n", len(diff.AddedNames), len(diff.RemovedNames))
+	list("+", diff.AddedNames)
+	list("-", diff.RemovedNames)
+
+	fmt.Fprintf(&out, "Mappings: %d added, %d removed, %d moved, %d unchanged\n",
+		len(diff.AddedMappings), len(diff.RemovedMappings), len(diff.MovedMappings), diff.UnchangedMappings)
+	changes("+", diff.AddedMappings, func(change MappingChange) string {
+		return "-> " + formatGeneratedPositions(change.After)
+	})
+	changes("-", diff.RemovedMappings, func(change MappingChange) string {
+		return "was " + formatGeneratedPositions(change.Before)
+	})
+	changes("~", diff.MovedMappings, func(change MappingChange) string {
+		return formatGeneratedPositions(change.Before) + " -> " + formatGeneratedPositions(change.After)
+	})
+
+	_, err := io.WriteString(w, out.String())
+
+	if err != nil {
+		return fmt.Errorf("Error writing diff: %w", err)
+	}
+
+	return nil
+}
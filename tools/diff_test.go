@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// diffTestMapping is a mapping of diffTestMap, to the source at index source.
+type diffTestMapping struct {
+	line, column, source, originalLine, originalColumn int
+	name                                               string
+}
+
+// diffTestMap builds a source map from sources, given as url and content, and mappings.
+func diffTestMap(sources [][2]string, mappings []diffTestMapping) *spec.DecodedSourceMapRecord {
+	mapRecord := &spec.DecodedSourceMapRecord{}
+
+	for _, source := range sources {
+		mapRecord.Sources = append(mapRecord.Sources, &spec.DecodedSourceRecord{Url: source[0], Content: source[1]})
+	}
+
+	for _, mapping := range mappings {
+		mapRecord.Mappings = append(mapRecord.Mappings, &spec.DecodedMappingRecord{
+			GeneratedLine:   mapping.line,
+			GeneratedColumn: mapping.column,
+			OriginalSource:  mapRecord.Sources[mapping.source],
+			OriginalLine:    mapping.originalLine,
+			OriginalColumn:  mapping.originalColumn,
+			Name:            mapping.name,
+		})
+	}
+
+	return mapRecord
+}
+
+func TestDiffSourceMaps(t *testing.T) {
+	before := diffTestMap([][2]string{{"a.js", "a"}, {"b.js", "b"}, {"c.js", "c"}}, []diffTestMapping{
+		{0, 0, 0, 0, 0, ""},
+		{0, 4, 0, 0, 4, "foo"},
+		{0, 6, 1, 0, 0, "bar"},
+		{1, 0, 2, 1, 0, ""},
+		{2, 0, 1, 1, 1, ""},
+	})
+	after := diffTestMap([][2]string{{"a.js", "a"}, {"b.js", "b2"}, {"d.js", "d"}}, []diffTestMapping{
+		{0, 0, 0, 0, 0, ""},
+		{0, 5, 0, 0, 4, "foo"},
+		{1, 0, 2, 0, 0, "baz"},
+		{2, 0, 1, 1, 1, ""},
+	})
+
+	diff := DiffSourceMaps(before, after)
+
+	if !slices.Equal(diff.AddedSources, []string{"d.js"}) || !slices.Equal(diff.RemovedSources, []string{"c.js"}) ||
+		!slices.Equal(diff.ChangedSources, []string{"b.js"}) {
+		t.Errorf("Unexpected sources: added %v, removed %v, changed %v", diff.AddedSources, diff.RemovedSources, diff.ChangedSources)
+	}
+
+	if !slices.Equal(diff.AddedNames, []string{"baz"}) || !slices.Equal(diff.RemovedNames, []string{"bar"}) {
+		t.Errorf("Unexpected names: added %v, removed %v", diff.AddedNames, diff.RemovedNames)
+	}
+
+	if diff.UnchangedMappings != 2 {
+		t.Errorf("Expected 2 unchanged mappings, got %d", diff.UnchangedMappings)
+	}
+
+	if len(diff.MovedMappings) != 1 || diff.MovedMappings[0].Original != (OriginalPosition{Source: "a.js", Column: 4}) ||
+		!slices.Equal(diff.MovedMappings[0].Before, []GeneratedPosition{{Line: 0, Column: 4}}) ||
+		!slices.Equal(diff.MovedMappings[0].After, []GeneratedPosition{{Line: 0, Column: 5}}) {
+		t.Errorf("Unexpected moved mappings %+v", diff.MovedMappings)
+	}
+
+	if len(diff.AddedMappings) != 1 || diff.AddedMappings[0].Original.Source != "d.js" {
+		t.Errorf("Unexpected added mappings %+v", diff.AddedMappings)
+	}
+
+	if len(diff.RemovedMappings) != 2 || diff.RemovedMappings[0].Original.Source != "b.js" || diff.RemovedMappings[1].Original.Source != "c.js" {
+		t.Errorf("Unexpected removed mappings %+v", diff.RemovedMappings)
+	}
+
+	if diff.Equal() {
+		t.Errorf("Expected the maps to differ")
+	}
+
+	if !DiffSourceMaps(before, before).Equal() {
+		t.Errorf("Expected a map to equal itself")
+	}
+
+	if _, err := json.Marshal(diff); err != nil {
+		t.Errorf("Error marshaling diff: %v", err)
+	}
+}
+
+func TestSourceMapDiffWriteText(t *testing.T) {
+	diff := &SourceMapDiff{
+		AddedSources: []string{"a.js", "b.js", "c.js"},
+		MovedMappings: []MappingChange{{
+			Original: OriginalPosition{Source: "a.js", Line: 2, Column: 4},
+			Before:   []GeneratedPosition{{Line: 0, Column: 10}},
+			After:    []GeneratedPosition{{Line: 0, Column: 12}, {Line: 1, Column: 0}},
+		}},
+		UnchangedMappings: 7,
+	}
+
+	var out strings.Builder
+
+	if err := diff.WriteText(&out, 2); err != nil {
+		t.Fatalf("Error writing diff: %v", err)
+	}
+
+	expected := `Sources: 3 added, 0 removed, 0 changed
++ a.js
++ b.js
+  ... and 1 more
+Names: 0 added, 0 removed
+Mappings: 0 added, 0 removed, 1 moved, 7 unchanged
+~ a.js:3:4 1:10 -> 1:12, 2:0
+`
+
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
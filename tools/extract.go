@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// RewrittenPath records a source url that could not be used verbatim as a path inside the output directory.
+type RewrittenPath struct {
+	// Url is the original url of the source
+	Url string `json:"url"`
+	// Path is the slash separated path, relative to the output directory, that was used instead
+	Path string `json:"path"`
+}
+
+// ContentResolver returns the contents of the source at url, for sources that have no sourcesContent.
+type ContentResolver func(url string) (string, error)
+
+// ExtractOptions controls how ExtractSourcesToDirectory lays out the extracted sources.
+type ExtractOptions struct {
+	// Layout is the strategy used to turn source urls into paths, LayoutClean by default
+	Layout Layout
+	// ResolveContent is called for every source without content. If nil, or it returns an error,
+	// the source is recorded in ExtractReport.Missing instead of being written.
+	ResolveContent ContentResolver
+}
+
+// ExtractReport describes the outcome of extracting the sources of a source map.
+type ExtractReport struct {
+	// Written is every source that was written
+	Written []WrittenSource `json:"written"`
+	// Rewritten is every source whose url was changed to keep it inside the output directory
+	Rewritten []RewrittenPath `json:"rewritten"`
+	// Rejected is every source url that could not be turned into a path at all, e.g. "../.."
+	Rejected []string `json:"rejected"`
+	// Resolved is every source url whose content was missing, and was retrieved with ExtractOptions.ResolveContent
+	Resolved []string `json:"resolved"`
+	// Missing is every source url whose content was missing, and could not be resolved
+	Missing []MissingSource `json:"missing"`
+	// Duplicates is every source that was not written because an earlier source was written to the same path
+	Duplicates []RewrittenPath `json:"duplicates"`
+	// Conflicts is every source that was not written because its path is both a file and a directory, e.g. a.js and a.js/b.js
+	Conflicts []RewrittenPath `json:"conflicts"`
+}
+
+// WrittenSource records a source that was written, and is the format of the entries in an archive's manifest.json.
+type WrittenSource struct {
+	// Path is the slash separated path, relative to the output directory or archive root, of the written file
+	Path string `json:"path"`
+	// Url is the original url of the source
+	Url string `json:"url"`
+	// Ignored is whether the source is in the source map's ignoreList
+	Ignored bool `json:"ignored"`
+	// Sha256 is the hex encoded SHA-256 hash of the written content
+	Sha256 string `json:"sha256"`
+	// Size is the length in bytes of the written content
+	Size int `json:"size"`
+}
+
+// MissingSource records a source that was not written because it had no content.
+type MissingSource struct {
+	// Url is the original url of the source
+	Url string `json:"url"`
+	// Error is why the content could not be resolved, if resolution was attempted
+	Error string `json:"error,omitempty"`
+}
+
+// schemePrefix matches url schemes such as "webpack:" and "file:", as well as windows drive letters such as "C:".
+var schemePrefix = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// SanitizePath converts url into a relative, slash separated path that cannot escape the directory it is joined to.
+// Backslashes are treated as separators, scheme prefixes, drive letters and NUL bytes are removed,
+// and empty, "." and ".." segments are dropped.
+// Returns "" if nothing is left of url.
+func SanitizePath(url string) string {
+	path := strings.ReplaceAll(url, "\\", "/")
+	path = strings.ReplaceAll(path, "\x00", "")
+	path = schemePrefix.ReplaceAllString(path, "")
+
+	segments := strings.Split(path, "/")
+	cleaned := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+
+		cleaned = append(cleaned, segment)
+	}
+
+	return strings.Join(cleaned, "/")
+}
+
+// SafeJoin joins dir and the relative, slash separated path.
+// Returns an error if path is empty, or the joined path would be outside of dir.
+func SafeJoin(dir string, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("Error joining %s: path is empty", dir)
+	}
+
+	fullPath := filepath.Join(dir, filepath.FromSlash(path))
+	rel, err := filepath.Rel(dir, fullPath)
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("Error joining %s: %s is outside of %s", path, fullPath, dir)
+	}
+
+	return fullPath, nil
+}
+
+// ExtractSourcesToDirectory saves mapRecord.Sources to dir, confining every file to dir.
+// If dir doesn't exist, it is recursively created with 0700 permissions.
+// Files are saved with 0600 permissions.
+// See ExtractSources for how source urls are turned into paths.
+func ExtractSourcesToDirectory(mapRecord *spec.DecodedSourceMapRecord, dir string, options ExtractOptions) (*ExtractReport, error) {
+	writer, err := NewDirectoryWriter(dir)
+
+	if err != nil {
+		return &ExtractReport{}, err
+	}
+
+	return ExtractSources(mapRecord, writer, options)
+}
+
+// ExtractSources writes mapRecord.Sources to writer.
+// Source urls are mapped to paths with NormalizeSourcePath and options.Layout, then passed through SanitizePath.
+// Any path that SanitizePath had to change is recorded in the returned report.
+// Sources without content are resolved with options.ResolveContent, and are reported as missing if that fails.
+// writer is not closed.
+func ExtractSources(mapRecord *spec.DecodedSourceMapRecord, writer SourceWriter, options ExtractOptions) (*ExtractReport, error) {
+	report := &ExtractReport{}
+
+	for _, source := range mapRecord.Sources {
+		normalized := NormalizeSourcePath(source.Url, options.Layout)
+		path := SanitizePath(normalized)
+
+		if path == "" {
+			report.Rejected = append(report.Rejected, source.Url)
+			continue
+		}
+
+		if path != normalized {
+			report.Rewritten = append(report.Rewritten, RewrittenPath{Url: source.Url, Path: path})
+		}
+
+		content, err := resolveSourceContent(source, options.ResolveContent)
+
+		if err != nil {
+			report.Missing = append(report.Missing, MissingSource{Url: source.Url, Error: err.Error()})
+			continue
+		}
+
+		if source.Content == "" {
+			report.Resolved = append(report.Resolved, source.Url)
+		}
+
+		err = writer.WriteSource(path, []byte(content))
+
+		if errors.Is(err, ErrDuplicateSource) {
+			report.Duplicates = append(report.Duplicates, RewrittenPath{Url: source.Url, Path: path})
+			continue
+		}
+
+		if errors.Is(err, ErrPathConflict) {
+			report.Conflicts = append(report.Conflicts, RewrittenPath{Url: source.Url, Path: path})
+			continue
+		}
+
+		if err != nil {
+			return report, err
+		}
+
+		hash := sha256.Sum256([]byte(content))
+
+		report.Written = append(report.Written, WrittenSource{
+			Path:    path,
+			Url:     source.Url,
+			Ignored: source.Ignored,
+			Sha256:  hex.EncodeToString(hash[:]),
+			Size:    len(content),
+		})
+	}
+
+	return report, nil
+}
+
+// resolveSourceContent returns the content of source, calling resolve if source has none.
+func resolveSourceContent(source *spec.DecodedSourceRecord, resolve ContentResolver) (string, error) {
+	if source.Content != "" {
+		return source.Content, nil
+	}
+
+	if resolve == nil {
+		return "", fmt.Errorf("Error: %s has no content", source.Url)
+	}
+
+	return resolve(source.Url)
+}
+
+// LocalContentResolver returns a ContentResolver that reads sources from a local checkout at root,
+// using the same paths as LayoutClean, e.g. webpack:///./src/a.js is read from root/src/a.js.
+func LocalContentResolver(root string) ContentResolver {
+	return func(url string) (string, error) {
+		fullPath, err := SafeJoin(root, SanitizePath(NormalizeSourcePath(url, LayoutClean)))
+
+		if err != nil {
+			return "", err
+		}
+
+		contents, err := os.ReadFile(fullPath)
+
+		if err != nil {
+			return "", fmt.Errorf("Error reading contents of %s: %w", fullPath, err)
+		}
+
+		return string(contents), nil
+	}
+}
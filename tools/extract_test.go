@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestSanitizePath(t *testing.T) {
+	tests := map[string]string{
+		"src/index.js":            "src/index.js",
+		"../../../.bashrc":        ".bashrc",
+		"/etc/passwd":             "etc/passwd",
+		"C:\\Windows\\system.ini": "Windows/system.ini",
+		"webpack:///./src/a.js":   "src/a.js",
+		"a/./b/../c.js":           "a/b/c.js",
+		"../..":                   "",
+	}
+
+	for url, expected := range tests {
+		t.Run(url, func(t *testing.T) {
+			actual := SanitizePath(url)
+
+			if actual != expected {
+				t.Errorf("SanitizePath(%q) = %q, expected %q", url, actual, expected)
+			}
+		})
+	}
+}
+
+func TestExtractSourcesToDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "out")
+
+	mapRecord := &spec.DecodedSourceMapRecord{
+		Sources: []*spec.DecodedSourceRecord{
+			{Url: "src/index.js", Content: "index"},
+			{Url: "../../escape.js", Content: "escape"},
+			{Url: "/abs/path.js", Content: "abs"},
+			{Url: "../..", Content: "nothing"},
+		},
+	}
+
+	report, err := ExtractSourcesToDirectory(mapRecord, dir, ExtractOptions{Layout: LayoutRaw})
+
+	if err != nil {
+		t.Fatalf("Error extracting sources: %v", err)
+	}
+
+	for path, content := range map[string]string{"src/index.js": "index", "escape.js": "escape", "abs/path.js": "abs"} {
+		actual, err := os.ReadFile(filepath.Join(dir, path))
+
+		if err != nil || string(actual) != content {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", path, content, actual, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "escape.js")); err == nil {
+		t.Errorf("escape.js was written outside of %s", dir)
+	}
+
+	if len(report.Rewritten) != 2 {
+		t.Errorf("Expected 2 rewritten paths, got %v", report.Rewritten)
+	}
+
+	if len(report.Rejected) != 1 || report.Rejected[0] != "../.." {
+		t.Errorf("Expected ../.. to be rejected, got %v", report.Rejected)
+	}
+}
+
+func TestExtractSourcesToDirectoryMissingContent(t *testing.T) {
+	dir := t.TempDir()
+	checkout := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(checkout, "found.js"), []byte("found"), 0600)
+
+	if err != nil {
+		t.Fatalf("Error writing found.js: %v", err)
+	}
+
+	mapRecord := &spec.DecodedSourceMapRecord{
+		Sources: []*spec.DecodedSourceRecord{
+			{Url: "index.js", Content: "index"},
+			{Url: "webpack:///./found.js"},
+			{Url: "webpack:///./lost.js"},
+		},
+	}
+
+	report, err := ExtractSourcesToDirectory(mapRecord, dir, ExtractOptions{ResolveContent: LocalContentResolver(checkout)})
+
+	if err != nil {
+		t.Fatalf("Error extracting sources: %v", err)
+	}
+
+	for path, content := range map[string]string{"index.js": "index", "found.js": "found"} {
+		actual, err := os.ReadFile(filepath.Join(dir, path))
+
+		if err != nil || string(actual) != content {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", path, content, actual, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "lost.js")); err == nil {
+		t.Errorf("Expected lost.js not to be written")
+	}
+
+	if len(report.Resolved) != 1 || report.Resolved[0] != "webpack:///./found.js" {
+		t.Errorf("Expected found.js to be resolved, got %v", report.Resolved)
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0].Url != "webpack:///./lost.js" {
+		t.Errorf("Expected lost.js to be missing, got %v", report.Missing)
+	}
+}
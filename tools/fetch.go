@@ -0,0 +1,542 @@
+package tools
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// DefaultMaxBytes is the largest response body, after decompression, that a Fetcher reads when MaxBytes is 0.
+const DefaultMaxBytes = 512 << 20
+
+// DefaultFetcher is the Fetcher used by ParseSourceMapFromUrl.
+var DefaultFetcher = &Fetcher{Timeout: 30 * time.Second, Retry: &RetryPolicy{}}
+
+// ErrResponseTooLarge is returned when a response body is larger than Fetcher.MaxBytes.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// StatusError is returned when a url responds with a status other than 2xx.
+type StatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("Error retrieving %s: %s", err.Url, err.Status)
+}
+
+// Decoder wraps a response body compressed with a Content-Encoding.
+// If the returned reader is an io.Closer, it is closed once the body has been read.
+type Decoder func(body io.Reader) (io.Reader, error)
+
+// Fetcher downloads source maps and sources over http(s).
+// The zero value is ready to use, and fetches with http.DefaultClient, no timeout, and DefaultMaxBytes.
+type Fetcher struct {
+	// Client is used for every request, http.DefaultClient if nil
+	Client *http.Client
+	// Header is added to requests to CredentialHosts, e.g. a User-Agent or Authorization header
+	Header http.Header
+	// Cookies are added to requests to CredentialHosts
+	Cookies []*http.Cookie
+	// BearerToken, if set, is sent as an "Authorization: Bearer" header to CredentialHosts
+	BearerToken string
+	// CredentialHosts are the hosts, with or without a port, that Header, Cookies and BearerToken are sent to.
+	// They are left out of requests and redirects to any other host, so they are never sent if CredentialHosts is empty
+	CredentialHosts []string
+	// MaxBytes is the largest response body, after decompression, that is read. DefaultMaxBytes if 0
+	MaxBytes int64
+	// Timeout limits every attempt, including reading the body. No limit if 0
+	Timeout time.Duration
+	// Retry controls retrying transient failures. Downloads are attempted once if nil
+	Retry *RetryPolicy
+	// MaxRedirects is the number of redirects followed. Client's policy is used if 0, and no redirects are followed if < 0
+	MaxRedirects int
+	// Cache, if set, stores responses on disk and revalidates them with If-None-Match and If-Modified-Since.
+	// Entries are keyed by url and the Header, Cookies and BearerToken sent, and no-store or private responses are not stored
+	Cache *DiskCache
+	// Maps, if set, caches parsed source maps by url, so ParseSourceMap only downloads each url once
+	Maps *MapCache
+	// Decoders decode additional Content-Encodings, which are then advertised in Accept-Encoding.
+	// gzip, deflate and br are always supported
+	Decoders map[string]Decoder
+}
+
+// builtinDecoders are the Content-Encodings a Fetcher decodes without any Decoders.
+var builtinDecoders = map[string]Decoder{
+	"gzip": func(body io.Reader) (io.Reader, error) {
+		return gzip.NewReader(body)
+	},
+	"deflate": decodeDeflate,
+	"br": func(body io.Reader) (io.Reader, error) {
+		return brotli.NewReader(body), nil
+	},
+}
+
+// decodeDeflate decodes a deflate Content-Encoding, which is zlib wrapped deflate data (RFC 9110 section 8.4.1.2).
+// Some servers send raw deflate data instead, which is detected by the missing zlib header.
+func decodeDeflate(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+
+	// A zlib header uses the deflate method, and is a multiple of 31 when read as a big endian number
+	if err == nil && header[0]&0x0f == 8 && (int(header[0])<<8|int(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
+
+// decoder returns the Decoder for encoding, or nil if it is not supported.
+func (fetcher *Fetcher) decoder(encoding string) Decoder {
+	if decoder, ok := fetcher.Decoders[encoding]; ok {
+		return decoder
+	}
+
+	return builtinDecoders[encoding]
+}
+
+// acceptEncoding returns the Accept-Encoding header advertising every supported encoding.
+func (fetcher *Fetcher) acceptEncoding() string {
+	encodings := []string{"gzip", "deflate", "br"}
+
+	for encoding := range fetcher.Decoders {
+		if builtinDecoders[encoding] == nil {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	sort.Strings(encodings[3:])
+
+	return strings.Join(encodings, ", ")
+}
+
+// client returns the http.Client to use, applying MaxRedirects, and removing credentials from redirects to hosts
+// that are not CredentialHosts.
+func (fetcher *Fetcher) client() *http.Client {
+	client := fetcher.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if fetcher.MaxRedirects == 0 && !fetcher.hasCredentials() {
+		return client
+	}
+
+	limited := *client
+	maxRedirects := fetcher.MaxRedirects
+	checkRedirect := client.CheckRedirect
+
+	limited.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if maxRedirects < 0 {
+			return http.ErrUseLastResponse
+		}
+
+		if maxRedirects > 0 && len(via) > maxRedirects {
+			return fmt.Errorf("Error: stopped after %d redirects", maxRedirects)
+		}
+
+		// The redirect copies the headers of the first request, which net/http only partly removes for other hosts
+		if !fetcher.sendsCredentials(request.URL) {
+			fetcher.removeCredentials(request)
+		}
+
+		if maxRedirects > 0 {
+			return nil
+		}
+
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+
+		// The default policy of http.Client
+		if len(via) >= 10 {
+			return fmt.Errorf("Error: stopped after 10 redirects")
+		}
+
+		return nil
+	}
+
+	return &limited
+}
+
+// hasCredentials reports whether the fetcher has any headers, cookies or bearer token to send.
+func (fetcher *Fetcher) hasCredentials() bool {
+	return len(fetcher.Header) > 0 || len(fetcher.Cookies) > 0 || fetcher.BearerToken != ""
+}
+
+// sendsCredentials reports whether the fetcher's headers, cookies and bearer token are sent to target.
+func (fetcher *Fetcher) sendsCredentials(target *url.URL) bool {
+	return matchesHost(target, fetcher.CredentialHosts)
+}
+
+// matchesHost reports whether the host of target is one of hosts, which may leave out the port.
+func matchesHost(target *url.URL, hosts []string) bool {
+	for _, host := range hosts {
+		if strings.EqualFold(target.Host, host) || strings.EqualFold(target.Hostname(), host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeCredentials removes the fetcher's headers, cookies and authorization from request.
+func (fetcher *Fetcher) removeCredentials(request *http.Request) {
+	for name := range fetcher.Header {
+		request.Header.Del(name)
+	}
+
+	if len(fetcher.Cookies) > 0 {
+		request.Header.Del("Cookie")
+	}
+
+	if fetcher.BearerToken != "" {
+		request.Header.Del("Authorization")
+	}
+
+	request.Header.Set("Accept-Encoding", fetcher.acceptEncoding())
+}
+
+// newRequest returns a GET request for url, with the fetcher's headers, cookies and authorization if url is on one of
+// CredentialHosts.
+func (fetcher *Fetcher) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request for %s: %w", url, err)
+	}
+
+	if fetcher.sendsCredentials(request.URL) {
+		for name, values := range fetcher.Header {
+			for _, value := range values {
+				request.Header.Add(name, value)
+			}
+		}
+
+		for _, cookie := range fetcher.Cookies {
+			request.AddCookie(cookie)
+		}
+
+		if fetcher.BearerToken != "" {
+			request.Header.Set("Authorization", "Bearer "+fetcher.BearerToken)
+		}
+	}
+
+	request.Header.Set("Accept-Encoding", fetcher.acceptEncoding())
+
+	return request, nil
+}
+
+// Fetch returns the decompressed body of url, retrying transient failures according to Retry.
+// Returns an error if url is unreachable, returns a status other than 2xx (as a *StatusError),
+// uses an unsupported Content-Encoding, or has a body larger than MaxBytes.
+func (fetcher *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if fetcher.Retry == nil {
+		return fetcher.fetchOnce(ctx, url)
+	}
+
+	return fetcher.Retry.do(ctx, url, func() ([]byte, error) {
+		return fetcher.fetchOnce(ctx, url)
+	})
+}
+
+// fetchOnce makes a single attempt at downloading url.
+func (fetcher *Fetcher) fetchOnce(ctx context.Context, url string) ([]byte, error) {
+	if fetcher.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fetcher.Timeout)
+		defer cancel()
+	}
+
+	request, err := fetcher.newRequest(ctx, url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fetcher.cacheKey(url)
+	cached := fetcher.loadCached(cacheKey)
+
+	if cached != nil {
+		if cached.ETag != "" {
+			request.Header.Set("If-None-Match", cached.ETag)
+		}
+
+		if cached.LastModified != "" {
+			request.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	response, err := fetcher.client().Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		if cacheable(response) {
+			cached.StoredAt = time.Now()
+			fetcher.storeCached(cached)
+		}
+
+		return cached.Body, nil
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &StatusError{Url: url, StatusCode: response.StatusCode, Status: response.Status, Header: response.Header}
+	}
+
+	contents, err := fetcher.readBody(url, response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	etag := response.Header.Get("ETag")
+	lastModified := response.Header.Get("Last-Modified")
+
+	if (etag != "" || lastModified != "") && cacheable(response) {
+		fetcher.storeCached(&DiskCacheEntry{
+			Url:          url,
+			Key:          cacheKey,
+			ETag:         etag,
+			LastModified: lastModified,
+			StoredAt:     time.Now(),
+			Body:         contents,
+		})
+	}
+
+	return contents, nil
+}
+
+// cacheKey returns the key of rawUrl in Cache. Requests sending headers, cookies or a bearer token get a key of their own,
+// with a hash of them, so that responses meant for one set of credentials are never used with another.
+func (fetcher *Fetcher) cacheKey(rawUrl string) string {
+	if !fetcher.hasCredentials() {
+		return rawUrl
+	}
+
+	if target, err := url.Parse(rawUrl); err != nil || !fetcher.sendsCredentials(target) {
+		return rawUrl
+	}
+
+	hash := sha256.New()
+	names := make([]string, 0, len(fetcher.Header))
+
+	for name := range fetcher.Header {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s: %q\n", name, fetcher.Header.Values(name))
+	}
+
+	for _, cookie := range fetcher.Cookies {
+		fmt.Fprintf(hash, "Cookie: %s\n", cookie.String())
+	}
+
+	fmt.Fprintf(hash, "Bearer: %s\n", fetcher.BearerToken)
+
+	return rawUrl + " " + hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheable reports whether response may be stored in Cache, which is not the case if its Cache-Control has no-store,
+// or private, since the cache directory may be shared.
+func cacheable(response *http.Response) bool {
+	for _, header := range response.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			name = strings.ToLower(name)
+
+			if name == "no-store" || name == "private" {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// loadCached returns the entry for key in Cache, or nil if there is no usable entry.
+func (fetcher *Fetcher) loadCached(key string) *DiskCacheEntry {
+	if fetcher.Cache == nil {
+		return nil
+	}
+
+	entry, err := fetcher.Cache.Load(key)
+
+	if err != nil {
+		slog.Warn("Ignoring unreadable cache entry", "key", key, "error", err)
+		return nil
+	}
+
+	return entry
+}
+
+// storeCached saves entry to Cache, if there is one.
+// Failing to cache is not fatal to the download, so errors are only logged.
+func (fetcher *Fetcher) storeCached(entry *DiskCacheEntry) {
+	if fetcher.Cache == nil {
+		return
+	}
+
+	err := fetcher.Cache.Store(entry)
+
+	if err != nil {
+		slog.Warn("Error caching response", "url", entry.Url, "error", err)
+	}
+}
+
+// readBody decodes and reads the body of response, enforcing MaxBytes.
+func (fetcher *Fetcher) readBody(url string, response *http.Response) ([]byte, error) {
+	var body io.Reader = response.Body
+
+	if encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding"))); encoding != "" && encoding != "identity" {
+		decoder := fetcher.decoder(encoding)
+
+		if decoder == nil {
+			return nil, fmt.Errorf("Error reading %s: unsupported Content-Encoding %s", url, encoding)
+		}
+
+		decoded, err := decoder(body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Error decoding %s: %w", url, err)
+		}
+
+		if closer, ok := decoded.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		body = decoded
+	}
+
+	maxBytes := fetcher.MaxBytes
+
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	contents, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response body: %w", err)
+	}
+
+	if int64(len(contents)) > maxBytes {
+		return nil, fmt.Errorf("Error reading %s: %w, limit is %d bytes", url, ErrResponseTooLarge, maxBytes)
+	}
+
+	return contents, nil
+}
+
+// ParseSourceMap parses the source map file located at url.
+// If Maps is set, a source map already parsed from url is returned without downloading it again.
+// Returns an error if url cannot be fetched, or is not a valid source map file.
+func (fetcher *Fetcher) ParseSourceMap(ctx context.Context, url string) (*spec.DecodedSourceMapRecord, error) {
+	if fetcher.Maps != nil {
+		if mapRecord, ok := fetcher.Maps.Get("url:" + url); ok {
+			return mapRecord, nil
+		}
+	}
+
+	contents, err := fetcher.Fetch(ctx, url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mapRecord, err := spec.ParseSourceMap(string(contents), "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fetcher.Maps != nil {
+		fetcher.Maps.Add("url:"+url, mapRecord)
+	}
+
+	return mapRecord, nil
+}
+
+// SourceResolver returns a ContentResolver for the sources of the source map loaded from mapLocation, an http(s) url,
+// a local file path, or StdinInput. Source urls are resolved against mapLocation, and http(s) sources are downloaded
+// with the fetcher, which only sends its credentials to sources on CredentialHosts, so a source map can't collect
+// them by naming a source on another host. Other sources are only read if the source map is a local file, and only from inside its directory,
+// so a source map can't make the tool read arbitrary files. Use LocalContentResolver to read sources from elsewhere.
+func (fetcher *Fetcher) SourceResolver(mapLocation string) ContentResolver {
+	return func(source string) (string, error) {
+		if isHttpUrl(mapLocation) {
+			base, err := url.Parse(mapLocation)
+
+			if err != nil {
+				return "", fmt.Errorf("Error parsing %s: %w", mapLocation, err)
+			}
+
+			resolved, err := base.Parse(source)
+
+			if err != nil {
+				return "", fmt.Errorf("Error resolving %s against %s: %w", source, mapLocation, err)
+			}
+
+			source = resolved.String()
+		}
+
+		if isHttpUrl(source) {
+			contents, err := fetcher.Fetch(context.Background(), source)
+
+			if err != nil {
+				return "", err
+			}
+
+			return string(contents), nil
+		}
+
+		if isHttpUrl(mapLocation) || mapLocation == StdinInput || mapLocation == "" {
+			return "", fmt.Errorf("Error: %s is not an http(s) url, sources are only read from disk for local source maps", source)
+		}
+
+		if strings.Contains(source, ":") || path.IsAbs(source) || filepath.IsAbs(source) {
+			return "", fmt.Errorf("Error: %s is not relative to the source map, use a local checkout instead", source)
+		}
+
+		fullPath, err := SafeJoin(filepath.Dir(mapLocation), source)
+
+		if err != nil {
+			return "", err
+		}
+
+		contents, err := os.ReadFile(fullPath)
+
+		if err != nil {
+			return "", fmt.Errorf("Error reading contents of %s: %w", fullPath, err)
+		}
+
+		return string(contents), nil
+	}
+}
@@ -0,0 +1,338 @@
+package tools
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestFetcherHeadersAndGzip(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write(contents)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gzipWriter := gzip.NewWriter(w)
+		gzipWriter.Write(contents)
+		gzipWriter.Close()
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{
+		Header:      http.Header{"X-Test": {"yes"}},
+		Cookies:     []*http.Cookie{{Name: "session", Value: "abc"}},
+		BearerToken: "secret",
+		// Only the host, so credentials are sent whatever the port
+		CredentialHosts: []string{"127.0.0.1"},
+	}
+
+	mapRecord, err := fetcher.ParseSourceMap(context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatalf("Error parsing source map: %v", err)
+	}
+
+	if len(mapRecord.Sources) != 1 {
+		t.Errorf("Expected 1 source, got %d", len(mapRecord.Sources))
+	}
+
+	_, err = (&Fetcher{}).Fetch(context.Background(), server.URL)
+
+	var statusErr *StatusError
+
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 StatusError, got %v", err)
+	}
+}
+
+func TestFetcherCredentialHosts(t *testing.T) {
+	leaked := make(chan string, 10)
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"Authorization", "Cookie", "X-Test"} {
+			if r.Header.Get(name) != "" {
+				leaked <- r.URL.Path + " " + name
+			}
+		}
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{
+		Header:          http.Header{"X-Test": {"yes"}},
+		Cookies:         []*http.Cookie{{Name: "session", Value: "abc"}},
+		BearerToken:     "secret",
+		CredentialHosts: []string{strings.TrimPrefix(server.URL, "http://")},
+	}
+
+	for _, target := range []string{other.URL + "/direct", server.URL + "/redirected"} {
+		if _, err := fetcher.Fetch(context.Background(), target); err != nil {
+			t.Fatalf("Error fetching %s: %v", target, err)
+		}
+	}
+
+	close(leaked)
+
+	for header := range leaked {
+		t.Errorf("Expected no credentials to be sent to another host, got %s", header)
+	}
+}
+
+func TestFetcherBrotli(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "br")
+		writer := brotli.NewWriter(w)
+		writer.Write([]byte("brotli body"))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	contents, err := (&Fetcher{}).Fetch(context.Background(), server.URL)
+
+	if err != nil || string(contents) != "brotli body" {
+		t.Errorf("Expected the decoded body, got %q (%v)", contents, err)
+	}
+}
+
+func TestFetcherDeflate(t *testing.T) {
+	compress := map[string]func(w io.Writer) io.WriteCloser{
+		"zlib": func(w io.Writer) io.WriteCloser {
+			return zlib.NewWriter(w)
+		},
+		"raw": func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		},
+	}
+
+	for name, newWriter := range compress {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "deflate")
+			writer := newWriter(w)
+			writer.Write([]byte("deflated body"))
+			writer.Close()
+		}))
+
+		contents, err := (&Fetcher{}).Fetch(context.Background(), server.URL)
+		server.Close()
+
+		if err != nil || string(contents) != "deflated body" {
+			t.Errorf("%s: expected the decoded body, got %q (%v)", name, contents, err)
+		}
+	}
+}
+
+func TestFetcherMaxBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	_, err := (&Fetcher{MaxBytes: 99}).Fetch(context.Background(), server.URL)
+
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+	}
+
+	contents, err := (&Fetcher{MaxBytes: 100}).Fetch(context.Background(), server.URL)
+
+	if err != nil || len(contents) != 100 {
+		t.Errorf("Expected 100 bytes, got %d (%v)", len(contents), err)
+	}
+}
+
+func TestFetcherRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/final" {
+			w.Write([]byte("final"))
+			return
+		}
+
+		http.Redirect(w, r, "/final", http.StatusFound)
+	}))
+	defer server.Close()
+
+	contents, err := (&Fetcher{MaxRedirects: 1}).Fetch(context.Background(), server.URL+"/start")
+
+	if err != nil || string(contents) != "final" {
+		t.Errorf("Expected redirect to be followed, got %q (%v)", contents, err)
+	}
+
+	_, err = (&Fetcher{MaxRedirects: -1}).Fetch(context.Background(), server.URL+"/start")
+
+	var statusErr *StatusError
+
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusFound {
+		t.Errorf("Expected a 302 StatusError, got %v", err)
+	}
+}
+
+func TestFetcherTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	_, err := (&Fetcher{Timeout: 50 * time.Millisecond}).Fetch(context.Background(), server.URL)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = (&Fetcher{}).Fetch(ctx, server.URL)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestFetcherSourceResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret")
+
+	for path, content := range map[string]string{"dist/app.js.map": "{}", "dist/src/a.js": "local a", "outside.js": "outside"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0700); err != nil {
+			t.Fatalf("Error creating %s: %v", path, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0600); err != nil {
+			t.Fatalf("Error writing %s: %v", path, err)
+		}
+	}
+
+	if err := os.WriteFile(secret, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Error writing secret: %v", err)
+	}
+
+	fetcher := &Fetcher{}
+	localMap := filepath.Join(dir, "dist", "app.js.map")
+	tests := []struct {
+		mapLocation string
+		source      string
+		expected    string
+	}{
+		{server.URL + "/assets/app.js.map", "../src/a.js", "remote /src/a.js"},
+		{server.URL + "/assets/app.js.map", server.URL + "/b.js", "remote /b.js"},
+		{server.URL + "/assets/app.js.map", "file://" + secret, ""},
+		// An absolute path is a path on the host of the source map
+		{server.URL + "/assets/app.js.map", secret, "remote " + filepath.ToSlash(secret)},
+		{server.URL + "/assets/app.js.map", "webpack:///./src/a.js", ""},
+		{localMap, "src/a.js", "local a"},
+		{localMap, "./src/../src/a.js", "local a"},
+		{localMap, server.URL + "/c.js", "remote /c.js"},
+		{localMap, "../outside.js", ""},
+		{localMap, secret, ""},
+		{localMap, "file://" + secret, ""},
+		{StdinInput, "src/a.js", ""},
+	}
+
+	for _, test := range tests {
+		contents, err := fetcher.SourceResolver(test.mapLocation)(test.source)
+
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s from %s: expected an error, got %q", test.source, test.mapLocation, contents)
+			}
+		} else if err != nil || contents != test.expected {
+			t.Errorf("%s from %s: expected %q, got %q (%v)", test.source, test.mapLocation, test.expected, contents, err)
+		}
+	}
+}
+
+func TestFetcherSourceResolverCredentials(t *testing.T) {
+	credentials := make(chan string, 10)
+	record := func(server string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+				credentials <- server + " " + r.URL.Path
+			}
+
+			w.Write([]byte("source"))
+		}
+	}
+
+	attacker := httptest.NewServer(record("attacker"))
+	defer attacker.Close()
+
+	server := httptest.NewServer(record("server"))
+	defer server.Close()
+
+	fetcher := &Fetcher{
+		Cookies:         []*http.Cookie{{Name: "session", Value: "abc"}},
+		BearerToken:     "secret",
+		CredentialHosts: []string{strings.TrimPrefix(server.URL, "http://")},
+	}
+	resolve := fetcher.SourceResolver(server.URL + "/app.js.map")
+
+	for _, source := range []string{"src/a.js", attacker.URL + "/x.js"} {
+		if _, err := resolve(source); err != nil {
+			t.Fatalf("Error resolving %s: %v", source, err)
+		}
+	}
+
+	close(credentials)
+	received := make([]string, 0)
+
+	for request := range credentials {
+		received = append(received, request)
+	}
+
+	if len(received) != 1 || received[0] != "server /src/a.js" {
+		t.Errorf("Expected credentials to only be sent with the source on the map's host, got %v", received)
+	}
+}
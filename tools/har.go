@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// HarResolution controls where a HarReader looks for source maps that are referenced by a script.
+type HarResolution int
+
+const (
+	// HarOffline only uses responses recorded in the HAR, and never touches the network
+	HarOffline HarResolution = iota
+	// HarThenNetwork uses responses recorded in the HAR, and downloads source maps that are missing from it
+	HarThenNetwork
+)
+
+// Har is a parsed HTTP Archive, indexed by request url.
+type Har struct {
+	// Urls is the url of every successful response, in the order they were recorded
+	Urls      []string
+	responses map[string]*harResponse
+}
+
+// harFile is the subset of the HAR 1.2 format used by ParseHar.
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Url string `json:"url"`
+			} `json:"request"`
+			Response harResponse `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harResponse struct {
+	Status  int `json:"status"`
+	Headers []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	Content struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+// header returns the first value of the response header name, or "".
+func (response *harResponse) header(name string) string {
+	for _, header := range response.Headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value
+		}
+	}
+
+	return ""
+}
+
+// ParseHar parses the HTTP Archive read from r.
+// Only responses with a 2xx status and recorded content are kept. If a url was requested more than once, the last response wins.
+func ParseHar(r io.Reader) (*Har, error) {
+	file := &harFile{}
+
+	err := json.NewDecoder(r).Decode(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing HAR: %w", err)
+	}
+
+	har := &Har{Urls: make([]string, 0), responses: make(map[string]*harResponse)}
+
+	for i := range file.Log.Entries {
+		entry := &file.Log.Entries[i]
+
+		if entry.Response.Status < 200 || entry.Response.Status > 299 || entry.Response.Content.Text == "" {
+			continue
+		}
+
+		if _, ok := har.responses[entry.Request.Url]; !ok {
+			har.Urls = append(har.Urls, entry.Request.Url)
+		}
+
+		har.responses[entry.Request.Url] = &entry.Response
+	}
+
+	return har, nil
+}
+
+// Content returns the recorded body of the response to url.
+func (har *Har) Content(url string) (string, bool) {
+	response, ok := har.responses[url]
+
+	if !ok {
+		return "", false
+	}
+
+	if response.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(response.Content.Text)
+
+		if err != nil {
+			return "", false
+		}
+
+		return string(decoded), true
+	}
+
+	return response.Content.Text, true
+}
+
+// Assets returns the urls of every recorded script and stylesheet, in the order they were recorded.
+func (har *Har) Assets() []string {
+	assets := make([]string, 0)
+
+	for _, assetUrl := range har.Urls {
+		mimeType := strings.ToLower(har.responses[assetUrl].Content.MimeType)
+
+		if strings.Contains(mimeType, "javascript") || strings.Contains(mimeType, "ecmascript") || strings.Contains(mimeType, "text/css") {
+			assets = append(assets, assetUrl)
+			continue
+		}
+
+		if parsed, err := url.Parse(assetUrl); err == nil && assetExtension.MatchString(parsed.Path) {
+			assets = append(assets, assetUrl)
+		}
+	}
+
+	return assets
+}
+
+// HarMap is the source map recovered for one script or stylesheet in a HAR.
+type HarMap struct {
+	// AssetUrl is the script or stylesheet the source map belongs to
+	AssetUrl string `json:"assetUrl"`
+	// MapUrl is the url of the source map, or "" if it was inline
+	MapUrl string `json:"mapUrl,omitempty"`
+	// From is where the source map was read from, one of "inline", "har" or "network"
+	From string `json:"from,omitempty"`
+	// Record is the parsed source map, if it was recovered
+	Record *spec.DecodedSourceMapRecord `json:"-"`
+	// Report describes the sources written by ExtractSources
+	Report *ExtractReport `json:"report,omitempty"`
+	// Error is why the source map could not be recovered, if it wasn't
+	Error string `json:"error,omitempty"`
+}
+
+// HarReader recovers the source maps of the scripts and stylesheets recorded in a HAR.
+type HarReader struct {
+	// Resolution controls whether source maps missing from the HAR are downloaded, HarOffline by default
+	Resolution HarResolution
+	// Fetcher downloads source maps with HarThenNetwork, DefaultFetcher if nil
+	Fetcher *Fetcher
+}
+
+// ParseSourceMaps pairs every script and stylesheet in har with its source map, and parses it.
+// Source maps are found from the SourceMap and X-SourceMap response headers and sourceMappingURL comments,
+// and are read from inline data: urls, the HAR, or with HarThenNetwork, the network.
+// Assets without any source map reference are only paired if url.map was recorded in the HAR.
+func (reader *HarReader) ParseSourceMaps(ctx context.Context, har *Har) []HarMap {
+	harMaps := make([]HarMap, 0)
+
+	for _, assetUrl := range har.Assets() {
+		harMaps = append(harMaps, reader.parseSourceMap(ctx, har, assetUrl))
+	}
+
+	return harMaps
+}
+
+// parseSourceMap finds and parses the source map of the asset at assetUrl.
+func (reader *HarReader) parseSourceMap(ctx context.Context, har *Har, assetUrl string) HarMap {
+	harMap := HarMap{AssetUrl: assetUrl}
+	response := har.responses[assetUrl]
+	contents, _ := har.Content(assetUrl)
+
+	reference := response.header("SourceMap")
+
+	if reference == "" {
+		reference = response.header("X-SourceMap")
+	}
+
+	if reference == "" {
+		reference = FindSourceMappingUrl(contents)
+	}
+
+	var mapContents string
+
+	if strings.HasPrefix(reference, "data:") {
+		decoded, err := decodeDataUrl(reference)
+
+		if err != nil {
+			harMap.Error = err.Error()
+			return harMap
+		}
+
+		harMap.From = "inline"
+		mapContents = decoded
+	} else {
+		mapUrl, err := resolveHarReference(assetUrl, reference)
+
+		if err != nil {
+			harMap.Error = err.Error()
+			return harMap
+		}
+
+		harMap.MapUrl = mapUrl
+
+		if recorded, ok := har.Content(mapUrl); ok {
+			harMap.From = "har"
+			mapContents = recorded
+		} else if reference == "" || reader.Resolution == HarOffline {
+			harMap.Error = fmt.Sprintf("Error: %s was not recorded in the HAR", mapUrl)
+			return harMap
+		} else {
+			fetcher := reader.Fetcher
+
+			if fetcher == nil {
+				fetcher = DefaultFetcher
+			}
+
+			downloaded, err := fetcher.Fetch(ctx, mapUrl)
+
+			if err != nil {
+				harMap.Error = err.Error()
+				return harMap
+			}
+
+			harMap.From = "network"
+			mapContents = string(downloaded)
+		}
+	}
+
+	mapRecord, err := spec.ParseSourceMap(mapContents, "")
+
+	if err != nil {
+		harMap.Error = err.Error()
+		return harMap
+	}
+
+	harMap.Record = mapRecord
+
+	return harMap
+}
+
+// resolveHarReference resolves the source map reference against assetUrl, defaulting to assetUrl.map.
+func resolveHarReference(assetUrl string, reference string) (string, error) {
+	asset, err := url.Parse(assetUrl)
+
+	if err != nil {
+		return "", fmt.Errorf("Error parsing %s: %w", assetUrl, err)
+	}
+
+	if reference == "" {
+		reference = asset.Path[strings.LastIndexByte(asset.Path, '/')+1:] + ".map"
+	}
+
+	mapUrl, err := asset.Parse(reference)
+
+	if err != nil {
+		return "", fmt.Errorf("Error parsing sourceMappingURL %s: %w", reference, err)
+	}
+
+	return mapUrl.String(), nil
+}
+
+// ExtractSources recovers the source maps in har as ParseSourceMaps does, and writes their sources to writer.
+func (reader *HarReader) ExtractSources(ctx context.Context, har *Har, writer SourceWriter, options ExtractOptions) ([]HarMap, error) {
+	harMaps := reader.ParseSourceMaps(ctx, har)
+
+	for i := range harMaps {
+		if harMaps[i].Record == nil {
+			continue
+		}
+
+		report, err := ExtractSources(harMaps[i].Record, writer, options)
+		harMaps[i].Report = report
+
+		if err != nil {
+			return harMaps, fmt.Errorf("Error saving sources of %s: %w", harMaps[i].AssetUrl, err)
+		}
+	}
+
+	return harMaps, nil
+}
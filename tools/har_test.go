@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// harEntry builds a HAR entry for the har files used in tests.
+func harEntry(url string, mimeType string, text string, headers map[string]string) map[string]any {
+	harHeaders := make([]map[string]string, 0)
+
+	for name, value := range headers {
+		harHeaders = append(harHeaders, map[string]string{"name": name, "value": value})
+	}
+
+	return map[string]any{
+		"request": map[string]any{"url": url},
+		"response": map[string]any{
+			"status":  200,
+			"headers": harHeaders,
+			"content": map[string]any{"mimeType": mimeType, "text": text},
+		},
+	}
+}
+
+func buildHar(t *testing.T, entries ...map[string]any) *Har {
+	t.Helper()
+
+	contents, err := json.Marshal(map[string]any{"log": map[string]any{"entries": entries}})
+
+	if err != nil {
+		t.Fatalf("Error building HAR: %v", err)
+	}
+
+	har, err := ParseHar(strings.NewReader(string(contents)))
+
+	if err != nil {
+		t.Fatalf("Error parsing HAR: %v", err)
+	}
+
+	return har
+}
+
+func TestHarReaderOffline(t *testing.T) {
+	test1, _ := os.ReadFile("../testdata/test1.js.map")
+	test2, _ := os.ReadFile("../testdata/test2.js.map")
+
+	har := buildHar(t,
+		harEntry("https://example.com/index.html", "text/html", "<html></html>", nil),
+		harEntry("https://example.com/main.js", "application/javascript", "main()\n//# sourceMappingURL=main.js.map", nil),
+		harEntry("https://example.com/main.js.map", "application/json", string(test1), nil),
+		harEntry("https://example.com/header.js", "text/javascript", "header()", map[string]string{"SourceMap": "/maps/header.js.map"}),
+		harEntry("https://example.com/maps/header.js.map", "application/json", string(test2), nil),
+		harEntry("https://example.com/inline.js", "text/javascript", "inline()\n//# sourceMappingURL=data:application/json;base64,"+base64.StdEncoding.EncodeToString(test1), nil),
+		harEntry("https://example.com/remote.js", "text/javascript", "remote()\n//# sourceMappingURL=remote.js.map", nil),
+	)
+
+	reader := &HarReader{}
+	harMaps := reader.ParseSourceMaps(context.Background(), har)
+
+	if len(harMaps) != 4 {
+		t.Fatalf("Expected 4 assets, got %+v", harMaps)
+	}
+
+	expectedFrom := map[string]string{
+		"https://example.com/main.js":   "har",
+		"https://example.com/header.js": "har",
+		"https://example.com/inline.js": "inline",
+	}
+
+	for _, harMap := range harMaps {
+		from, ok := expectedFrom[harMap.AssetUrl]
+
+		if !ok {
+			if harMap.Error == "" || harMap.Record != nil {
+				t.Errorf("Expected %s not to be recovered offline, got %+v", harMap.AssetUrl, harMap)
+			}
+
+			continue
+		}
+
+		if harMap.Record == nil || harMap.From != from {
+			t.Errorf("Expected %s to be recovered from %s, got %+v", harMap.AssetUrl, from, harMap)
+		}
+	}
+}
+
+func TestHarReaderThenNetwork(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("../testdata")))
+	defer server.Close()
+
+	har := buildHar(t,
+		harEntry(server.URL+"/remote.js", "text/javascript", "remote()\n//# sourceMappingURL=test2.js.map", nil),
+	)
+
+	reader := &HarReader{Resolution: HarThenNetwork, Fetcher: &Fetcher{}}
+	dir := t.TempDir()
+	writer, _ := NewDirectoryWriter(dir)
+
+	harMaps, err := reader.ExtractSources(context.Background(), har, writer, ExtractOptions{})
+
+	if err != nil {
+		t.Fatalf("Error extracting sources: %v", err)
+	}
+
+	if len(harMaps) != 1 || harMaps[0].From != "network" || harMaps[0].Report == nil || len(harMaps[0].Report.Written) != 1 {
+		t.Errorf("Expected the source map to be downloaded and extracted, got %+v", harMaps)
+	}
+}
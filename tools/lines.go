@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// SplitLines splits content into lines on \n, \r\n and \r, matching how source maps count generated and original lines.
+// The line terminators are not included.
+func SplitLines(content string) []string {
+	lines := make([]string, 0, strings.Count(content, "\n")+1)
+	start := 0
+
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\n':
+			lines = append(lines, content[start:i])
+			start = i + 1
+		case '\r':
+			lines = append(lines, content[start:i])
+
+			if i+1 < len(content) && content[i+1] == '\n' {
+				i++
+			}
+
+			start = i + 1
+		}
+	}
+
+	return append(lines, content[start:])
+}
+
+// UTF16Length returns the length of s in UTF-16 code units, the unit source map columns are measured in.
+func UTF16Length(s string) int {
+	length := 0
+
+	for _, r := range s {
+		length += utf16.RuneLen(r)
+	}
+
+	return length
+}
+
+// ByteOffset returns the byte offset in line of the UTF-16 column, clamped to len(line).
+func ByteOffset(line string, column int) int {
+	units := 0
+
+	for offset, r := range line {
+		if units >= column {
+			return offset
+		}
+
+		units += utf16.RuneLen(r)
+	}
+
+	return len(line)
+}
+
+// SliceColumns returns the part of line between the UTF-16 columns start and end. end < 0 means the end of line.
+func SliceColumns(line string, start int, end int) string {
+	startOffset := ByteOffset(line, start)
+
+	if end < 0 {
+		return line[startOffset:]
+	}
+
+	return line[startOffset:max(ByteOffset(line, end), startOffset)]
+}
@@ -0,0 +1,39 @@
+package tools
+
+import "testing"
+
+func TestSplitLines(t *testing.T) {
+	lines := SplitLines("a\nb\r\nc\rd")
+	expected := []string{"a", "b", "c", "d"}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, lines)
+	}
+
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, lines)
+		}
+	}
+}
+
+func TestSliceColumns(t *testing.T) {
+	// 😀 is two UTF-16 code units, and é is one
+	line := "é😀abc"
+
+	if UTF16Length(line) != 6 {
+		t.Errorf("Expected a UTF-16 length of 6, got %d", UTF16Length(line))
+	}
+
+	if actual := SliceColumns(line, 1, 3); actual != "😀" {
+		t.Errorf("Expected 😀, got %q", actual)
+	}
+
+	if actual := SliceColumns(line, 3, -1); actual != "abc" {
+		t.Errorf("Expected abc, got %q", actual)
+	}
+
+	if actual := SliceColumns(line, 10, 20); actual != "" {
+		t.Errorf("Expected an empty slice, got %q", actual)
+	}
+}
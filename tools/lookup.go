@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"sort"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// OriginalPositionFor returns the mapping of mapRecord that covers the 0-based generated line and column, that is the mapping
+// on line with the greatest generated column <= column. Returns nil if there is no such mapping.
+// mapRecord.Mappings must be ordered by generated line, as spec.DecodeMappings returns them.
+func OriginalPositionFor(mapRecord *spec.DecodedSourceMapRecord, line int, column int) *spec.DecodedMappingRecord {
+	mappings := mapRecord.Mappings
+	first := sort.Search(len(mappings), func(i int) bool {
+		return mappings[i].GeneratedLine >= line
+	})
+
+	var found *spec.DecodedMappingRecord
+
+	for i := first; i < len(mappings) && mappings[i].GeneratedLine == line; i++ {
+		if mappings[i].GeneratedColumn > column {
+			continue
+		}
+
+		if found == nil || mappings[i].GeneratedColumn >= found.GeneratedColumn {
+			found = mappings[i]
+		}
+	}
+
+	return found
+}
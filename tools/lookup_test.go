@@ -0,0 +1,37 @@
+package tools
+
+import "testing"
+
+func TestOriginalPositionFor(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	tests := []struct {
+		line, column   int
+		found          bool
+		originalLine   int
+		originalColumn int
+	}{
+		{0, 0, true, 1, 0},
+		{0, 8, true, 1, 0},
+		{0, 9, true, 1, 9},
+		{0, 100, true, 2, 15},
+		{1, 0, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		mapping := OriginalPositionFor(mapRecord, test.line, test.column)
+
+		if (mapping != nil) != test.found {
+			t.Errorf("%d:%d: expected found to be %v, got %v", test.line, test.column, test.found, mapping)
+			continue
+		}
+
+		if mapping != nil && (mapping.OriginalLine != test.originalLine || mapping.OriginalColumn != test.originalColumn) {
+			t.Errorf("%d:%d: expected %d:%d, got %d:%d", test.line, test.column, test.originalLine, test.originalColumn, mapping.OriginalLine, mapping.OriginalColumn)
+		}
+	}
+}
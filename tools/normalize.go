@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout controls how source urls are turned into paths when extracting sources.
+type Layout int
+
+const (
+	// LayoutClean strips bundler schemes, so webpack://myapp/./src/a.js becomes myapp/src/a.js,
+	// file:///home/ci/app/src/a.js becomes home/ci/app/src/a.js, and https://example.com/a.js becomes example.com/a.js.
+	// Anything inside node_modules is moved to node_modules/..., and rollup/vite virtual modules are moved to _virtual/....
+	LayoutClean Layout = iota
+	// LayoutOrigin is LayoutClean, but every path is prefixed with a directory naming where it came from,
+	// e.g. webpack/myapp/src/a.js, file/home/ci/app/src/a.js, https/example.com/a.js or virtual/commonjsHelpers.js.
+	LayoutOrigin
+	// LayoutRaw uses the source url as is, only removing the parts that would escape the output directory.
+	LayoutRaw
+)
+
+var layoutNames = map[Layout]string{
+	LayoutClean:  "clean",
+	LayoutOrigin: "origin",
+	LayoutRaw:    "raw",
+}
+
+// String returns the name of layout, as accepted by ParseLayout.
+func (layout Layout) String() string {
+	if name, ok := layoutNames[layout]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Layout(%d)", int(layout))
+}
+
+// ParseLayout returns the Layout called name, one of "clean", "origin" or "raw".
+func ParseLayout(name string) (Layout, error) {
+	for layout, layoutName := range layoutNames {
+		if layoutName == name {
+			return layout, nil
+		}
+	}
+
+	return LayoutClean, fmt.Errorf("Error: unknown layout %q, expected one of clean, origin or raw", name)
+}
+
+// originPrefixes maps bundler url prefixes to the origin directory used by LayoutOrigin.
+// Order matters, since webpack-internal:// must be checked before any shorter prefix.
+var originPrefixes = []struct {
+	prefix string
+	origin string
+}{
+	{"webpack-internal://", "webpack"},
+	{"webpack://", "webpack"},
+	{"ng://", "ng"},
+	{"file://", "file"},
+	{"http://", "http"},
+	{"https://", "https"},
+	{"\x00", "virtual"},
+}
+
+// splitOrigin splits url into the origin it came from, and the remaining path.
+// origin is "" for plain relative or absolute paths.
+func splitOrigin(url string) (origin string, path string) {
+	for _, originPrefix := range originPrefixes {
+		if strings.HasPrefix(url, originPrefix.prefix) {
+			return originPrefix.origin, url[len(originPrefix.prefix):]
+		}
+	}
+
+	if match := schemePrefix.FindString(url); len(match) > 2 {
+		// Unknown scheme, e.g. "rollup:" or "vite:". Single letters are drive letters, and are left for SanitizePath.
+		return strings.ToLower(strings.TrimSuffix(match, ":")), strings.TrimLeft(url[len(match):], "/")
+	}
+
+	return "", url
+}
+
+// nodeModulesIndex returns the index of the first node_modules path segment in path, or -1.
+func nodeModulesIndex(path string) int {
+	if strings.HasPrefix(path, "node_modules/") {
+		return 0
+	}
+
+	index := strings.Index(path, "/node_modules/")
+
+	if index == -1 {
+		return -1
+	}
+
+	return index + 1
+}
+
+// NormalizeSourcePath maps the source url to a relative, slash separated path according to layout.
+// Empty and "." segments are removed, but the returned path has not been sanitized,
+// and must still be passed through SanitizePath before use.
+func NormalizeSourcePath(url string, layout Layout) string {
+	if layout == LayoutRaw {
+		return url
+	}
+
+	origin, path := splitOrigin(url)
+	path = strings.ReplaceAll(path, "\\", "/")
+
+	if index := strings.IndexAny(path, "?#"); index != -1 {
+		// Drop loader queries and hashes, e.g. webpack:///./src/App.vue?a1b2
+		path = path[:index]
+	}
+
+	if index := nodeModulesIndex(path); index != -1 {
+		path = path[index:]
+	} else if origin == "virtual" && layout == LayoutClean {
+		path = "_virtual/" + path
+	}
+
+	if layout == LayoutOrigin && origin != "" {
+		path = origin + "/" + path
+	}
+
+	return tidyPath(path, origin == "" && strings.HasPrefix(path, "/"))
+}
+
+// tidyPath drops empty and "." segments from path, keeping the leading slash if absolute is true.
+// ".." segments are kept, so that SanitizePath can report them.
+func tidyPath(path string, absolute bool) string {
+	segments := strings.Split(path, "/")
+	tidied := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		if segment != "" && segment != "." {
+			tidied = append(tidied, segment)
+		}
+	}
+
+	if absolute {
+		return "/" + strings.Join(tidied, "/")
+	}
+
+	return strings.Join(tidied, "/")
+}
@@ -0,0 +1,51 @@
+package tools
+
+import "testing"
+
+func TestNormalizeSourcePath(t *testing.T) {
+	tests := []struct {
+		url    string
+		layout Layout
+		path   string
+	}{
+		{"webpack://myapp/./src/index.js", LayoutClean, "myapp/src/index.js"},
+		{"webpack:///./src/App.vue?a1b2", LayoutClean, "src/App.vue"},
+		{"webpack:///./node_modules/react/index.js", LayoutClean, "node_modules/react/index.js"},
+		{"file:///home/ci/app/node_modules/react/index.js", LayoutClean, "node_modules/react/index.js"},
+		{"file:///home/ci/app/src/a.js", LayoutClean, "home/ci/app/src/a.js"},
+		{"https://example.com/static/a.js", LayoutClean, "example.com/static/a.js"},
+		{"ng://AppModule/AppComponent.html", LayoutClean, "AppModule/AppComponent.html"},
+		{"\x00commonjsHelpers.js", LayoutClean, "_virtual/commonjsHelpers.js"},
+		{"../src/index.js", LayoutClean, "../src/index.js"},
+		{"/etc/passwd", LayoutClean, "/etc/passwd"},
+		{"webpack://myapp/./src/index.js", LayoutOrigin, "webpack/myapp/src/index.js"},
+		{"\x00commonjsHelpers.js", LayoutOrigin, "virtual/commonjsHelpers.js"},
+		{"https://example.com/a.js", LayoutOrigin, "https/example.com/a.js"},
+		{"src/index.js", LayoutOrigin, "src/index.js"},
+		{"webpack://myapp/./src/index.js", LayoutRaw, "webpack://myapp/./src/index.js"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.layout.String()+" "+test.url, func(t *testing.T) {
+			actual := NormalizeSourcePath(test.url, test.layout)
+
+			if actual != test.path {
+				t.Errorf("NormalizeSourcePath(%q, %s) = %q, expected %q", test.url, test.layout, actual, test.path)
+			}
+		})
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	for _, layout := range []Layout{LayoutClean, LayoutOrigin, LayoutRaw} {
+		parsed, err := ParseLayout(layout.String())
+
+		if err != nil || parsed != layout {
+			t.Errorf("ParseLayout(%q) = %v, %v", layout.String(), parsed, err)
+		}
+	}
+
+	if _, err := ParseLayout("bogus"); err == nil {
+		t.Errorf("Expected an error parsing bogus layout")
+	}
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// OutputFormat selects how WriteDecodedSourceMapRecord encodes a source map.
+type OutputFormat int
+
+const (
+	// FormatJSON is the JSON encoding of the DecodedSourceMapRecord, as returned by MarshalDecodedSourceMapRecord.
+	// Every mapping repeats its full original source, including content.
+	FormatJSON OutputFormat = iota
+	// FormatPretty is the indented JSON encoding of the IndexedSourceMapRecord, where mappings reference sources by index
+	FormatPretty
+	// FormatNDJSON is one JSON object per line: an IndexedSource line with "type": "source" for every source,
+	// followed by an IndexedMapping line with "type": "mapping" for every mapping
+	FormatNDJSON
+	// FormatCompact is the single line JSON encoding of the IndexedSourceMapRecord, without source contents
+	FormatCompact
+	// FormatTable is the human readable table written by RenderMappingTable, without any filters or snippets
+	FormatTable
+)
+
+var outputFormatNames = map[OutputFormat]string{
+	FormatJSON:    "json",
+	FormatPretty:  "pretty",
+	FormatNDJSON:  "ndjson",
+	FormatCompact: "compact",
+	FormatTable:   "table",
+}
+
+// String returns the name of format, as accepted by ParseOutputFormat.
+func (format OutputFormat) String() string {
+	if name, ok := outputFormatNames[format]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("OutputFormat(%d)", int(format))
+}
+
+// ParseOutputFormat returns the OutputFormat called name, one of "json", "pretty", "ndjson", "compact" or "table".
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for format, formatName := range outputFormatNames {
+		if formatName == name {
+			return format, nil
+		}
+	}
+
+	return FormatJSON, fmt.Errorf("Error: unknown format %q, expected one of json, pretty, ndjson, compact or table", name)
+}
+
+// IndexedSource is a DecodedSourceRecord together with its position in the source map's sources.
+type IndexedSource struct {
+	Type    string `json:"type,omitempty"`
+	Index   int    `json:"index"`
+	Url     string `json:"url"`
+	Content string `json:"content,omitempty"`
+	Ignored bool   `json:"ignored"`
+}
+
+// IndexedMapping is a DecodedMappingRecord that references its original source by index.
+// Source is -1 for mappings without an original source.
+type IndexedMapping struct {
+	Type            string `json:"type,omitempty"`
+	GeneratedLine   int    `json:"generatedLine"`
+	GeneratedColumn int    `json:"generatedColumn"`
+	Source          int    `json:"source"`
+	OriginalLine    int    `json:"originalLine"`
+	OriginalColumn  int    `json:"originalColumn"`
+	Name            string `json:"name,omitempty"`
+}
+
+// IndexedSourceMapRecord is a DecodedSourceMapRecord whose mappings reference sources by index,
+// so each source is only encoded once.
+type IndexedSourceMapRecord struct {
+	File     string           `json:"file"`
+	Sources  []IndexedSource  `json:"sources"`
+	Mappings []IndexedMapping `json:"mappings"`
+}
+
+// IndexDecodedSourceMapRecord converts mapRecord to an IndexedSourceMapRecord.
+// Source contents are only included if withContent is true.
+func IndexDecodedSourceMapRecord(mapRecord *spec.DecodedSourceMapRecord, withContent bool) *IndexedSourceMapRecord {
+	indexed := &IndexedSourceMapRecord{
+		File:     mapRecord.File,
+		Sources:  indexSources(mapRecord, withContent),
+		Mappings: make([]IndexedMapping, len(mapRecord.Mappings)),
+	}
+
+	sourceIndexes := sourceIndexes(mapRecord)
+
+	for i, mapping := range mapRecord.Mappings {
+		indexed.Mappings[i] = indexMapping(mapping, sourceIndexes)
+	}
+
+	return indexed
+}
+
+// sourceIndexes returns the index of every source of mapRecord.
+func sourceIndexes(mapRecord *spec.DecodedSourceMapRecord) map[*spec.DecodedSourceRecord]int {
+	indexes := make(map[*spec.DecodedSourceRecord]int, len(mapRecord.Sources))
+
+	for index, source := range mapRecord.Sources {
+		indexes[source] = index
+	}
+
+	return indexes
+}
+
+func indexSources(mapRecord *spec.DecodedSourceMapRecord, withContent bool) []IndexedSource {
+	sources := make([]IndexedSource, len(mapRecord.Sources))
+
+	for index, source := range mapRecord.Sources {
+		sources[index] = IndexedSource{Index: index, Url: source.Url, Ignored: source.Ignored}
+
+		if withContent {
+			sources[index].Content = source.Content
+		}
+	}
+
+	return sources
+}
+
+func indexMapping(mapping *spec.DecodedMappingRecord, sourceIndexes map[*spec.DecodedSourceRecord]int) IndexedMapping {
+	indexed := IndexedMapping{
+		GeneratedLine:   mapping.GeneratedLine,
+		GeneratedColumn: mapping.GeneratedColumn,
+		Source:          -1,
+		OriginalLine:    mapping.OriginalLine,
+		OriginalColumn:  mapping.OriginalColumn,
+		Name:            mapping.Name,
+	}
+
+	if index, ok := sourceIndexes[mapping.OriginalSource]; ok && mapping.OriginalSource != nil {
+		indexed.Source = index
+	}
+
+	return indexed
+}
+
+// WriteDecodedSourceMapRecord writes mapRecord to w, encoded according to format, followed by a newline.
+// Use RenderMappingTable directly to filter FormatTable, or show code snippets.
+func WriteDecodedSourceMapRecord(w io.Writer, mapRecord *spec.DecodedSourceMapRecord, format OutputFormat) error {
+	switch format {
+	case FormatPretty:
+		return writeJSON(w, IndexDecodedSourceMapRecord(mapRecord, true), "  ")
+	case FormatCompact:
+		return writeJSON(w, IndexDecodedSourceMapRecord(mapRecord, false), "")
+	case FormatNDJSON:
+		return writeNDJSON(w, mapRecord)
+	case FormatTable:
+		return RenderMappingTable(w, mapRecord, TableOptions{})
+	}
+
+	return writeJSON(w, mapRecord, "")
+}
+
+// writeJSON writes the JSON encoding of value to w, indented with indent if it isn't "".
+func writeJSON(w io.Writer, value any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+
+	err := encoder.Encode(value)
+
+	if err != nil {
+		return fmt.Errorf("Error stringifying mapRecord: %w", err)
+	}
+
+	return nil
+}
+
+// writeNDJSON writes mapRecord as FormatNDJSON, one source or mapping at a time.
+func writeNDJSON(w io.Writer, mapRecord *spec.DecodedSourceMapRecord) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+
+	for _, source := range indexSources(mapRecord, true) {
+		source.Type = "source"
+
+		if err := encoder.Encode(source); err != nil {
+			return fmt.Errorf("Error stringifying source %s: %w", source.Url, err)
+		}
+	}
+
+	sourceIndexes := sourceIndexes(mapRecord)
+
+	for _, mapping := range mapRecord.Mappings {
+		indexed := indexMapping(mapping, sourceIndexes)
+		indexed.Type = "mapping"
+
+		if err := encoder.Encode(indexed); err != nil {
+			return fmt.Errorf("Error stringifying mapping: %w", err)
+		}
+	}
+
+	return buffered.Flush()
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteDecodedSourceMapRecordFormats(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test2.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test2.js.map: %v", err)
+	}
+
+	for _, format := range []OutputFormat{FormatPretty, FormatCompact} {
+		t.Run(format.String(), func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+
+			err := WriteDecodedSourceMapRecord(buffer, mapRecord, format)
+
+			if err != nil {
+				t.Fatalf("Error writing: %v", err)
+			}
+
+			indexed := &IndexedSourceMapRecord{}
+
+			err = json.Unmarshal(buffer.Bytes(), indexed)
+
+			if err != nil {
+				t.Fatalf("Error parsing output: %v", err)
+			}
+
+			if len(indexed.Mappings) != len(mapRecord.Mappings) || len(indexed.Sources) != 1 {
+				t.Fatalf("Expected %d mappings and 1 source, got %d and %d", len(mapRecord.Mappings), len(indexed.Mappings), len(indexed.Sources))
+			}
+
+			if indexed.Mappings[0].Source != 0 {
+				t.Errorf("Expected the first mapping to reference source 0, got %d", indexed.Mappings[0].Source)
+			}
+
+			hasContent := indexed.Sources[0].Content != ""
+
+			if hasContent != (format == FormatPretty) {
+				t.Errorf("Unexpected content in %s output: %v", format, hasContent)
+			}
+
+			lines := strings.Count(strings.TrimSpace(buffer.String()), "\n")
+
+			if (format == FormatCompact) != (lines == 0) {
+				t.Errorf("Unexpected number of lines in %s output: %d", format, lines+1)
+			}
+		})
+	}
+}
+
+func TestWriteDecodedSourceMapRecordNDJSON(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test2.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test2.js.map: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err = WriteDecodedSourceMapRecord(buffer, mapRecord, FormatNDJSON)
+
+	if err != nil {
+		t.Fatalf("Error writing: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+	if len(lines) != 1+len(mapRecord.Mappings) {
+		t.Fatalf("Expected %d lines, got %d", 1+len(mapRecord.Mappings), len(lines))
+	}
+
+	source := IndexedSource{}
+	mapping := IndexedMapping{}
+
+	if err := json.Unmarshal([]byte(lines[0]), &source); err != nil || source.Type != "source" || source.Content == "" {
+		t.Errorf("Unexpected source line %s (%v)", lines[0], err)
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &mapping); err != nil || mapping.Type != "mapping" || mapping.Source != 0 {
+		t.Errorf("Unexpected mapping line %s (%v)", lines[1], err)
+	}
+}
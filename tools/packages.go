@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"cmp"
+	"encoding/json"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// cdnPrefixes are the hosts and paths, without the scheme, of CDNs serving npm packages as name@version/file.
+var cdnPrefixes = []string{"unpkg.com/", "cdn.jsdelivr.net/npm/", "esm.sh/", "cdn.skypack.dev/"}
+
+// yarnCacheZip matches the zip archives of the yarn berry cache, e.g. react-npm-18.2.0-1a2b3c4d5e.zip or
+// @babel-runtime-npm-7.23.2-8f9e0d1c2b.zip
+var yarnCacheZip = regexp.MustCompile(`^(.+?)-npm-(\d.*)-[0-9a-f]{6,}\.zip$`)
+
+// PackageFromPath returns the name and, when the path reveals it, the version of the npm package that the source url or
+// path belongs to, e.g. react, or @babel/runtime and 7.23.2. Bundler schemes such as webpack:// are ignored.
+// Packages are found inside node_modules, where nested packages belong to the innermost one, and in urls of npm CDNs
+// such as unpkg.com/react@18.2.0/index.js. The version is known for pnpm (node_modules/.pnpm/name@version/node_modules/name),
+// the yarn berry cache (.yarn/cache/name-npm-version-hash.zip/node_modules/name) and CDN urls.
+// name is "" if path is not part of a package.
+func PackageFromPath(path string) (name string, version string) {
+	_, name, version = packageRoot(path)
+
+	return name, version
+}
+
+// cleanPackagePath strips the bundler scheme, query and hash of a source url or path, and cleans the rest.
+func cleanPackagePath(sourcePath string) string {
+	_, sourcePath = splitOrigin(strings.ReplaceAll(sourcePath, "\\", "/"))
+
+	if index := strings.IndexAny(sourcePath, "?#"); index != -1 {
+		sourcePath = sourcePath[:index]
+	}
+
+	return strings.Trim(path.Clean("/"+sourcePath), "/")
+}
+
+// packageRoot is PackageFromPath, also returning the directory of the package, e.g. app/node_modules/react,
+// which tells apart copies of a package installed in different places.
+func packageRoot(sourcePath string) (root string, name string, version string) {
+	sourcePath = cleanPackagePath(sourcePath)
+
+	for _, prefix := range cdnPrefixes {
+		if strings.HasPrefix(sourcePath, prefix) {
+			name, version = cdnPackage(sourcePath[len(prefix):])
+			root = prefix + name
+
+			if version != "" {
+				root += "@" + version
+			}
+
+			return root, name, version
+		}
+	}
+
+	index := strings.LastIndex("/"+sourcePath, "/node_modules/")
+
+	if index == -1 {
+		return "", "", ""
+	}
+
+	segments := strings.Split(sourcePath[index+len("node_modules/"):], "/")
+
+	if len(segments) < 2 || segments[0] == ".pnpm" {
+		return "", "", ""
+	}
+
+	name = segments[0]
+
+	if strings.HasPrefix(name, "@") {
+		if len(segments) < 3 {
+			return "", "", ""
+		}
+
+		name += "/" + segments[1]
+	}
+
+	// The directory holding the node_modules of the package is named after its version by pnpm and yarn
+	parents := strings.Split(strings.TrimSuffix(sourcePath[:max(index-1, 0)], "/"), "/")
+	parent := parents[len(parents)-1]
+
+	if len(parents) >= 2 && parents[len(parents)-2] == ".pnpm" {
+		version = pnpmVersion(parent, name)
+	} else if match := yarnCacheZip.FindStringSubmatch(parent); match != nil && match[1] == strings.Replace(name, "/", "-", 1) {
+		version = match[2]
+	}
+
+	return sourcePath[:index] + "node_modules/" + name, name, version
+}
+
+// pnpmVersion returns the version of name in the pnpm store directory dir, e.g. 18.2.0 for react-dom@18.2.0_react@18.2.0,
+// or 7.23.2 for @babel+runtime@7.23.2. Returns "" if dir is not the directory of name.
+func pnpmVersion(dir string, name string) string {
+	prefix := strings.Replace(name, "/", "+", 1) + "@"
+
+	if !strings.HasPrefix(dir, prefix) {
+		return ""
+	}
+
+	version := dir[len(prefix):]
+
+	// Peer dependencies are appended as _peer@version by pnpm 7, and as (peer@version) by pnpm 8 and later
+	if end := strings.IndexAny(version, "_("); end != -1 {
+		version = version[:end]
+	}
+
+	return version
+}
+
+// cdnPackage parses the name@version at the start of the path of a CDN url.
+func cdnPackage(path string) (string, string) {
+	segments := strings.Split(path, "/")
+
+	// esm.sh urls may start with the build version, e.g. esm.sh/v135/react@18.2.0
+	if len(segments) > 1 && len(segments[0]) > 1 && segments[0][0] == 'v' && strings.Trim(segments[0][1:], "0123456789") == "" {
+		segments = segments[1:]
+	}
+
+	nameVersion := segments[0]
+
+	if strings.HasPrefix(nameVersion, "@") && len(segments) > 1 {
+		nameVersion += "/" + segments[1]
+	}
+
+	at := strings.LastIndex(nameVersion, "@")
+
+	if at <= 0 {
+		return nameVersion, ""
+	}
+
+	return nameVersion[:at], nameVersion[at+1:]
+}
+
+// NpmPackage is an npm package whose sources were found in source maps.
+type NpmPackage struct {
+	Name string `json:"name"`
+	// Version is "" if it could not be inferred
+	Version string `json:"version,omitempty"`
+	// Sources are the urls of the sources of the package
+	Sources []string `json:"sources"`
+}
+
+// packageJsonVersion returns the version in the contents of a package.json, or "".
+func packageJsonVersion(contents string) string {
+	manifest := struct {
+		Version string `json:"version"`
+	}{}
+
+	if json.Unmarshal([]byte(contents), &manifest) != nil {
+		return ""
+	}
+
+	return manifest.Version
+}
+
+// DetectPackages groups sources by the npm package they belong to, found with PackageFromPath. Copies of a package
+// installed in different directories are told apart, and packages whose version is not in their path get the version
+// of their package.json, if it is one of the sources and has content. Copies with the same name and version are merged.
+// Packages are sorted by name and version.
+func DetectPackages(sources []*spec.DecodedSourceRecord) []*NpmPackage {
+	packages := make(map[string]*NpmPackage)
+	manifestVersions := make(map[string]string)
+
+	for _, source := range sources {
+		root, name, version := packageRoot(source.Url)
+
+		if name == "" {
+			continue
+		}
+
+		if cleanPackagePath(source.Url) == root+"/package.json" && source.Content != "" {
+			if manifestVersion := packageJsonVersion(source.Content); manifestVersion != "" {
+				manifestVersions[root] = manifestVersion
+			}
+		}
+
+		npmPackage, ok := packages[root]
+
+		if !ok {
+			npmPackage = &NpmPackage{Name: name, Version: version, Sources: make([]string, 0)}
+			packages[root] = npmPackage
+		}
+
+		if !slices.Contains(npmPackage.Sources, source.Url) {
+			npmPackage.Sources = append(npmPackage.Sources, source.Url)
+		}
+	}
+
+	detected := make([]*NpmPackage, 0, len(packages))
+
+	for root, npmPackage := range packages {
+		if npmPackage.Version == "" {
+			npmPackage.Version = manifestVersions[root]
+		}
+
+		detected = append(detected, npmPackage)
+	}
+
+	slices.SortFunc(detected, func(a *NpmPackage, b *NpmPackage) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Version, b.Version))
+	})
+
+	merged := make([]*NpmPackage, 0, len(detected))
+
+	for _, npmPackage := range detected {
+		if last := len(merged) - 1; last >= 0 && merged[last].Name == npmPackage.Name && merged[last].Version == npmPackage.Version {
+			merged[last].Sources = append(merged[last].Sources, npmPackage.Sources...)
+			continue
+		}
+
+		merged = append(merged, npmPackage)
+	}
+
+	for _, npmPackage := range merged {
+		slices.Sort(npmPackage.Sources)
+	}
+
+	return merged
+}
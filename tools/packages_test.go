@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestPackageFromPath(t *testing.T) {
+	tests := []struct {
+		path, name, version string
+	}{
+		{"webpack://app/./node_modules/react/index.js", "react", ""},
+		{"webpack://app/node_modules/react-dom/cjs/react-dom.production.min.js", "react-dom", ""},
+		{"node_modules/@babel/runtime/helpers/extends.js", "@babel/runtime", ""},
+		{"node_modules/a/node_modules/@b/c/index.js", "@b/c", ""},
+		{"../node_modules/.pnpm/react@18.2.0/node_modules/react/index.js", "react", "18.2.0"},
+		{"node_modules/.pnpm/react-dom@18.2.0_react@18.2.0/node_modules/react-dom/index.js", "react-dom", "18.2.0"},
+		{"node_modules/.pnpm/@tanstack+query-core@5.8.1(react@18.2.0)/node_modules/@tanstack/query-core/build/index.js", "@tanstack/query-core", "5.8.1"},
+		// A dependency linked next to the package in the pnpm store doesn't have its version in the path
+		{"node_modules/.pnpm/react-dom@18.2.0/node_modules/scheduler/index.js", "scheduler", ""},
+		{"../.yarn/cache/lodash-npm-4.17.21-6382451519.zip/node_modules/lodash/map.js", "lodash", "4.17.21"},
+		{".yarn/cache/@babel-runtime-npm-7.23.2-8f9e0d1c2b.zip/node_modules/@babel/runtime/index.js", "@babel/runtime", "7.23.2"},
+		{"https://unpkg.com/preact@10.19.2/dist/preact.module.js", "preact", "10.19.2"},
+		{"https://cdn.jsdelivr.net/npm/@floating-ui/dom@1.5.3/dist/floating-ui.dom.mjs", "@floating-ui/dom", "1.5.3"},
+		{"https://esm.sh/v135/htm@3.1.1/es2022/htm.mjs", "htm", "3.1.1"},
+		{"webpack://app/./node_modules/.pnpm/lit@3.0.0/node_modules/lit/index.js?abc", "lit", "3.0.0"},
+		{"webpack://app/./src/node_modules.js", "", ""},
+		{"node_modules/@scope/index.js", "", ""},
+		{"src/index.js", "", ""},
+		{"node_modules/.pnpm/lock.yaml", "", ""},
+	}
+
+	for _, test := range tests {
+		name, version := PackageFromPath(test.path)
+
+		if name != test.name || version != test.version {
+			t.Errorf("%s: expected %q %q, got %q %q", test.path, test.name, test.version, name, version)
+		}
+	}
+}
+
+func TestDetectPackages(t *testing.T) {
+	sources := []*spec.DecodedSourceRecord{
+		{Url: "webpack://app/./src/index.js"},
+		{Url: "webpack://app/./node_modules/react/index.js"},
+		{Url: "webpack://app/./node_modules/react/cjs/react.production.min.js"},
+		{Url: "webpack://app/./node_modules/react/package.json", Content: `{"name": "react", "version": "18.2.0"}`},
+		{Url: "webpack://app/./node_modules/.pnpm/lodash@4.17.21/node_modules/lodash/map.js"},
+		{Url: "webpack://app/./node_modules/.pnpm/lodash@3.10.1/node_modules/lodash/map.js"},
+		{Url: "webpack://app/./node_modules/scheduler/index.js"},
+	}
+
+	expected := []NpmPackage{
+		{Name: "lodash", Version: "3.10.1", Sources: []string{sources[5].Url}},
+		{Name: "lodash", Version: "4.17.21", Sources: []string{sources[4].Url}},
+		{Name: "react", Version: "18.2.0", Sources: []string{sources[2].Url, sources[1].Url, sources[3].Url}},
+		{Name: "scheduler", Sources: []string{sources[6].Url}},
+	}
+
+	packages := DetectPackages(sources)
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d", len(expected), len(packages))
+	}
+
+	for i, npmPackage := range packages {
+		if npmPackage.Name != expected[i].Name || npmPackage.Version != expected[i].Version || !slices.Equal(npmPackage.Sources, expected[i].Sources) {
+			t.Errorf("Package %d: expected %+v, got %+v", i, expected[i], *npmPackage)
+		}
+	}
+}
+
+func TestDetectPackagesNestedCopies(t *testing.T) {
+	sources := []*spec.DecodedSourceRecord{
+		{Url: "webpack://app/./node_modules/react/index.js"},
+		{Url: "webpack://app/./node_modules/react/package.json", Content: `{"version": "18.2.0"}`},
+		{Url: "webpack://app/./node_modules/foo/node_modules/react/index.js"},
+		{Url: "webpack://app/./node_modules/foo/node_modules/react/package.json", Content: `{"version": "17.0.2"}`},
+		{Url: "webpack://app/./node_modules/bar/node_modules/react/index.js"},
+		{Url: "webpack://app/./node_modules/bar/node_modules/react/package.json", Content: `{"version": "18.2.0"}`},
+	}
+
+	packages := DetectPackages(sources)
+	expected := []NpmPackage{
+		{Name: "react", Version: "17.0.2", Sources: []string{sources[2].Url, sources[3].Url}},
+		{Name: "react", Version: "18.2.0", Sources: []string{sources[4].Url, sources[5].Url, sources[0].Url, sources[1].Url}},
+	}
+
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %d", len(expected), len(packages))
+	}
+
+	for i, npmPackage := range packages {
+		if npmPackage.Name != expected[i].Name || npmPackage.Version != expected[i].Version || !slices.Equal(npmPackage.Sources, expected[i].Sources) {
+			t.Errorf("Package %d: expected %+v, got %+v", i, expected[i], *npmPackage)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// ScriptMapResolver finds the source map of a script from its url, for tools such as coverage and profile remapping
+// that only know which scripts ran.
+type ScriptMapResolver struct {
+	// Fetcher downloads scripts and source maps, DefaultFetcher if nil
+	Fetcher *Fetcher
+	// MapDir, if set, is a directory of source maps named after their script, e.g. app.js.map for https://example.com/assets/app.js.
+	// Scripts are not downloaded when MapDir is set.
+	MapDir string
+	// Scripts holds the contents of scripts that are already known, by url, so they don't need to be downloaded
+	Scripts map[string]string
+}
+
+// Resolve returns the source map of the script at scriptUrl, which is an http(s) url, a file:// url or a file path.
+// Without MapDir, the script's sourceMappingURL comment is followed, which may be an inline data: url, and scriptUrl.map
+// is used if the script has no comment. The comment of an http(s) script must resolve to an http(s) or data: url, so a
+// downloaded script can't make the tool read local files.
+func (resolver *ScriptMapResolver) Resolve(scriptUrl string) (*spec.DecodedSourceMapRecord, error) {
+	script, err := url.Parse(scriptUrl)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %w", scriptUrl, err)
+	}
+
+	if resolver.MapDir != "" {
+		mapPath, err := SafeJoin(resolver.MapDir, path.Base(script.Path)+".map")
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := resolver.Read(mapPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return spec.ParseSourceMap(contents, "")
+	}
+
+	contents, ok := resolver.Scripts[scriptUrl]
+
+	if !ok {
+		contents, err = resolver.Read(scriptUrl)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reference := FindSourceMappingUrl(contents)
+
+	if strings.HasPrefix(reference, "data:") {
+		decoded, err := decodeDataUrl(reference)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return spec.ParseSourceMap(decoded, "")
+	}
+
+	if reference == "" {
+		reference = path.Base(script.Path) + ".map"
+	}
+
+	mapUrl, err := script.Parse(reference)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing sourceMappingURL %s: %w", reference, err)
+	}
+
+	if isHttpUrl(scriptUrl) && mapUrl.Scheme != "http" && mapUrl.Scheme != "https" {
+		return nil, fmt.Errorf("Error: sourceMappingURL %s of %s is not an http(s) url, local source maps are only read for local scripts", reference, scriptUrl)
+	}
+
+	contents, err = resolver.Read(mapUrl.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return spec.ParseSourceMap(contents, "")
+}
+
+// Read returns the contents of the http(s) url, file:// url or file path location.
+func (resolver *ScriptMapResolver) Read(location string) (string, error) {
+	if isHttpUrl(location) {
+		fetcher := resolver.Fetcher
+
+		if fetcher == nil {
+			fetcher = DefaultFetcher
+		}
+
+		contents, err := fetcher.Fetch(context.Background(), location)
+
+		return string(contents), err
+	}
+
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme == "file" {
+		location = parsed.Path
+	}
+
+	contents, err := os.ReadFile(location)
+
+	if err != nil {
+		return "", fmt.Errorf("Error reading contents of %s: %w", location, err)
+	}
+
+	return string(contents), nil
+}
+
+// isHttpUrl reports whether location is an http(s) url.
+func isHttpUrl(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
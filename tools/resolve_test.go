@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScriptMapResolver(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/assets/app.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("x();\n//# sourceMappingURL=maps/app.js.map"))
+	})
+	mux.HandleFunc("/assets/maps/app.js.map", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	mux.HandleFunc("/plain.js.map", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(contents)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mapDir := t.TempDir()
+	os.WriteFile(filepath.Join(mapDir, "chunk.js.map"), contents, 0600)
+
+	inline := "y();\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(contents)
+
+	tests := []struct {
+		name     string
+		resolver ScriptMapResolver
+		url      string
+	}{
+		{"sourceMappingURL", ScriptMapResolver{}, server.URL + "/assets/app.js"},
+		{"known script without comment", ScriptMapResolver{Scripts: map[string]string{server.URL + "/plain.js": "z();"}}, server.URL + "/plain.js"},
+		{"inline", ScriptMapResolver{Scripts: map[string]string{"https://example.com/inline.js": inline}}, "https://example.com/inline.js"},
+		{"map dir", ScriptMapResolver{MapDir: mapDir}, "https://example.com/static/chunk.js?v=2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapRecord, err := test.resolver.Resolve(test.url)
+
+			if err != nil {
+				t.Fatalf("Error resolving %s: %v", test.url, err)
+			}
+
+			if len(mapRecord.Sources) != 1 || mapRecord.Sources[0].Url != "tests/fixtures/simple/original.js" {
+				t.Errorf("Unexpected source map %+v", mapRecord.Sources)
+			}
+		})
+	}
+
+	if _, err := (&ScriptMapResolver{}).Resolve(server.URL + "/missing.js"); err == nil {
+		t.Errorf("Expected an error for a missing script")
+	}
+}
+
+func TestScriptMapResolverLocalReferences(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	dir := t.TempDir()
+	localMap := filepath.Join(dir, "local.js.map")
+	os.WriteFile(localMap, contents, 0600)
+
+	tests := []struct {
+		name      string
+		scriptUrl string
+		reference string
+		valid     bool
+	}{
+		{"remote script, file url", "https://example.com/app.js", "file://" + filepath.ToSlash(localMap), false},
+		{"remote script, other scheme", "https://example.com/app.js", "webpack:///app.js.map", false},
+		{"local script, file url", "file://" + filepath.ToSlash(filepath.Join(dir, "app.js")), "file://" + filepath.ToSlash(localMap), true},
+		{"local script, path", filepath.Join(dir, "app.js"), "local.js.map", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolver := ScriptMapResolver{Scripts: map[string]string{test.scriptUrl: "x();\n//# sourceMappingURL=" + test.reference}}
+			_, err := resolver.Resolve(test.scriptUrl)
+
+			if test.valid && err != nil {
+				t.Errorf("Error resolving %s: %v", test.scriptUrl, err)
+			} else if !test.valid && err == nil {
+				t.Errorf("Expected %s from %s to be rejected", test.reference, test.scriptUrl)
+			}
+		})
+	}
+}
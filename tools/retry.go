@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how a Fetcher retries failed downloads.
+// A download is retried on connection errors and resets, and on 408, 429 and 5xx responses other than 501.
+// Delays grow exponentially from BaseDelay with random jitter, unless the response has a Retry-After header.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. 4 if 0
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for every following retry. 500ms if 0
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A download whose server asks for longer with Retry-After is not retried.
+	// 30s if 0
+	MaxDelay time.Duration
+	// Budget caps the total time spent waiting between attempts. DefaultRetryBudget if 0
+	Budget time.Duration
+	// Logger receives a message for every retried attempt, slog.Default() if nil
+	Logger *slog.Logger
+}
+
+// DefaultRetryBudget is the total time a RetryPolicy without a Budget waits between the attempts of a download.
+const DefaultRetryBudget = 2 * time.Minute
+
+func (policy *RetryPolicy) maxAttempts() int {
+	if policy.MaxAttempts <= 0 {
+		return 4
+	}
+
+	return policy.MaxAttempts
+}
+
+func (policy *RetryPolicy) logger() *slog.Logger {
+	if policy.Logger == nil {
+		return slog.Default()
+	}
+
+	return policy.Logger
+}
+
+func (policy *RetryPolicy) maxDelay() time.Duration {
+	if policy.MaxDelay <= 0 {
+		return 30 * time.Second
+	}
+
+	return policy.MaxDelay
+}
+
+func (policy *RetryPolicy) budget() time.Duration {
+	if policy.Budget <= 0 {
+		return DefaultRetryBudget
+	}
+
+	return policy.Budget
+}
+
+// backoff returns the delay before retry number attempt, starting from 1.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	baseDelay := policy.BaseDelay
+
+	if baseDelay <= 0 {
+		baseDelay = 500 * time.Millisecond
+	}
+
+	maxDelay := policy.maxDelay()
+	delay := baseDelay
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, maxDelay)
+
+	// Jitter between half and all of delay, so that many clients failing together don't retry together
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// isRetryable reports whether a download that failed with err may succeed if attempted again.
+// A per attempt timeout is retryable, the caller must check whether its own context is done.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		case http.StatusNotImplemented:
+			return false
+		}
+
+		return statusErr.StatusCode >= 500
+	}
+
+	// Every error of http.Client.Do is a net.Error, but only timeouts among them are transient
+	var netErr net.Error
+
+	return (errors.As(err, &netErr) && netErr.Timeout()) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the response that caused err, if any.
+func retryAfter(err error, now time.Time) (time.Duration, bool) {
+	var statusErr *StatusError
+
+	if !errors.As(err, &statusErr) {
+		return 0, false
+	}
+
+	value := statusErr.Header.Get("Retry-After")
+
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// do calls attempt until it succeeds, fails with an error that is not retryable,
+// or the policy's attempts or budget run out. Returns the error of the last attempt.
+func (policy *RetryPolicy) do(ctx context.Context, url string, attempt func() ([]byte, error)) ([]byte, error) {
+	var waited time.Duration
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		contents, err := attempt()
+
+		if err == nil || ctx.Err() != nil || !isRetryable(err) || attemptNumber >= policy.maxAttempts() {
+			return contents, err
+		}
+
+		delay, ok := retryAfter(err, time.Now())
+
+		if !ok {
+			delay = policy.backoff(attemptNumber)
+		} else if delay > policy.maxDelay() {
+			policy.logger().Warn("Retry-After exceeds the maximum delay", "url", url, "attempt", attemptNumber, "retryAfter", delay, "error", err)
+			return contents, err
+		}
+
+		if waited+delay > policy.budget() {
+			policy.logger().Warn("Retry budget exhausted", "url", url, "attempt", attemptNumber, "waited", waited, "error", err)
+			return contents, err
+		}
+
+		policy.logger().Warn("Retrying download", "url", url, "attempt", attemptNumber, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		waited += delay
+	}
+}
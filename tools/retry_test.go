@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// flappingServer fails the first failures requests with status, then succeeds.
+func flappingServer(failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	requests := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+
+	return server, requests
+}
+
+func TestRetryFlappingServer(t *testing.T) {
+	server, requests := flappingServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{BaseDelay: time.Millisecond, Logger: quietLogger}}
+
+	contents, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err != nil || string(contents) != "ok" {
+		t.Fatalf("Expected ok, got %q (%v)", contents, err)
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	server, requests := flappingServer(10, http.StatusBadGateway, nil)
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Logger: quietLogger}}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	var statusErr *StatusError
+
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a 502 StatusError, got %v", err)
+	}
+
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	server, requests := flappingServer(10, http.StatusNotFound, nil)
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{BaseDelay: time.Millisecond, Logger: quietLogger}}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err == nil || requests.Load() != 1 {
+		t.Errorf("Expected a single failed request, got %d requests (%v)", requests.Load(), err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	server, requests := flappingServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{BaseDelay: time.Millisecond, Logger: quietLogger}}
+
+	start := time.Now()
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected Retry-After to delay the retry by 1s, took %v", elapsed)
+	}
+
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestRetryAfterExceedsMaxDelay(t *testing.T) {
+	server, requests := flappingServer(10, http.StatusTooManyRequests, http.Header{"Retry-After": {"86400"}})
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{Logger: quietLogger}}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err == nil || requests.Load() != 1 {
+		t.Errorf("Expected a Retry-After over MaxDelay to stop retries, got %d requests (%v)", requests.Load(), err)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	server, requests := flappingServer(10, http.StatusTooManyRequests, http.Header{"Retry-After": {"2"}})
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{Budget: time.Second, Logger: quietLogger}}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err == nil || requests.Load() != 1 {
+		t.Errorf("Expected the budget to stop retries, got %d requests (%v)", requests.Load(), err)
+	}
+}
+
+func TestRetryNetworkErrors(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}}, false},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("tls: failed to verify certificate: x509: certificate signed by unknown authority")}, false},
+		{&url.Error{Op: "Get", URL: "ftp://example.com", Err: errors.New("unsupported protocol scheme \"ftp\"")}, false},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("stopped after 10 redirects")}, false},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: syscall.ECONNREFUSED}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: io.ErrUnexpectedEOF}, true},
+	}
+
+	for _, test := range tests {
+		if retryable := isRetryable(test.err); retryable != test.retryable {
+			t.Errorf("%v: expected retryable %v, got %v", test.err, test.retryable, retryable)
+		}
+	}
+}
+
+func TestRetryConnectionReset(t *testing.T) {
+	requests := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			connection, _, err := w.(http.Hijacker).Hijack()
+
+			if err == nil {
+				connection.Close()
+			}
+
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: &RetryPolicy{BaseDelay: time.Millisecond, Logger: quietLogger}}
+
+	contents, err := fetcher.Fetch(context.Background(), server.URL)
+
+	if err != nil || string(contents) != "ok" {
+		t.Errorf("Expected ok after a reset connection, got %q (%v)", contents, err)
+	}
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// FlattenIndexMap decodes the sections of indexMap into a single source map, whose mappings are shifted to the offset of
+// their section. Sources with the same url and content in several sections are merged into one.
+// Returns an error if the sections are not ordered, or a section is itself an index map.
+func FlattenIndexMap(indexMap *spec.IndexSourceMap) (*spec.SourceMap, error) {
+	sections := indexMap.Sections
+
+	if sections == nil {
+		sections = make([]spec.Section, 0)
+	}
+
+	decoded, err := spec.DecodeIndexSourceMap(&spec.SourceMap{Version: indexMap.Version, File: indexMap.File, Sections: sections}, "")
+
+	if err != nil {
+		return nil, fmt.Errorf("Error flattening index map: %w", err)
+	}
+
+	flattened := &spec.DecodedSourceMapRecord{File: decoded.File, Mappings: make([]*spec.DecodedMappingRecord, 0)}
+	newSourceMerger().add(flattened, decoded, concatOffset{})
+
+	return spec.EncodeSourceMap(flattened), nil
+}
+
+// SplitOptions controls how SplitSourceMap divides a source map into sections.
+type SplitOptions struct {
+	// Lines is the number of generated lines of each section
+	Lines int
+	// BySource starts a section wherever the original source of the mappings changes, instead of every Lines lines.
+	// Mappings without a source stay in the section before them.
+	BySource bool
+}
+
+// SplitSourceMap divides mapRecord into an index map, with sections of options.Lines generated lines or one section per run
+// of mappings from the same original source. Each section only lists the sources it uses, and sections without mappings
+// are left out.
+// Returns an error if options.Lines is not positive when options.BySource is false.
+func SplitSourceMap(mapRecord *spec.DecodedSourceMapRecord, options SplitOptions) (*spec.IndexSourceMap, error) {
+	if !options.BySource && options.Lines <= 0 {
+		return nil, fmt.Errorf("Error splitting source map: lines per section must be positive, got %d", options.Lines)
+	}
+
+	mappings := slices.Clone(mapRecord.Mappings)
+	slices.SortStableFunc(mappings, func(a *spec.DecodedMappingRecord, b *spec.DecodedMappingRecord) int {
+		if a.GeneratedLine != b.GeneratedLine {
+			return a.GeneratedLine - b.GeneratedLine
+		}
+
+		return a.GeneratedColumn - b.GeneratedColumn
+	})
+
+	indexMap := &spec.IndexSourceMap{Version: 3, File: mapRecord.File, Sections: make([]spec.Section, 0)}
+
+	var section *spec.DecodedSourceMapRecord
+	var offset spec.SectionOffset
+	var source *spec.DecodedSourceRecord
+
+	flush := func() {
+		if section != nil {
+			section.Sources = usedSources(mapRecord.Sources, section.Mappings)
+			indexMap.Sections = append(indexMap.Sections, spec.Section{Offset: offset, Map: spec.EncodeSourceMap(section)})
+		}
+	}
+
+	for _, mapping := range mappings {
+		if options.BySource {
+			position := spec.SectionOffset{Line: mapping.GeneratedLine, Column: mapping.GeneratedColumn}
+			changed := mapping.OriginalSource != nil && mapping.OriginalSource != source
+
+			// Sections can't share an offset, so mappings at the start of the section stay in it
+			if section == nil || (changed && position != offset) {
+				flush()
+				section = &spec.DecodedSourceMapRecord{}
+				offset = position
+			}
+
+			if mapping.OriginalSource != nil {
+				source = mapping.OriginalSource
+			}
+		} else if start := mapping.GeneratedLine - mapping.GeneratedLine%options.Lines; section == nil || start != offset.Line {
+			flush()
+			section = &spec.DecodedSourceMapRecord{}
+			offset = spec.SectionOffset{Line: start}
+		}
+
+		shifted := *mapping
+		shifted.GeneratedLine -= offset.Line
+
+		if shifted.GeneratedLine == 0 {
+			shifted.GeneratedColumn -= offset.Column
+		}
+
+		section.Mappings = append(section.Mappings, &shifted)
+	}
+
+	flush()
+
+	return indexMap, nil
+}
+
+// usedSources returns the sources that mappings refer to, in the order of sources.
+func usedSources(sources []*spec.DecodedSourceRecord, mappings []*spec.DecodedMappingRecord) []*spec.DecodedSourceRecord {
+	used := make(map[*spec.DecodedSourceRecord]bool)
+
+	for _, mapping := range mappings {
+		if mapping.OriginalSource != nil {
+			used[mapping.OriginalSource] = true
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(sources), func(source *spec.DecodedSourceRecord) bool {
+		return !used[source]
+	})
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"os"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestFlattenIndexMap(t *testing.T) {
+	_, indexMap := ConcatIndexMap(concatTestInputs(t), ";")
+
+	flattened, err := FlattenIndexMap(indexMap)
+
+	if err != nil {
+		t.Fatalf("Error flattening index map: %v", err)
+	}
+
+	_, concatenated := Concat(concatTestInputs(t), ";")
+	expected := spec.EncodeSourceMap(concatenated)
+
+	if flattened.Mappings != expected.Mappings {
+		t.Errorf("Expected mappings %s, got %s", expected.Mappings, flattened.Mappings)
+	}
+
+	if len(flattened.Sources) != len(expected.Sources) {
+		t.Errorf("Expected sources %v, got %v", expected.Sources, flattened.Sources)
+	}
+
+	_, err = FlattenIndexMap(&spec.IndexSourceMap{Version: 3, Sections: []spec.Section{
+		{Offset: spec.SectionOffset{Line: 2}, Map: expected},
+		{Offset: spec.SectionOffset{Line: 1}, Map: expected},
+	}})
+
+	if err == nil {
+		t.Errorf("Expected an error for unordered sections")
+	}
+}
+
+func TestSplitSourceMap(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test2.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test2.js.map: %v", err)
+	}
+
+	mapRecord, err := spec.ParseSourceMap(string(contents), "")
+
+	if err != nil {
+		t.Fatalf("Error parsing test2.js.map: %v", err)
+	}
+
+	original := spec.EncodeSourceMap(mapRecord)
+
+	tests := []struct {
+		name     string
+		options  SplitOptions
+		sections int
+	}{
+		{"every line", SplitOptions{Lines: 1}, 4},
+		{"every 4 lines", SplitOptions{Lines: 4}, 2},
+		{"whole map", SplitOptions{Lines: 100}, 1},
+		{"by source", SplitOptions{BySource: true}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexMap, err := SplitSourceMap(mapRecord, test.options)
+
+			if err != nil {
+				t.Fatalf("Error splitting: %v", err)
+			}
+
+			if len(indexMap.Sections) != test.sections {
+				t.Errorf("Expected %d sections, got %d", test.sections, len(indexMap.Sections))
+			}
+
+			flattened, err := FlattenIndexMap(indexMap)
+
+			if err != nil {
+				t.Fatalf("Error flattening: %v", err)
+			}
+
+			if flattened.Mappings != original.Mappings {
+				t.Errorf("Expected mappings %s, got %s", original.Mappings, flattened.Mappings)
+			}
+		})
+	}
+
+	if _, err := SplitSourceMap(mapRecord, SplitOptions{}); err == nil {
+		t.Errorf("Expected an error without lines per section")
+	}
+}
+
+func TestSplitSourceMapBySource(t *testing.T) {
+	_, concatenated := Concat(concatTestInputs(t), ";")
+
+	indexMap, err := SplitSourceMap(concatenated, SplitOptions{BySource: true})
+
+	if err != nil {
+		t.Fatalf("Error splitting: %v", err)
+	}
+
+	expected := []struct {
+		offset  spec.SectionOffset
+		sources []string
+	}{
+		{spec.SectionOffset{Line: 0, Column: 0}, []string{"a.js"}},
+		{spec.SectionOffset{Line: 1, Column: 19}, []string{"b.js"}},
+		{spec.SectionOffset{Line: 1, Column: 21}, []string{"a.js"}},
+		{spec.SectionOffset{Line: 1, Column: 23}, []string{"plain.js"}},
+	}
+
+	if len(indexMap.Sections) != len(expected) {
+		t.Fatalf("Expected %d sections, got %d", len(expected), len(indexMap.Sections))
+	}
+
+	for i, section := range indexMap.Sections {
+		if section.Offset != expected[i].offset || len(section.Map.Sources) != 1 || section.Map.Sources[0] != expected[i].sources[0] {
+			t.Errorf("Section %d: expected %v %v, got %v %v", i, expected[i].offset, expected[i].sources, section.Offset, section.Map.Sources)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"fmt"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// shiftMapping returns a copy of mapping moved down by lines, and right by columns if it is on the first generated line.
+func shiftMapping(mapping *spec.DecodedMappingRecord, lines int, columns int) *spec.DecodedMappingRecord {
+	shifted := *mapping
+
+	if shifted.GeneratedLine == 0 {
+		shifted.GeneratedColumn += columns
+	}
+
+	shifted.GeneratedLine += lines
+
+	return &shifted
+}
+
+// ShiftSourceMap returns a copy of mapRecord for its generated file inserted at the 0-based generated line and column,
+// such as after a banner. Mappings on the first generated line are moved right by column, and all mappings down by line.
+// It is the inverse of slicing a map at that position.
+func ShiftSourceMap(mapRecord *spec.DecodedSourceMapRecord, line int, column int) *spec.DecodedSourceMapRecord {
+	shifted := &spec.DecodedSourceMapRecord{
+		File:     mapRecord.File,
+		Sources:  mapRecord.Sources,
+		Mappings: make([]*spec.DecodedMappingRecord, len(mapRecord.Mappings)),
+		DebugId:  mapRecord.DebugId,
+	}
+
+	for i, mapping := range mapRecord.Mappings {
+		shifted.Mappings[i] = shiftMapping(mapping, line, column)
+	}
+
+	return shifted
+}
+
+// SliceSourceMap returns the part of mapRecord for the 0-based generated lines from up to, but excluding, to,
+// rebased so that line from becomes line 0. Sources that no mapping of the slice uses are pruned, and so are names,
+// since they are only kept by the mappings that use them.
+func SliceSourceMap(mapRecord *spec.DecodedSourceMapRecord, from int, to int) *spec.DecodedSourceMapRecord {
+	sliced := &spec.DecodedSourceMapRecord{File: mapRecord.File, Mappings: make([]*spec.DecodedMappingRecord, 0)}
+
+	for _, mapping := range mapRecord.Mappings {
+		if mapping.GeneratedLine >= from && mapping.GeneratedLine < to {
+			sliced.Mappings = append(sliced.Mappings, shiftMapping(mapping, -from, 0))
+		}
+	}
+
+	sliced.Sources = usedSources(mapRecord.Sources, sliced.Mappings)
+
+	return sliced
+}
+
+// SliceSourceMapBytes returns the bytes of generated from start up to, but excluding, end, with the part of mapRecord for them,
+// rebased so that start becomes line 0, column 0. If no mapping starts exactly at start, the mapping covering it is
+// moved there, so the beginning of the slice stays mapped. Unused sources are pruned like SliceSourceMap.
+// Returns an error if start and end are not a valid range of generated.
+func SliceSourceMapBytes(mapRecord *spec.DecodedSourceMapRecord, generated string, start int, end int) (string, *spec.DecodedSourceMapRecord, error) {
+	if start < 0 || end > len(generated) || start > end {
+		return "", nil, fmt.Errorf("Error slicing source map: invalid range %d-%d of %d bytes", start, end, len(generated))
+	}
+
+	startPosition := advance(concatOffset{}, generated[:start])
+	endPosition := advance(concatOffset{}, generated[:end])
+	before := func(mapping *spec.DecodedMappingRecord, position concatOffset) bool {
+		return mapping.GeneratedLine < position.line || (mapping.GeneratedLine == position.line && mapping.GeneratedColumn < position.column)
+	}
+
+	sliced := &spec.DecodedSourceMapRecord{File: mapRecord.File, Mappings: make([]*spec.DecodedMappingRecord, 0)}
+
+	if covering := OriginalPositionFor(mapRecord, startPosition.line, startPosition.column); covering != nil && covering.GeneratedColumn < startPosition.column && start < end {
+		moved := *covering
+		moved.GeneratedLine = 0
+		moved.GeneratedColumn = 0
+		sliced.Mappings = append(sliced.Mappings, &moved)
+	}
+
+	for _, mapping := range mapRecord.Mappings {
+		if before(mapping, startPosition) || !before(mapping, endPosition) {
+			continue
+		}
+
+		rebased := shiftMapping(mapping, -startPosition.line, 0)
+
+		if rebased.GeneratedLine == 0 {
+			rebased.GeneratedColumn -= startPosition.column
+		}
+
+		sliced.Mappings = append(sliced.Mappings, rebased)
+	}
+
+	sliced.Sources = usedSources(mapRecord.Sources, sliced.Mappings)
+
+	return generated[start:end], sliced, nil
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func sourceUrls(mapRecord *spec.DecodedSourceMapRecord) []string {
+	urls := make([]string, len(mapRecord.Sources))
+
+	for i, source := range mapRecord.Sources {
+		urls[i] = source.Url
+	}
+
+	return urls
+}
+
+func equalMappings(a []*spec.DecodedMappingRecord, b []*spec.DecodedMappingRecord) bool {
+	return slices.EqualFunc(a, b, func(x *spec.DecodedMappingRecord, y *spec.DecodedMappingRecord) bool {
+		return *x == *y
+	})
+}
+
+func TestSliceSourceMap(t *testing.T) {
+	_, mapRecord := Concat(concatTestInputs(t), ";")
+
+	tests := []struct {
+		name     string
+		from, to int
+		sources  []string
+		names    []string
+	}{
+		{"first line", 0, 1, []string{"a.js"}, []string{"foo"}},
+		{"middle line", 1, 2, []string{"a.js", "b.js", "plain.js"}, []string{"foo"}},
+		{"last line", 2, 3, []string{"plain.js"}, []string{}},
+		{"past the end", 5, 10, []string{}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sliced := SliceSourceMap(mapRecord, test.from, test.to)
+
+			if urls := sourceUrls(sliced); !slices.Equal(urls, test.sources) {
+				t.Errorf("Expected sources %v, got %v", test.sources, urls)
+			}
+
+			if names := spec.EncodeSourceMap(sliced).Names; !slices.Equal(names, test.names) {
+				t.Errorf("Expected names %v, got %v", test.names, names)
+			}
+
+			for _, mapping := range sliced.Mappings {
+				if mapping.GeneratedLine != 0 {
+					t.Errorf("Expected every mapping to be rebased to line 0, got %d", mapping.GeneratedLine)
+				}
+			}
+
+			// Shifting the slice back restores the mappings of its lines
+			expected := slices.DeleteFunc(slices.Clone(mapRecord.Mappings), func(mapping *spec.DecodedMappingRecord) bool {
+				return mapping.GeneratedLine < test.from || mapping.GeneratedLine >= test.to
+			})
+
+			if restored := ShiftSourceMap(sliced, test.from, 0); !equalMappings(restored.Mappings, expected) {
+				t.Errorf("Expected shifting the slice back to restore its mappings")
+			}
+		})
+	}
+}
+
+func TestSliceSourceMapBytes(t *testing.T) {
+	generated, mapRecord := Concat(concatTestInputs(t), ";")
+
+	tests := []struct {
+		name       string
+		start, end int
+		text       string
+		positions  [][2]int
+		sources    []string
+	}{
+		{"whole input", 27, 30, "x;y", [][2]int{{0, 0}, {0, 2}}, []string{"a.js", "b.js"}},
+		{"inside a mapping", 28, 30, ";y", [][2]int{{0, 0}, {0, 1}}, []string{"a.js", "b.js"}},
+		{"across lines", 31, 40, "plain\ncod", [][2]int{{0, 0}, {1, 0}}, []string{"plain.js"}},
+		{"empty", 27, 27, "", [][2]int{}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, sliced, err := SliceSourceMapBytes(mapRecord, generated, test.start, test.end)
+
+			if err != nil {
+				t.Fatalf("Error slicing: %v", err)
+			}
+
+			if text != test.text {
+				t.Errorf("Expected text %q, got %q", test.text, text)
+			}
+
+			positions := make([][2]int, len(sliced.Mappings))
+
+			for i, mapping := range sliced.Mappings {
+				positions[i] = [2]int{mapping.GeneratedLine, mapping.GeneratedColumn}
+			}
+
+			if !slices.Equal(positions, test.positions) {
+				t.Errorf("Expected positions %v, got %v", test.positions, positions)
+			}
+
+			if urls := sourceUrls(sliced); !slices.Equal(urls, test.sources) {
+				t.Errorf("Expected sources %v, got %v", test.sources, urls)
+			}
+		})
+	}
+
+	if _, _, err := SliceSourceMapBytes(mapRecord, generated, 10, 5); err == nil {
+		t.Errorf("Expected an error for an invalid range")
+	}
+}
+
+func TestShiftSourceMap(t *testing.T) {
+	_, mapRecord := Concat(concatTestInputs(t), ";")
+	shifted := ShiftSourceMap(mapRecord, 2, 5)
+
+	for i, mapping := range shifted.Mappings {
+		original := mapRecord.Mappings[i]
+		column := original.GeneratedColumn
+
+		if original.GeneratedLine == 0 {
+			column += 5
+		}
+
+		if mapping.GeneratedLine != original.GeneratedLine+2 || mapping.GeneratedColumn != column || mapping.OriginalSource != original.OriginalSource {
+			t.Errorf("Mapping %d: expected %d:%d, got %d:%d", i, original.GeneratedLine+2, column, mapping.GeneratedLine, mapping.GeneratedColumn)
+		}
+	}
+
+	if mapRecord.Mappings[0].GeneratedLine != 0 {
+		t.Errorf("Expected the original map to be unchanged")
+	}
+}
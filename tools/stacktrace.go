@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// StackFrame is one frame of a JavaScript stack trace.
+// Line and Column are 1-based, as printed by browsers.
+type StackFrame struct {
+	// Function is the function name, "" for anonymous functions and top level code
+	Function string `json:"function,omitempty"`
+	// Url is the script the frame is in
+	Url    string `json:"url"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Raw is the line of the stack trace the frame was parsed from
+	Raw string `json:"raw,omitempty"`
+}
+
+var (
+	// v8Frame matches Chrome and Node.js frames, e.g. "    at render (https://example.com/app.js:1:2345)"
+	v8Frame = regexp.MustCompile(`^\s*at (?:(?:async )?(.+?) \()?(.+):(\d+):(\d+)\)?$`)
+	// geckoFrame matches Firefox and Safari frames, e.g. "render@https://example.com/app.js:1:2345"
+	geckoFrame = regexp.MustCompile(`^\s*(.*?)@(.+):(\d+):(\d+)$`)
+)
+
+// ParseStackTrace parses the frames of a V8 (Chrome, Node.js), SpiderMonkey (Firefox) or JavaScriptCore (Safari) stack trace.
+// Lines that are not frames, such as the error message, are skipped.
+func ParseStackTrace(trace string) []StackFrame {
+	frames := make([]StackFrame, 0)
+
+	for _, line := range SplitLines(trace) {
+		match := v8Frame.FindStringSubmatch(line)
+
+		if match == nil {
+			match = geckoFrame.FindStringSubmatch(line)
+		}
+
+		if match == nil {
+			continue
+		}
+
+		frameLine, _ := strconv.Atoi(match[3])
+		frameColumn, _ := strconv.Atoi(match[4])
+
+		frames = append(frames, StackFrame{
+			Function: match[1],
+			Url:      match[2],
+			Line:     frameLine,
+			Column:   frameColumn,
+			Raw:      strings.TrimSpace(line),
+		})
+	}
+
+	return frames
+}
+
+// String formats frame as a V8 stack trace frame.
+func (frame StackFrame) String() string {
+	if frame.Function == "" {
+		return fmt.Sprintf("    at %s:%d:%d", frame.Url, frame.Line, frame.Column)
+	}
+
+	return fmt.Sprintf("    at %s (%s:%d:%d)", frame.Function, frame.Url, frame.Line, frame.Column)
+}
+
+// SymbolicatedFrame is a StackFrame together with its original position.
+type SymbolicatedFrame struct {
+	// Generated is the frame as it was in the stack trace
+	Generated StackFrame `json:"generated"`
+	// Original is the frame in the original source, nil if it could not be symbolicated
+	Original *StackFrame `json:"original,omitempty"`
+	// Error is why the frame could not be symbolicated, if it wasn't
+	Error string `json:"error,omitempty"`
+}
+
+// MapResolver returns the source map of the script at url, or nil if it has none.
+type MapResolver func(url string) (*spec.DecodedSourceMapRecord, error)
+
+// SymbolicateStackTrace maps every frame to its original source, function name and position, using the source maps
+// returned by resolve. resolve is called once for each distinct url.
+func SymbolicateStackTrace(frames []StackFrame, resolve MapResolver) []SymbolicatedFrame {
+	symbolicated := make([]SymbolicatedFrame, len(frames))
+	mapRecords := make(map[string]*spec.DecodedSourceMapRecord)
+	mapErrors := make(map[string]error)
+
+	for i, frame := range frames {
+		symbolicated[i].Generated = frame
+
+		mapRecord, ok := mapRecords[frame.Url]
+		err := mapErrors[frame.Url]
+
+		if !ok && err == nil {
+			mapRecord, err = resolve(frame.Url)
+			mapRecords[frame.Url] = mapRecord
+			mapErrors[frame.Url] = err
+		}
+
+		if err != nil {
+			symbolicated[i].Error = err.Error()
+			continue
+		}
+
+		if mapRecord == nil {
+			symbolicated[i].Error = fmt.Sprintf("Error: no source map for %s", frame.Url)
+			continue
+		}
+
+		mapping := OriginalPositionFor(mapRecord, frame.Line-1, frame.Column-1)
+
+		if mapping == nil || mapping.OriginalSource == nil {
+			symbolicated[i].Error = fmt.Sprintf("Error: no mapping for %s:%d:%d", frame.Url, frame.Line, frame.Column)
+			continue
+		}
+
+		function := mapping.Name
+
+		if function == "" {
+			function = frame.Function
+		}
+
+		symbolicated[i].Original = &StackFrame{
+			Function: function,
+			Url:      mapping.OriginalSource.Url,
+			Line:     mapping.OriginalLine + 1,
+			Column:   mapping.OriginalColumn + 1,
+		}
+	}
+
+	return symbolicated
+}
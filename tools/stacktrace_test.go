@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestParseStackTrace(t *testing.T) {
+	trace := `TypeError: x is undefined
+    at a (https://example.com/app.js:1:10)
+    at https://example.com/app.js:1:30
+    at async b (https://example.com/vendor.js:2:5)
+c@https://example.com/app.js:1:1
+@https://example.com/app.js:1:2`
+
+	expected := []StackFrame{
+		{Function: "a", Url: "https://example.com/app.js", Line: 1, Column: 10},
+		{Url: "https://example.com/app.js", Line: 1, Column: 30},
+		{Function: "b", Url: "https://example.com/vendor.js", Line: 2, Column: 5},
+		{Function: "c", Url: "https://example.com/app.js", Line: 1, Column: 1},
+		{Url: "https://example.com/app.js", Line: 1, Column: 2},
+	}
+
+	frames := ParseStackTrace(trace)
+
+	if len(frames) != len(expected) {
+		t.Fatalf("Expected %d frames, got %d: %v", len(expected), len(frames), frames)
+	}
+
+	for i, frame := range frames {
+		frame.Raw = ""
+
+		if frame != expected[i] {
+			t.Errorf("Frame %d: expected %v, got %v", i, expected[i], frame)
+		}
+	}
+}
+
+func TestSymbolicateStackTrace(t *testing.T) {
+	contents, err := os.ReadFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error reading test1.js.map: %v", err)
+	}
+
+	mapRecord, err := spec.ParseSourceMap(string(contents), "")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	resolved := 0
+	frames := SymbolicateStackTrace(ParseStackTrace(`    at x (https://example.com/app.js:1:10)
+    at https://example.com/app.js:1:1
+    at https://example.com/missing.js:1:1`), func(url string) (*spec.DecodedSourceMapRecord, error) {
+		resolved++
+
+		if url == "https://example.com/app.js" {
+			return mapRecord, nil
+		}
+
+		return nil, errors.New("no source map")
+	})
+
+	if resolved != 2 {
+		t.Errorf("Expected each url to be resolved once, got %d", resolved)
+	}
+
+	original := frames[0].Original
+
+	if original == nil || original.Function != "abcd" || original.Line != 2 || original.Column != 10 || original.Url != "tests/fixtures/simple/original.js" {
+		t.Errorf("Unexpected first frame %+v", original)
+	}
+
+	if frames[1].Original == nil || frames[1].Original.Line != 2 || frames[1].Original.Column != 1 {
+		t.Errorf("Unexpected second frame %+v", frames[1].Original)
+	}
+
+	if frames[2].Original != nil || frames[2].Error == "" {
+		t.Errorf("Expected the third frame to fail, got %+v", frames[2])
+	}
+}
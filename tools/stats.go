@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// StatsOptions gives SourceMapStats what a DecodedSourceMapRecord doesn't hold.
+type StatsOptions struct {
+	// Generated is the contents of the generated file, needed for Coverage
+	Generated string
+	// Names is the names array of the source map, needed for UnusedNames, since decoding drops names that no mapping uses
+	Names []string
+}
+
+// CoverageStats measures how much of the generated file is mapped to an original source.
+// Whitespace is not counted, since minifiers rarely map it.
+type CoverageStats struct {
+	// Columns is the number of non-whitespace UTF-16 columns of the generated file
+	Columns int `json:"columns"`
+	// MappedColumns is how many of Columns are covered by a mapping with an original source
+	MappedColumns int `json:"mappedColumns"`
+	// Percent is MappedColumns as a percentage of Columns
+	Percent float64 `json:"percent"`
+	// UnmappedRanges is the number of runs of columns on a line that no mapping covers
+	UnmappedRanges int `json:"unmappedRanges"`
+}
+
+// SourceMapStats are metrics of the quality of a source map, as returned by Stats.
+type SourceMapStats struct {
+	Mappings int `json:"mappings"`
+	// MappedLines is the number of generated lines with at least one mapping
+	MappedLines int `json:"mappedLines"`
+	// SegmentsPerLine is the mean number of mappings of MappedLines
+	SegmentsPerLine float64 `json:"segmentsPerLine"`
+	// MaxSegmentsPerLine is the number of mappings of the generated line with the most
+	MaxSegmentsPerLine int `json:"maxSegmentsPerLine"`
+	// Coverage is only set if StatsOptions.Generated was given
+	Coverage *CoverageStats `json:"coverage,omitempty"`
+
+	Sources int `json:"sources"`
+	// SourcesWithoutContent are the urls of sources without sourcesContent
+	SourcesWithoutContent []string `json:"sourcesWithoutContent"`
+	// UnusedSources are the urls of sources that no mapping refers to
+	UnusedSources []string `json:"unusedSources"`
+	// IgnoredSources is the number of sources in the ignoreList
+	IgnoredSources int `json:"ignoredSources"`
+	// IgnoredSourcesPercent is IgnoredSources as a percentage of Sources
+	IgnoredSourcesPercent float64 `json:"ignoredSourcesPercent"`
+	// IgnoredMappingsPercent is the percentage of mappings to an ignored source
+	IgnoredMappingsPercent float64 `json:"ignoredMappingsPercent"`
+
+	// Names is the number of different names used by mappings
+	Names int `json:"names"`
+	// UnusedNames are the names no mapping uses, only set if StatsOptions.Names was given
+	UnusedNames []string `json:"unusedNames"`
+}
+
+// percent returns part as a percentage of whole, 0 if whole is 0.
+func percent(part int, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+
+	return float64(part) * 100 / float64(whole)
+}
+
+// Stats computes metrics of mapRecord that reveal degraded source maps, such as missing sources or unmapped code.
+func Stats(mapRecord *spec.DecodedSourceMapRecord, options StatsOptions) *SourceMapStats {
+	stats := &SourceMapStats{
+		Mappings:              len(mapRecord.Mappings),
+		Sources:               len(mapRecord.Sources),
+		SourcesWithoutContent: make([]string, 0),
+		UnusedSources:         make([]string, 0),
+		UnusedNames:           make([]string, 0),
+	}
+
+	segments := make(map[int]int)
+	usedSources := make(map[*spec.DecodedSourceRecord]bool)
+	names := usedNames(mapRecord)
+	ignoredMappings := 0
+
+	for _, mapping := range mapRecord.Mappings {
+		segments[mapping.GeneratedLine]++
+
+		if mapping.OriginalSource != nil {
+			usedSources[mapping.OriginalSource] = true
+
+			if mapping.OriginalSource.Ignored {
+				ignoredMappings++
+			}
+		}
+	}
+
+	for _, count := range segments {
+		stats.MaxSegmentsPerLine = max(stats.MaxSegmentsPerLine, count)
+	}
+
+	stats.MappedLines = len(segments)
+
+	if stats.MappedLines > 0 {
+		stats.SegmentsPerLine = float64(stats.Mappings) / float64(stats.MappedLines)
+	}
+
+	for _, source := range mapRecord.Sources {
+		if source.Content == "" {
+			stats.SourcesWithoutContent = append(stats.SourcesWithoutContent, source.Url)
+		}
+
+		if !usedSources[source] {
+			stats.UnusedSources = append(stats.UnusedSources, source.Url)
+		}
+
+		if source.Ignored {
+			stats.IgnoredSources++
+		}
+	}
+
+	stats.IgnoredSourcesPercent = percent(stats.IgnoredSources, stats.Sources)
+	stats.IgnoredMappingsPercent = percent(ignoredMappings, stats.Mappings)
+	stats.Names = len(names)
+
+	seenNames := make(map[string]bool)
+
+	for _, name := range options.Names {
+		if !names[name] && !seenNames[name] {
+			stats.UnusedNames = append(stats.UnusedNames, name)
+		}
+
+		seenNames[name] = true
+	}
+
+	if options.Generated != "" {
+		stats.Coverage = generatedCoverage(mapRecord, options.Generated)
+	}
+
+	return stats
+}
+
+// generatedCoverage measures which non-whitespace columns of generated are covered by a mapping of mapRecord to an
+// original source. A mapping covers its line up to the next mapping.
+func generatedCoverage(mapRecord *spec.DecodedSourceMapRecord, generated string) *CoverageStats {
+	mappings := slices.Clone(mapRecord.Mappings)
+	slices.SortStableFunc(mappings, func(a *spec.DecodedMappingRecord, b *spec.DecodedMappingRecord) int {
+		if a.GeneratedLine != b.GeneratedLine {
+			return a.GeneratedLine - b.GeneratedLine
+		}
+
+		return a.GeneratedColumn - b.GeneratedColumn
+	})
+
+	coverage := &CoverageStats{}
+	next := 0
+
+	for lineIndex, line := range SplitLines(generated) {
+		for next < len(mappings) && mappings[next].GeneratedLine < lineIndex {
+			next++
+		}
+
+		var current *spec.DecodedMappingRecord
+		column := 0
+		inUnmapped := false
+
+		for _, r := range line {
+			for next < len(mappings) && mappings[next].GeneratedLine == lineIndex && mappings[next].GeneratedColumn <= column {
+				current = mappings[next]
+				next++
+			}
+
+			column += utf16.RuneLen(r)
+
+			if unicode.IsSpace(r) {
+				continue
+			}
+
+			coverage.Columns += utf16.RuneLen(r)
+
+			if current != nil && current.OriginalSource != nil {
+				coverage.MappedColumns += utf16.RuneLen(r)
+				inUnmapped = false
+			} else if !inUnmapped {
+				coverage.UnmappedRanges++
+				inUnmapped = true
+			}
+		}
+	}
+
+	coverage.Percent = percent(coverage.MappedColumns, coverage.Columns)
+
+	return coverage
+}
+
+// WriteText writes stats to w in a readable form.
+func (stats *SourceMapStats) WriteText(w io.Writer) error {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "Mappings: %d on %d lines, %.1f per line, at most %d\n", stats.Mappings, stats.MappedLines, stats.SegmentsPerLine, stats.MaxSegmentsPerLine)
+
+	if stats.Coverage != nil {
+		fmt.Fprintf(&out, "Coverage: %.1f%% of %d generated columns mapped, %d unmapped ranges\n",
+			stats.Coverage.Percent, stats.Coverage.Columns, stats.Coverage.UnmappedRanges)
+	}
+
+	fmt.Fprintf(&out, "Sources: %d, %d without content, %d unused, %d ignored (%.1f%%, %.1f%% of mappings)\n",
+		stats.Sources, len(stats.SourcesWithoutContent), len(stats.UnusedSources), stats.IgnoredSources,
+		stats.IgnoredSourcesPercent, stats.IgnoredMappingsPercent)
+	fmt.Fprintf(&out, "Names: %d used, %d unused\n", stats.Names, len(stats.UnusedNames))
+
+	_, err := io.WriteString(w, out.String())
+
+	if err != nil {
+		return fmt.Errorf("Error writing stats: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+func TestStats(t *testing.T) {
+	mapRecord, err := spec.ParseSourceMap(`{"version":3,"sources":["a.js","b.js","vendor.js"],"sourcesContent":["a"],"names":["foo","unused","foo"],
+		"ignoreList":[2],"mappings":"AAAA,IAAIA,E;AEAA,C;;AFAA"}`, "")
+
+	if err != nil {
+		t.Fatalf("Error parsing source map: %v", err)
+	}
+
+	stats := Stats(mapRecord, StatsOptions{
+		Generated: "var foo=1\nx = y\n\n  a b",
+		Names:     []string{"foo", "unused", "foo"},
+	})
+
+	if stats.Mappings != 6 || stats.MappedLines != 3 || stats.SegmentsPerLine != 2 || stats.MaxSegmentsPerLine != 3 {
+		t.Errorf("Unexpected mapping stats %+v", stats)
+	}
+
+	if stats.Coverage == nil {
+		t.Fatalf("Expected coverage with a generated file")
+	}
+
+	// "var fo", "x", "a" and "b" are mapped, and the segments without a source leave "o=1" and "= y" unmapped
+	expected := CoverageStats{Columns: 13, MappedColumns: 8, UnmappedRanges: 2}
+	expected.Percent = percent(8, 13)
+
+	if *stats.Coverage != expected {
+		t.Errorf("Expected coverage %+v, got %+v", expected, *stats.Coverage)
+	}
+
+	if !slices.Equal(stats.SourcesWithoutContent, []string{"b.js", "vendor.js"}) {
+		t.Errorf("Expected b.js and vendor.js without content, got %v", stats.SourcesWithoutContent)
+	}
+
+	if !slices.Equal(stats.UnusedSources, []string{"b.js"}) {
+		t.Errorf("Expected b.js to be unused, got %v", stats.UnusedSources)
+	}
+
+	if stats.IgnoredSources != 1 || stats.IgnoredMappingsPercent != percent(1, 6) {
+		t.Errorf("Expected 1 ignored source with 1 mapping, got %d and %.1f%%", stats.IgnoredSources, stats.IgnoredMappingsPercent)
+	}
+
+	if stats.Names != 1 || !slices.Equal(stats.UnusedNames, []string{"unused"}) {
+		t.Errorf("Expected foo to be used and unused not, got %d and %v", stats.Names, stats.UnusedNames)
+	}
+
+	var out strings.Builder
+
+	if err := stats.WriteText(&out); err != nil {
+		t.Fatalf("Error writing stats: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Coverage: 61.5% of 13 generated columns mapped, 2 unmapped ranges") {
+		t.Errorf("Unexpected text %s", out.String())
+	}
+
+	if stats := Stats(mapRecord, StatsOptions{}); stats.Coverage != nil {
+		t.Errorf("Expected no coverage without a generated file")
+	}
+}
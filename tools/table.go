@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// maxSnippetLength is the most characters of code shown for each side of a mapping by RenderMappingTable.
+const maxSnippetLength = 30
+
+// TableOptions filters and decorates the table written by RenderMappingTable.
+// Lines are 1-based and columns 0-based, as shown in the table.
+type TableOptions struct {
+	// Generated is the contents of the generated file, used for Snippets
+	Generated string
+	// Snippets adds the generated and original code of each mapping to the table.
+	// The generated code is only shown if Generated is set, and the original code if the source has content.
+	Snippets bool
+	// Source only shows mappings whose original source url contains Source
+	Source string
+	// FromLine only shows mappings on or after this generated line, if > 0
+	FromLine int
+	// ToLine only shows mappings on or before this generated line, if > 0
+	ToLine int
+	// Name only shows mappings with exactly this name
+	Name string
+}
+
+// matches reports whether mapping passes the filters in options.
+func (options *TableOptions) matches(mapping *spec.DecodedMappingRecord) bool {
+	line := mapping.GeneratedLine + 1
+
+	if options.FromLine > 0 && line < options.FromLine {
+		return false
+	}
+
+	if options.ToLine > 0 && line > options.ToLine {
+		return false
+	}
+
+	if options.Name != "" && mapping.Name != options.Name {
+		return false
+	}
+
+	if options.Source != "" && (mapping.OriginalSource == nil || !strings.Contains(mapping.OriginalSource.Url, options.Source)) {
+		return false
+	}
+
+	return true
+}
+
+// RenderMappingTable writes a table of the mappings of mapRecord to w, one mapping per row, in the form
+//
+//	generated line:column -> source:line:column name
+//
+// optionally filtered, and with the generated and original code of each mapping, according to options.
+func RenderMappingTable(w io.Writer, mapRecord *spec.DecodedSourceMapRecord, options TableOptions) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	header := "GENERATED\t\tORIGINAL\tNAME"
+
+	if options.Snippets {
+		header += "\tGENERATED CODE\tORIGINAL CODE"
+	}
+
+	fmt.Fprintln(table, header)
+
+	generatedLines := SplitLines(options.Generated)
+	originalLines := make(map[*spec.DecodedSourceRecord][]string)
+
+	for i, mapping := range mapRecord.Mappings {
+		if !options.matches(mapping) {
+			continue
+		}
+
+		original := "-"
+
+		if mapping.OriginalSource != nil {
+			original = fmt.Sprintf("%s:%d:%d", mapping.OriginalSource.Url, mapping.OriginalLine+1, mapping.OriginalColumn)
+		}
+
+		row := fmt.Sprintf("%d:%d\t->\t%s\t%s", mapping.GeneratedLine+1, mapping.GeneratedColumn, original, mapping.Name)
+
+		if options.Snippets {
+			generated := generatedSnippet(mapRecord.Mappings, i, generatedLines)
+			row += fmt.Sprintf("\t%s\t%s", quoteSnippet(generated), quoteSnippet(originalSnippet(mapping, originalLines, UTF16Length(generated))))
+		}
+
+		fmt.Fprintln(table, row)
+	}
+
+	return table.Flush()
+}
+
+// generatedSnippet returns the generated code covered by mappings[index], up to the next mapping on the same line.
+func generatedSnippet(mappings []*spec.DecodedMappingRecord, index int, generatedLines []string) string {
+	mapping := mappings[index]
+
+	if mapping.GeneratedLine >= len(generatedLines) {
+		return ""
+	}
+
+	end := -1
+
+	if index+1 < len(mappings) && mappings[index+1].GeneratedLine == mapping.GeneratedLine {
+		end = mappings[index+1].GeneratedColumn
+	}
+
+	return truncateSnippet(SliceColumns(generatedLines[mapping.GeneratedLine], mapping.GeneratedColumn, end))
+}
+
+// originalSnippet returns the original code at mapping, length UTF-16 units long, or up to the end of the line if length is 0.
+// originalLines caches the split content of each source.
+func originalSnippet(mapping *spec.DecodedMappingRecord, originalLines map[*spec.DecodedSourceRecord][]string, length int) string {
+	if mapping.OriginalSource == nil || mapping.OriginalSource.Content == "" {
+		return ""
+	}
+
+	lines, ok := originalLines[mapping.OriginalSource]
+
+	if !ok {
+		lines = SplitLines(mapping.OriginalSource.Content)
+		originalLines[mapping.OriginalSource] = lines
+	}
+
+	if mapping.OriginalLine >= len(lines) {
+		return ""
+	}
+
+	end := -1
+
+	if length > 0 {
+		end = mapping.OriginalColumn + length
+	}
+
+	return truncateSnippet(SliceColumns(lines[mapping.OriginalLine], mapping.OriginalColumn, end))
+}
+
+// truncateSnippet shortens snippet to maxSnippetLength characters.
+func truncateSnippet(snippet string) string {
+	runes := []rune(snippet)
+
+	if len(runes) > maxSnippetLength {
+		return string(runes[:maxSnippetLength-1]) + "…"
+	}
+
+	return snippet
+}
+
+// quoteSnippet quotes snippet so that whitespace and tabs don't break the table.
+func quoteSnippet(snippet string) string {
+	if snippet == "" {
+		return "-"
+	}
+
+	return fmt.Sprintf("%q", snippet)
+}
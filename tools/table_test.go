@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderMappingTable(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err = RenderMappingTable(buffer, mapRecord, TableOptions{
+		Generated: "function abcd(){}export default abcd;",
+		Snippets:  true,
+	})
+
+	if err != nil {
+		t.Fatalf("Error rendering table: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and 3 mappings, got %q", buffer.String())
+	}
+
+	if !strings.HasPrefix(lines[2], "1:9 ") || !strings.Contains(lines[2], "original.js:2:9") || !strings.Contains(lines[2], "abcd") {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+
+	if !strings.Contains(lines[2], `"abcd(){}export defau"`) || !strings.Contains(lines[2], `"abcd() {}"`) {
+		t.Errorf("Expected generated and original snippets in %q", lines[2])
+	}
+
+	buffer.Reset()
+
+	err = RenderMappingTable(buffer, mapRecord, TableOptions{Name: "abcd", FromLine: 1, ToLine: 1})
+
+	if err != nil {
+		t.Fatalf("Error rendering table: %v", err)
+	}
+
+	if rows := strings.Count(strings.TrimSpace(buffer.String()), "\n"); rows != 2 {
+		t.Errorf("Expected 2 mappings named abcd, got %q", buffer.String())
+	}
+
+	buffer.Reset()
+	RenderMappingTable(buffer, mapRecord, TableOptions{Source: "does-not-exist"})
+
+	if rows := strings.Count(strings.TrimSpace(buffer.String()), "\n"); rows != 0 {
+		t.Errorf("Expected no mappings, got %q", buffer.String())
+	}
+}
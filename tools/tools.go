@@ -3,37 +3,21 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
-	"strings"
 
 	"github.com/redawl/go-sourcemap/spec"
 )
 
 // Parse functions
 
-// ParseSourceMapFromUrl parses a source map file located at url.
-// Returns an error if url is unreachable, returns a status != 200, or url is not a valid source map file.
+// ParseSourceMapFromUrl parses a source map file located at url, using DefaultFetcher.
+// Returns an error if url is unreachable, returns a status other than 2xx, or url is not a valid source map file.
 func ParseSourceMapFromUrl(url string) (*spec.DecodedSourceMapRecord, error) {
-	response, err := http.Get(url)
-
-	if err != nil {
-		return nil, err
-	}
-	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("Error retrieving %s: %s", url, response.Status)
-	}
-
-	contents, err := io.ReadAll(response.Body)
-
-	if err != nil {
-		return nil, fmt.Errorf("Error reading response body: %w", err)
-	}
-
-	return spec.ParseSourceMap(string(contents), "")
+	return DefaultFetcher.ParseSourceMap(context.Background(), url)
 }
 
 // ParseSourceMapFromFile parses a source map file.
@@ -48,37 +32,26 @@ func ParseSourceMapFromFile(filename string) (*spec.DecodedSourceMapRecord, erro
 	return spec.ParseSourceMap(string(contents), filename)
 }
 
-// SaveSourcesToDirectory saves mapRecord.Sources to dir.
-// If dir doesn't exist, it is recursively created with 0700 permissions.
-// Files are saved with 0600 permissions.
-func SaveSourcesToDirectory(mapRecord *spec.DecodedSourceMapRecord, dir string) error {
-	err := os.MkdirAll(dir, 0700)
+// ParseSourceMapFromReader parses a source map read from r, such as os.Stdin.
+// Returns an error if r cannot be read, or the contents are not a valid source map file.
+func ParseSourceMapFromReader(r io.Reader, baseURL string) (*spec.DecodedSourceMapRecord, error) {
+	contents, err := io.ReadAll(r)
 
 	if err != nil {
-		return fmt.Errorf("Error creating %s: %w", dir, err)
+		return nil, fmt.Errorf("Error reading source map: %w", err)
 	}
 
-	for _, source := range mapRecord.Sources {
-		index := strings.LastIndexByte(source.Url, os.PathSeparator)
-
-		if index != -1 {
-			basePath := dir + "/" + source.Url[:index]
-			fullPath := basePath + source.Url[index:]
-			err = os.MkdirAll(basePath, 0700)
-
-			if err != nil {
-				return fmt.Errorf("Error creating %s: %w", dir+source.Url[:index], err)
-			}
-
-			err := os.WriteFile(fullPath, []byte(source.Content), 0600)
+	return spec.ParseSourceMap(string(contents), baseURL)
+}
 
-			if err != nil {
-				return fmt.Errorf("Error writing file contents to %s: %w", fullPath, err)
-			}
-		}
-	}
+// SaveSourcesToDirectory saves mapRecord.Sources to dir.
+// If dir doesn't exist, it is recursively created with 0700 permissions.
+// Files are saved with 0600 permissions.
+// Source urls are normalized with LayoutClean and confined to dir, see ExtractSourcesToDirectory for details.
+func SaveSourcesToDirectory(mapRecord *spec.DecodedSourceMapRecord, dir string) error {
+	_, err := ExtractSourcesToDirectory(mapRecord, dir, ExtractOptions{})
 
-	return nil
+	return err
 }
 
 // MarshalDecodedSourceMapRecord returns the JSON encoding of mapRecord
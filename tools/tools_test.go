@@ -3,6 +3,7 @@ package tools
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 )
 
@@ -36,3 +37,22 @@ func TestParseSourceMapFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSourceMapFromReader(t *testing.T) {
+	for _, testFile := range testFiles {
+		t.Run(testFile, func(t *testing.T) {
+			file, err := os.Open("../testdata/" + testFile)
+
+			if err != nil {
+				t.Fatalf("Error opening %s: %v", testFile, err)
+			}
+			defer file.Close()
+
+			_, err = ParseSourceMapFromReader(file, "")
+
+			if err != nil {
+				t.Errorf("Error parsing %s: %v", testFile, err)
+			}
+		})
+	}
+}
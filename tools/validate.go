@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// ValidationReport lists the problems found in a source map by ValidateSourceMap.
+type ValidationReport struct {
+	// Valid is false if the source map has any Errors
+	Valid bool `json:"valid"`
+	// Errors are problems that make the source map, or some of its mappings, unusable
+	Errors []string `json:"errors"`
+	// Warnings are problems that consumers usually tolerate
+	Warnings []string `json:"warnings"`
+	// Sources is the number of sources
+	Sources int `json:"sources"`
+	// Mappings is the number of decoded mappings
+	Mappings int `json:"mappings"`
+}
+
+func (report *ValidationReport) errorf(format string, args ...any) {
+	report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+}
+
+func (report *ValidationReport) warnf(format string, args ...any) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// maxValidationProblems is the most problems reported for each kind of invalid segment, so a broken map doesn't produce a huge report.
+const maxValidationProblems = 10
+
+// ValidateSourceMap checks that contents is a well formed source map, and that every mapping references an existing
+// source and name. Unlike spec.ParseSourceMap, which logs and skips invalid segments, every problem is reported.
+func ValidateSourceMap(contents string) *ValidationReport {
+	report := &ValidationReport{Errors: make([]string, 0), Warnings: make([]string, 0)}
+
+	sourceMap, err := spec.ParseJSON(contents)
+
+	if err != nil {
+		report.errorf("Error parsing JSON: %v", err)
+		return report
+	}
+
+	if sourceMap.Version != 3 {
+		report.errorf("Error: version is %d, expected 3", sourceMap.Version)
+	}
+
+	if sourceMap.Sections != nil {
+		validateSections(report, sourceMap)
+	} else {
+		validateMap(report, sourceMap)
+	}
+
+	report.Valid = len(report.Errors) == 0
+
+	return report
+}
+
+// validateSections validates each section of an index map, checking that the sections are ordered like
+// spec.DecodeIndexSourceMap requires. Problems in a section are reported with the section index.
+func validateSections(report *ValidationReport, sourceMap *spec.SourceMap) {
+	if sourceMap.Mappings != "" || len(sourceMap.Sources) > 0 {
+		report.warnf("Warning: index map has its own sources or mappings, which are ignored")
+	}
+
+	previous := spec.SectionOffset{Line: -1, Column: -1}
+
+	for index, section := range sourceMap.Sections {
+		offset := section.Offset
+
+		if offset.Line < 0 || offset.Column < 0 {
+			report.errorf("Error: section %d has negative offset %d:%d", index, offset.Line, offset.Column)
+		} else if offset.Line < previous.Line || (offset.Line == previous.Line && offset.Column <= previous.Column) {
+			report.errorf("Error: section %d offset %d:%d is not after the previous section", index, offset.Line, offset.Column)
+		}
+
+		previous = offset
+
+		if section.Map == nil {
+			report.errorf("Error: section %d has no map", index)
+			continue
+		}
+
+		if section.Map.Sections != nil {
+			report.errorf("Error: section %d is an index map, which is not allowed", index)
+			continue
+		}
+
+		sectionReport := &ValidationReport{}
+
+		if section.Map.Version != 3 {
+			sectionReport.errorf("Error: version is %d, expected 3", section.Map.Version)
+		}
+
+		validateMap(sectionReport, section.Map)
+
+		for _, problem := range sectionReport.Errors {
+			report.errorf("%s (section %d)", problem, index)
+		}
+
+		for _, problem := range sectionReport.Warnings {
+			report.warnf("%s (section %d)", problem, index)
+		}
+
+		report.Sources += sectionReport.Sources
+		report.Mappings += sectionReport.Mappings
+	}
+}
+
+// validateMap validates the sources and mappings of a source map that is not an index map.
+func validateMap(report *ValidationReport, sourceMap *spec.SourceMap) {
+	if len(sourceMap.SourcesContent) > len(sourceMap.Sources) {
+		report.errorf("Error: sourcesContent has %d entries, but there are only %d sources", len(sourceMap.SourcesContent), len(sourceMap.Sources))
+	} else if len(sourceMap.SourcesContent) > 0 && len(sourceMap.SourcesContent) < len(sourceMap.Sources) {
+		report.warnf("Warning: sourcesContent has %d entries for %d sources", len(sourceMap.SourcesContent), len(sourceMap.Sources))
+	}
+
+	for _, index := range sourceMap.IgnoreList {
+		if index < 0 || index >= len(sourceMap.Sources) {
+			report.errorf("Error: ignoreList references source %d, but there are %d sources", index, len(sourceMap.Sources))
+		}
+	}
+
+	for index, source := range sourceMap.Sources {
+		if source == "" {
+			report.warnf("Warning: source %d has no url", index)
+		}
+	}
+
+	report.Sources = len(sourceMap.Sources)
+
+	err := spec.ValidateBase64VLQGroupings(sourceMap.Mappings)
+
+	if err != nil {
+		report.errorf("Error: mappings contains characters that are not base64 VLQ")
+		return
+	}
+
+	validateMappings(report, sourceMap)
+}
+
+// validateMappings walks the segments of sourceMap.Mappings, reporting segments that spec.DecodeMappings would reject or skip.
+func validateMappings(report *ValidationReport, sourceMap *spec.SourceMap) {
+	problems := make(map[string]int)
+	problem := func(kind string, format string, args ...any) {
+		problems[kind]++
+
+		if problems[kind] <= maxValidationProblems {
+			report.errorf(format, args...)
+		}
+	}
+
+	sourceIndex, originalLine, originalColumn, nameIndex := 0, 0, 0, 0
+
+	for line, group := range strings.Split(sourceMap.Mappings, ";") {
+		if group == "" {
+			continue
+		}
+
+		generatedColumn := 0
+
+		for _, segment := range strings.Split(group, ",") {
+			fields := make([]int, 0, 5)
+			position := 0
+
+			for position < len(segment) {
+				value, err := spec.DecodeBase64VLQ(segment, &position)
+
+				if err != nil {
+					problem("vlq", "Error: invalid segment %q on generated line %d: %v", segment, line+1, err)
+					break
+				}
+
+				if value == math.MaxInt {
+					break
+				}
+
+				fields = append(fields, value)
+			}
+
+			if len(fields) != 1 && len(fields) != 4 && len(fields) != 5 {
+				problem("fields", "Error: segment %q on generated line %d has %d fields, expected 1, 4 or 5", segment, line+1, len(fields))
+				continue
+			}
+
+			report.Mappings++
+			generatedColumn += fields[0]
+
+			if generatedColumn < 0 {
+				problem("column", "Error: negative generated column on generated line %d", line+1)
+			}
+
+			if len(fields) == 1 {
+				continue
+			}
+
+			sourceIndex += fields[1]
+			originalLine += fields[2]
+			originalColumn += fields[3]
+
+			if sourceIndex < 0 || sourceIndex >= len(sourceMap.Sources) {
+				problem("source", "Error: generated line %d references source %d, but there are %d sources", line+1, sourceIndex, len(sourceMap.Sources))
+			}
+
+			if originalLine < 0 || originalColumn < 0 {
+				problem("original", "Error: negative original position %d:%d on generated line %d", originalLine, originalColumn, line+1)
+			}
+
+			if len(fields) == 5 {
+				nameIndex += fields[4]
+
+				if nameIndex < 0 || nameIndex >= len(sourceMap.Names) {
+					problem("name", "Error: generated line %d references name %d, but there are %d names", line+1, nameIndex, len(sourceMap.Names))
+				}
+			}
+		}
+	}
+
+	for _, kind := range slices.Sorted(maps.Keys(problems)) {
+		if problems[kind] > maxValidationProblems {
+			report.errorf("Error: %d more %s problems were not reported", problems[kind]-maxValidationProblems, kind)
+		}
+	}
+}
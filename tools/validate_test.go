@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateSourceMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		valid    bool
+		problem  string
+	}{
+		{"valid", `{"version": 3, "sources": ["a.js"], "names": ["x"], "mappings": "AAAAA,CAAC;AACA"}`, true, ""},
+		{"not json", `{`, false, "Error parsing JSON"},
+		{"version", `{"version": 2, "sources": [], "mappings": ""}`, false, "version is 2"},
+		{"source out of range", `{"version": 3, "sources": ["a.js"], "mappings": "ACAA"}`, false, "references source 1"},
+		{"name out of range", `{"version": 3, "sources": ["a.js"], "names": [], "mappings": "AAAAA"}`, false, "references name 0"},
+		{"fields", `{"version": 3, "sources": ["a.js"], "mappings": "AA"}`, false, "has 2 fields"},
+		{"invalid chars", `{"version": 3, "sources": [], "mappings": "A!"}`, false, "not base64 VLQ"},
+		{"sourcesContent", `{"version": 3, "sources": ["a.js"], "sourcesContent": ["", ""], "mappings": ""}`, false, "sourcesContent has 2 entries"},
+		{"ignoreList", `{"version": 3, "sources": ["a.js"], "ignoreList": [3], "mappings": ""}`, false, "ignoreList references source 3"},
+		{"index map", `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "mappings": "AAAA"}}]}`, true, ""},
+		{"section source out of range", `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "mappings": "ACAA"}}]}`, false, "references source 1, but there are 1 sources (section 0)"},
+		{"section order", `{"version": 3, "sections": [{"offset": {"line": 1, "column": 0}, "map": {"version": 3, "sources": [], "mappings": ""}}, {"offset": {"line": 0, "column": 5}, "map": {"version": 3, "sources": [], "mappings": ""}}]}`, false, "section 1 offset 0:5 is not after the previous section"},
+		{"section without map", `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}}]}`, false, "section 0 has no map"},
+		{"nested index map", `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sections": []}}]}`, false, "section 0 is an index map"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := ValidateSourceMap(test.contents)
+
+			if report.Valid != test.valid {
+				t.Errorf("Expected valid to be %v, got %v: %v", test.valid, report.Valid, report.Errors)
+			}
+
+			if test.problem != "" && !strings.Contains(strings.Join(report.Errors, "\n"), test.problem) {
+				t.Errorf("Expected an error containing %q, got %v", test.problem, report.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateIndexMapCounts(t *testing.T) {
+	report := ValidateSourceMap(`{"version": 3, "sections": [
+		{"offset": {"line": 0, "column": 0}, "map": {"version": 3, "sources": ["a.js"], "mappings": "AAAA,CAAC"}},
+		{"offset": {"line": 2, "column": 0}, "map": {"version": 3, "sources": ["b.js", "c.js"], "mappings": "AAAA"}}
+	]}`)
+
+	if !report.Valid || report.Sources != 3 || report.Mappings != 3 {
+		t.Errorf("Expected a valid report with 3 sources and 3 mappings, got %+v", report)
+	}
+}
+
+func TestValidateSourceMapTestdata(t *testing.T) {
+	for _, testFile := range testFiles {
+		contents, err := os.ReadFile("../testdata/" + testFile)
+
+		if err != nil {
+			t.Fatalf("Error reading %s: %v", testFile, err)
+		}
+
+		if report := ValidateSourceMap(string(contents)); !report.Valid {
+			t.Errorf("Expected %s to be valid, got %v", testFile, report.Errors)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/spec"
+)
+
+// visualColors is the number of highlight colors, .c0 to .c7 in visualizationTemplate.
+const visualColors = 8
+
+// visualSpan is a run of code in the visualization. Spans of the generated file and of the original sources that belong
+// to the same mapping share an Id, which is -1 for code that isn't mapped.
+type visualSpan struct {
+	Text  string
+	Id    int
+	Color int
+	Title string
+}
+
+type visualSource struct {
+	Index      int
+	Url        string
+	Ignored    bool
+	HasContent bool
+	Lines      [][]visualSpan
+}
+
+// visualTreeNode is a directory, or with Source >= 0, a source in the sidebar.
+type visualTreeNode struct {
+	Name     string
+	Source   int
+	Ignored  bool
+	Children []*visualTreeNode
+}
+
+type visualization struct {
+	File      string
+	Mappings  int
+	Generated [][]visualSpan
+	Sources   []visualSource
+	Tree      []*visualTreeNode
+}
+
+// originalPosition identifies the code a mapping points to, shared by every mapping to the same position.
+type originalPosition struct {
+	source *spec.DecodedSourceRecord
+	line   int
+	column int
+}
+
+// RenderVisualization writes a self-contained HTML page to w, showing generated, the contents of the file mapRecord was
+// generated into, side by side with the original sources. Every mapping is colored, and hovering over either side
+// highlights its counterpart. A sidebar lists the sources as a tree.
+func RenderVisualization(w io.Writer, mapRecord *spec.DecodedSourceMapRecord, generated string) error {
+	positions := make(map[originalPosition]int)
+	columns := make(map[*spec.DecodedSourceRecord]map[int][]int)
+
+	for _, mapping := range mapRecord.Mappings {
+		if mapping.OriginalSource == nil {
+			continue
+		}
+
+		position := originalPosition{mapping.OriginalSource, mapping.OriginalLine, mapping.OriginalColumn}
+
+		if _, ok := positions[position]; ok {
+			continue
+		}
+
+		positions[position] = len(positions)
+
+		if columns[mapping.OriginalSource] == nil {
+			columns[mapping.OriginalSource] = make(map[int][]int)
+		}
+
+		columns[mapping.OriginalSource][mapping.OriginalLine] = append(columns[mapping.OriginalSource][mapping.OriginalLine], mapping.OriginalColumn)
+	}
+
+	data := visualization{
+		File:      mapRecord.File,
+		Mappings:  len(mapRecord.Mappings),
+		Generated: visualGeneratedLines(mapRecord.Mappings, generated, positions),
+		Sources:   make([]visualSource, len(mapRecord.Sources)),
+	}
+
+	for index, source := range mapRecord.Sources {
+		data.Sources[index] = visualSource{
+			Index:      index,
+			Url:        source.Url,
+			Ignored:    source.Ignored,
+			HasContent: source.Content != "",
+			Lines:      visualOriginalLines(source, columns[source], positions),
+		}
+	}
+
+	data.Tree = visualSourceTree(mapRecord.Sources)
+
+	err := visualizationTemplate.Execute(w, data)
+
+	if err != nil {
+		return fmt.Errorf("Error rendering visualization: %w", err)
+	}
+
+	return nil
+}
+
+// visualGeneratedLines splits generated into spans, one per mapping, up to the next mapping on the same line.
+func visualGeneratedLines(mappings []*spec.DecodedMappingRecord, generated string, positions map[originalPosition]int) [][]visualSpan {
+	lines := SplitLines(generated)
+	spans := make([][]visualSpan, len(lines))
+	next := 0
+
+	for lineIndex, line := range lines {
+		for next < len(mappings) && mappings[next].GeneratedLine < lineIndex {
+			next++
+		}
+
+		column := 0
+
+		for ; next < len(mappings) && mappings[next].GeneratedLine == lineIndex; next++ {
+			mapping := mappings[next]
+			end := -1
+
+			if next+1 < len(mappings) && mappings[next+1].GeneratedLine == lineIndex {
+				end = mappings[next+1].GeneratedColumn
+			}
+
+			if mapping.GeneratedColumn > column {
+				spans[lineIndex] = appendSpan(spans[lineIndex], visualSpan{Text: SliceColumns(line, column, mapping.GeneratedColumn), Id: -1})
+			}
+
+			span := visualSpan{Text: SliceColumns(line, mapping.GeneratedColumn, end), Id: -1}
+
+			if mapping.OriginalSource != nil {
+				span.Id = positions[originalPosition{mapping.OriginalSource, mapping.OriginalLine, mapping.OriginalColumn}]
+				span.Color = span.Id % visualColors
+				span.Title = fmt.Sprintf("%d:%d -> %s:%d:%d %s", lineIndex+1, mapping.GeneratedColumn, mapping.OriginalSource.Url,
+					mapping.OriginalLine+1, mapping.OriginalColumn, mapping.Name)
+			}
+
+			spans[lineIndex] = appendSpan(spans[lineIndex], span)
+
+			if end < 0 {
+				column = UTF16Length(line)
+			} else {
+				column = max(mapping.GeneratedColumn, end)
+			}
+		}
+
+		if column < UTF16Length(line) {
+			spans[lineIndex] = appendSpan(spans[lineIndex], visualSpan{Text: SliceColumns(line, column, -1), Id: -1})
+		}
+	}
+
+	return spans
+}
+
+// visualOriginalLines splits the content of source into spans, one per mapped position in columns, up to the next mapped
+// position on the same line.
+func visualOriginalLines(source *spec.DecodedSourceRecord, columns map[int][]int, positions map[originalPosition]int) [][]visualSpan {
+	if source.Content == "" {
+		return nil
+	}
+
+	lines := SplitLines(source.Content)
+	spans := make([][]visualSpan, len(lines))
+
+	for lineIndex, line := range lines {
+		lineColumns := columns[lineIndex]
+		slices.Sort(lineColumns)
+
+		if len(lineColumns) == 0 || lineColumns[0] > 0 {
+			end := -1
+
+			if len(lineColumns) > 0 {
+				end = lineColumns[0]
+			}
+
+			spans[lineIndex] = appendSpan(spans[lineIndex], visualSpan{Text: SliceColumns(line, 0, end), Id: -1})
+		}
+
+		for i, column := range lineColumns {
+			end := -1
+
+			if i+1 < len(lineColumns) {
+				end = lineColumns[i+1]
+			}
+
+			id := positions[originalPosition{source, lineIndex, column}]
+			spans[lineIndex] = appendSpan(spans[lineIndex], visualSpan{Text: SliceColumns(line, column, end), Id: id, Color: id % visualColors})
+		}
+	}
+
+	return spans
+}
+
+// appendSpan appends span to spans, unless it is empty.
+func appendSpan(spans []visualSpan, span visualSpan) []visualSpan {
+	if span.Text == "" {
+		return spans
+	}
+
+	return append(spans, span)
+}
+
+// visualSourceTree groups sources into directories by their clean path, directories first.
+func visualSourceTree(sources []*spec.DecodedSourceRecord) []*visualTreeNode {
+	root := &visualTreeNode{Source: -1}
+
+	for index, source := range sources {
+		node := root
+		parts := strings.Split(strings.Trim(NormalizeSourcePath(source.Url, LayoutClean), "/"), "/")
+
+		for _, dir := range parts[:len(parts)-1] {
+			i := slices.IndexFunc(node.Children, func(child *visualTreeNode) bool {
+				return child.Source < 0 && child.Name == dir
+			})
+
+			if i < 0 {
+				node.Children = append(node.Children, &visualTreeNode{Name: dir, Source: -1})
+				i = len(node.Children) - 1
+			}
+
+			node = node.Children[i]
+		}
+
+		node.Children = append(node.Children, &visualTreeNode{Name: parts[len(parts)-1], Source: index, Ignored: source.Ignored})
+	}
+
+	sortVisualTree(root)
+
+	return root.Children
+}
+
+func sortVisualTree(node *visualTreeNode) {
+	slices.SortStableFunc(node.Children, func(a *visualTreeNode, b *visualTreeNode) int {
+		if (a.Source < 0) != (b.Source < 0) {
+			if a.Source < 0 {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, child := range node.Children {
+		sortVisualTree(child)
+	}
+}
+
+var visualizationTemplate = template.Must(template.New("visualization").Parse(`{{- define "spans"}}{{range .}}{{if ge .Id 0}}<span class="m c{{.Color}}" data-o="{{.Id}}"{{if .Title}} title="{{.Title}}"{{end}}>{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end}}{{end}}
+{{- define "tree"}}<ul>{{range .}}{{if ge .Source 0}}<li><a data-source="{{.Source}}"{{if .Ignored}} class="ignored"{{end}}>{{.Name}}</a></li>{{else}}<li><details open><summary>{{.Name}}</summary>{{template "tree" .Children}}</details></li>{{end}}{{end}}</ul>{{end}}
+{{- /* the page */ -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Source map visualization{{if .File}} of {{.File}}{{end}}</title>
+<style>
+body { margin: 0; display: flex; height: 100vh; font-family: sans-serif; font-size: 13px; }
+nav { width: 260px; overflow: auto; border-right: 1px solid #ccc; padding: 8px; box-sizing: border-box; }
+nav ul { list-style: none; margin: 0; padding-left: 12px; }
+nav summary { cursor: pointer; }
+nav a { color: inherit; text-decoration: none; cursor: pointer; }
+nav a.selected { font-weight: bold; }
+nav a.ignored { color: #888; }
+main { flex: 1; display: flex; min-width: 0; }
+section { flex: 1; display: flex; flex-direction: column; min-width: 0; border-right: 1px solid #ccc; }
+header { padding: 6px 8px; background: #f4f4f4; border-bottom: 1px solid #ccc; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+.code { flex: 1; overflow: auto; margin: 0; padding: 4px 0; font-family: monospace; white-space: pre; counter-reset: line; }
+.code div::before { counter-increment: line; content: counter(line); display: inline-block; width: 4em; margin-right: 8px; text-align: right; color: #999; }
+.m { cursor: pointer; border-radius: 2px; }
+.c0 { background: #fde2e2; } .c1 { background: #fdf0d5; } .c2 { background: #e2f5e2; } .c3 { background: #dceefd; }
+.c4 { background: #ece2fd; } .c5 { background: #fde2f4; } .c6 { background: #dff7f5; } .c7 { background: #f3f3d5; }
+.hl { background: #ffd400; outline: 1px solid #c90; }
+.empty { padding: 8px; color: #888; }
+</style>
+</head>
+<body>
+<nav>
+<strong>Sources</strong>
+{{template "tree" .Tree}}
+</nav>
+<main>
+<section>
+<header>Generated{{if .File}}: {{.File}}{{end}} ({{.Mappings}} mappings)</header>
+<pre class="code" id="generated">{{range .Generated}}<div>{{template "spans" .}}</div>{{end}}</pre>
+</section>
+<section>
+{{range .Sources}}<div class="source" id="source-{{.Index}}" data-source="{{.Index}}" style="display: none; flex: 1; flex-direction: column; min-height: 0">
+<header>{{.Url}}{{if .Ignored}} (ignored){{end}}</header>
+{{if .HasContent}}<pre class="code">{{range .Lines}}<div>{{template "spans" .}}</div>{{end}}</pre>{{else}}<div class="empty">No sourcesContent for this source</div>{{end}}
+</div>
+{{end}}</section>
+</main>
+<script>
+(function () {
+  var selected = null;
+  var highlighted = [];
+
+  function showSource(index) {
+    var source = document.getElementById("source-" + index);
+    if (!source || source === selected) return;
+    if (selected) selected.style.display = "none";
+    source.style.display = "flex";
+    selected = source;
+    document.querySelectorAll("nav a").forEach(function (link) {
+      link.classList.toggle("selected", link.dataset.source === String(index));
+    });
+  }
+
+  function highlight(id, from) {
+    highlighted.forEach(function (span) { span.classList.remove("hl"); });
+    highlighted = Array.prototype.slice.call(document.querySelectorAll('[data-o="' + id + '"]'));
+    highlighted.forEach(function (span) { span.classList.add("hl"); });
+
+    var counterpart = highlighted.find(function (span) {
+      return span.closest("section") !== from.closest("section");
+    });
+    if (!counterpart) return;
+    var source = counterpart.closest(".source");
+    if (source) showSource(source.dataset.source);
+    counterpart.scrollIntoView({ block: "nearest", inline: "nearest" });
+  }
+
+  document.addEventListener("mouseover", function (event) {
+    var span = event.target.closest("[data-o]");
+    if (span) highlight(span.dataset.o, span);
+  });
+
+  document.querySelectorAll("nav a").forEach(function (link) {
+    link.addEventListener("click", function () { showSource(link.dataset.source); });
+  });
+
+  showSource(0);
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderVisualization(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err = RenderVisualization(buffer, mapRecord, "function abcd(){}export default abcd;")
+
+	if err != nil {
+		t.Fatalf("Error rendering visualization: %v", err)
+	}
+
+	page := buffer.String()
+
+	if !strings.HasPrefix(page, "<!DOCTYPE html>") {
+		t.Errorf("Expected an HTML page, got %q", page[:min(len(page), 40)])
+	}
+
+	for _, expected := range []string{`data-o="1"`, ">abcd(){}export defau</span>", ">abcd() {}</span>", `<a data-source="0">original.js</a>`} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %q in visualization", expected)
+		}
+	}
+
+	if strings.Contains(page, "<script src") || strings.Contains(page, "<link") {
+		t.Errorf("Expected a self-contained page")
+	}
+}
+
+func TestVisualGeneratedLines(t *testing.T) {
+	mapRecord, err := ParseSourceMapFromFile("../testdata/test1.js.map")
+
+	if err != nil {
+		t.Fatalf("Error parsing test1.js.map: %v", err)
+	}
+
+	generated := "function abcd(){}export default abcd;\nunmapped"
+	lines := visualGeneratedLines(mapRecord.Mappings, generated, map[originalPosition]int{})
+	text := ""
+
+	for _, line := range lines {
+		for _, span := range line {
+			text += span.Text
+		}
+
+		text += "\n"
+	}
+
+	if text != generated+"\n" {
+		t.Errorf("Expected the spans to cover the generated file, got %q", text)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redawl/go-sourcemap/tools"
+)
+
+// runVisualize implements go-sourcemap visualize, rendering a source map and its generated file as an HTML page.
+func runVisualize(arguments []string) {
+	flags := flag.NewFlagSet("visualize", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage of go-sourcemap visualize: go-sourcemap visualize [flags] input")
+		flags.PrintDefaults()
+	}
+
+	fetch := fetchArgs{}
+
+	fetch.register(flags)
+	outPath := flags.String("o", "", "File to save the HTML report to. If not specified, the report is printed to stdout")
+	generatedPath := flags.String("generated", "", "Generated file or url the source map belongs to. Defaults to the input without its .map extension")
+
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	input := flags.Arg(0)
+
+	if *generatedPath == "" {
+		if input == tools.StdinInput || !strings.HasSuffix(input, ".map") {
+			fmt.Println("-generated is required when the input doesn't end in .map")
+			os.Exit(-1)
+		}
+
+		*generatedPath = strings.TrimSuffix(input, ".map")
+	}
+
+	fetcher, err := fetch.build(input, *generatedPath)
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	mapRecord, err := tools.ParseSourceMapFromInput(context.Background(), input, fetcher)
+
+	if err != nil {
+		fmt.Printf("Error parsing %s: %v\n", input, err)
+		os.Exit(-1)
+	}
+
+	var generated []byte
+
+	if isUrlInput(*generatedPath) {
+		generated, err = fetcher.Fetch(context.Background(), *generatedPath)
+	} else {
+		generated, err = os.ReadFile(*generatedPath)
+	}
+
+	if err != nil {
+		fmt.Printf("Error reading generated file %s: %v\n", *generatedPath, err)
+		os.Exit(-1)
+	}
+
+	out := os.Stdout
+
+	if *outPath != "" {
+		out, err = os.Create(*outPath)
+
+		if err != nil {
+			fmt.Printf("Error creating %s: %v\n", *outPath, err)
+			os.Exit(-1)
+		}
+	}
+
+	err = tools.RenderVisualization(out, mapRecord, string(generated))
+
+	if *outPath != "" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}